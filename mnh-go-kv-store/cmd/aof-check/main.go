@@ -0,0 +1,95 @@
+package main
+
+import (
+  "errors"
+  "flag"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// aof-check kiểm tra một file AOF, hoặc mọi file được liệt kê trong một manifest, và (với -fix)
+// cắt bỏ mọi dữ liệu kể từ mục hỏng đầu tiên. Với manifest, chỉ file cuối cùng được phép sửa.
+// Thoát với mã 0 nếu AOF hợp lệ (hoặc đã được sửa), 1 nếu AOF hỏng.
+func main() {
+  fix := flag.Bool("fix", false, "truncate the file at the first bad entry")
+  flag.Usage = func() {
+    fmt.Fprintf(os.Stderr, "Usage: %s [-fix] <file.aof | file.manifest>\n", os.Args[0])
+    flag.PrintDefaults()
+  }
+  flag.Parse()
+  if flag.NArg() != 1 {
+    flag.Usage()
+    os.Exit(2)
+  }
+  path := flag.Arg(0)
+
+  if !strings.HasSuffix(path, ".manifest") {
+    if !checkFile(path, *fix) {
+      os.Exit(1)
+    }
+    return
+  }
+
+  manifest, err := store.ReadManifest(path)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "Cannot read manifest %s: %v\n", path, err)
+    os.Exit(1)
+  }
+  files := manifest.Files()
+  dir := filepath.Dir(path)
+  for i, info := range files {
+    fmt.Printf("Checking %s (seq %d, type %s)\n", info.Name, info.Seq, info.Type)
+    // Chỉ file cuối cùng có thể bị ghi dở; các file trước đó hỏng thì cắt bỏ sẽ làm mất dữ liệu
+    last := i == len(files)-1
+    if !checkFile(filepath.Join(dir, info.Name), *fix && last) {
+      if *fix && !last {
+        fmt.Println("Only the last file of a manifest can be fixed by truncation")
+      }
+      os.Exit(1)
+    }
+  }
+  fmt.Printf("All %d files in the manifest are valid\n", len(files))
+}
+
+// checkFile kiểm tra (và nếu fix thì sửa) một file AOF, trả về true nếu file hợp lệ sau khi kiểm tra
+func checkFile(path string, fix bool) bool {
+  result, err := store.CheckAOF(path)
+  var formatErr *store.AOFFormatError
+  if err != nil && !errors.As(err, &formatErr) {
+    fmt.Fprintf(os.Stderr, "Cannot check %s: %v\n", path, err)
+    return false
+  }
+
+  if result.Preamble {
+    fmt.Println("RDB preamble detected")
+  }
+  fmt.Printf("AOF analyzed: size=%d, ok_up_to=%d, commands=%d, diff=%d\n",
+    result.Size, result.ValidSize, result.Commands, result.Size-result.ValidSize)
+
+  if formatErr == nil {
+    fmt.Println("AOF is valid")
+    return true
+  }
+
+  fmt.Printf("First bad entry at offset %d: %v\n", formatErr.Offset, formatErr.Err)
+  if formatErr.Preamble {
+    fmt.Println("The RDB preamble is damaged and cannot be repaired by truncation")
+    return false
+  }
+  if !fix {
+    fmt.Println("AOF is not valid. Use the -fix option to try fixing it")
+    return false
+  }
+
+  fmt.Printf("This will shrink the AOF from %d bytes to %d bytes\n", result.Size, result.ValidSize)
+  if err := os.Truncate(path, result.ValidSize); err != nil {
+    fmt.Fprintf(os.Stderr, "Failed to truncate AOF: %v\n", err)
+    return false
+  }
+  fmt.Println("Successfully truncated AOF")
+  return true
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+func TestCheckFile(t *testing.T) {
+  valid := "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"
+  partial := "*2\r\n$3\r\nDEL" // Lệnh cuối cùng bị ghi dở
+  badRDB := "MNHRDB0001"       // Snapshot ở đầu file chỉ có phần header
+  tests := []struct {
+    name     string
+    data     string
+    fix      bool
+    wantOK   bool
+    wantSize int // Kích thước file sau khi kiểm tra
+  }{
+    {"valid", valid, false, true, len(valid)},
+    {"valid with fix", valid, true, true, len(valid)},
+    {"truncated without fix", valid + partial, false, false, len(valid + partial)},
+    {"truncated with fix", valid + partial, true, true, len(valid)},
+    {"corrupt with fix", valid + "+OK\r\n" + valid, true, true, len(valid)},
+    {"corrupt preamble with fix", badRDB + valid, true, false, len(badRDB + valid)},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      path := filepath.Join(t.TempDir(), "appendonly.aof")
+      if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+        t.Fatal(err)
+      }
+      if ok := checkFile(path, tt.fix); ok != tt.wantOK {
+        t.Errorf("checkFile() = %v, want %v", ok, tt.wantOK)
+      }
+      info, err := os.Stat(path)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if info.Size() != int64(tt.wantSize) {
+        t.Errorf("file size = %d, want %d", info.Size(), tt.wantSize)
+      }
+    })
+  }
+
+  if checkFile(filepath.Join(t.TempDir(), "missing.aof"), true) {
+    t.Error("checkFile() accepted a missing file")
+  }
+}
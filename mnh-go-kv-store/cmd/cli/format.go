@@ -0,0 +1,148 @@
+package main
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// formatReply định dạng phản hồi để in ra: dạng dễ đọc giống redis-cli khi in ra terminal,
+// hoặc dạng thô (mỗi giá trị một dòng, không chú thích kiểu) khi raw để dùng trong script
+func formatReply(v protocol.Value, raw bool) string {
+  if raw {
+    return formatRaw(v)
+  }
+  return formatTTY(v, "")
+}
+
+// formatTTY định dạng giống redis-cli: (integer), (nil), (error), chuỗi trong dấu nháy và
+// mảng lồng nhau được đánh số và thụt lề theo độ rộng của chỉ số. Với RESP3, phần tử của Set
+// được đánh dấu "~", cặp của Map được đánh dấu "#" và in dạng key => value.
+func formatTTY(v protocol.Value, prefix string) string {
+  switch v.Typ {
+  case "string":
+    return v.Str + "\n"
+  case "error":
+    return "(error) " + v.Str + "\n"
+  case "integer":
+    return "(integer) " + strconv.Itoa(v.Num) + "\n"
+  case "bulk":
+    return quoteBulk(v.Bulk) + "\n"
+  case "null":
+    return "(nil)\n"
+  case "double":
+    return "(double) " + string(protocol.AppendDouble(nil, v.Double)) + "\n"
+  case "boolean":
+    if v.Bool {
+      return "(true)\n"
+    }
+    return "(false)\n"
+  case "bignum":
+    return "(big number) " + v.Str + "\n"
+  case "verbatim":
+    return strings.TrimSuffix(v.Bulk, "\n") + "\n"
+  case "array", "set", "push", "map":
+    n, mark := len(v.Array), ")"
+    switch v.Typ {
+    case "set":
+      mark = "~"
+    case "map":
+      n, mark = n/2, "#"
+    }
+    if n == 0 {
+      return "(empty " + v.Typ + ")\n"
+    }
+    width := len(strconv.Itoa(n))
+    // Phần tử lồng bên trong được thụt lề bằng độ rộng của "n) "
+    childPrefix := prefix + strings.Repeat(" ", width+2)
+    var b strings.Builder
+    for i := 0; i < n; i++ {
+      if i > 0 {
+        b.WriteString(prefix)
+      }
+      fmt.Fprintf(&b, "%*d%s ", width, i+1, mark)
+      if v.Typ != "map" {
+        b.WriteString(formatTTY(v.Array[i], childPrefix))
+        continue
+      }
+      key := strings.TrimSuffix(formatTTY(v.Array[2*i], childPrefix), "\n")
+      b.WriteString(key + " => ")
+      b.WriteString(formatTTY(v.Array[2*i+1], childPrefix+strings.Repeat(" ", len(key)+4)))
+    }
+    return b.String()
+  default:
+    return fmt.Sprintf("(unknown reply type %q)\n", v.Typ)
+  }
+}
+
+// formatRaw định dạng thô: giá trị được in nguyên vẹn, mỗi phần tử mảng một dòng
+func formatRaw(v protocol.Value) string {
+  switch v.Typ {
+  case "string", "error":
+    return v.Str + "\n"
+  case "integer":
+    return strconv.Itoa(v.Num) + "\n"
+  case "bulk", "verbatim":
+    return v.Bulk + "\n"
+  case "bignum":
+    return v.Str + "\n"
+  case "double":
+    return string(protocol.AppendDouble(nil, v.Double)) + "\n"
+  case "boolean":
+    return strconv.Itoa(boolToInt(v.Bool)) + "\n"
+  case "null":
+    return "\n"
+  case "array", "set", "push", "map":
+    var b strings.Builder
+    for _, item := range v.Array {
+      b.WriteString(formatRaw(item))
+    }
+    return b.String()
+  default:
+    return "\n"
+  }
+}
+
+// quoteBulk đặt chuỗi trong dấu nháy kép và escape các byte không in được giống redis-cli
+// (sdscatrepr), nên kết quả có thể dán lại vào CLI
+func quoteBulk(s string) string {
+  var b strings.Builder
+  b.Grow(len(s) + 2)
+  b.WriteByte('"')
+  for i := 0; i < len(s); i++ {
+    c := s[i]
+    switch c {
+    case '\\', '"':
+      b.WriteByte('\\')
+      b.WriteByte(c)
+    case '\n':
+      b.WriteString("\\n")
+    case '\r':
+      b.WriteString("\\r")
+    case '\t':
+      b.WriteString("\\t")
+    case '\a':
+      b.WriteString("\\a")
+    case '\b':
+      b.WriteString("\\b")
+    default:
+      if c < 32 || c >= 127 {
+        fmt.Fprintf(&b, "\\x%02x", c)
+      } else {
+        b.WriteByte(c)
+      }
+    }
+  }
+  b.WriteByte('"')
+  return b.String()
+}
+
+// boolToInt chuyển Boolean thành 1/0 như khi server trả về cho RESP2
+func boolToInt(b bool) int {
+  if b {
+    return 1
+  }
+  return 0
+}
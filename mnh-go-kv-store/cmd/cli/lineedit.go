@@ -0,0 +1,262 @@
+package main
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "strings"
+)
+
+// maxHistory là số dòng lịch sử tối đa được giữ lại
+const maxHistory = 1000
+
+// errInterrupted được trả về khi người dùng nhấn Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor đọc từng dòng lệnh từ terminal, hỗ trợ di chuyển con trỏ, các phím tắt kiểu Emacs
+// (Ctrl-A/E/U/K/W/L) và duyệt lịch sử bằng phím mũi tên. Khi stdin không phải terminal
+// thì đọc từng dòng thông thường.
+type lineEditor struct {
+  in          *os.File
+  reader      *bufio.Reader
+  out         io.Writer
+  raw         bool     // stdin là terminal và hỗ trợ chế độ raw
+  history     []string // Các dòng đã nhập, cũ nhất trước
+  historyFile string   // File lưu lịch sử ("" nếu không lưu)
+}
+
+// newLineEditor tạo line editor và tải lịch sử từ historyFile
+func newLineEditor(in *os.File, out io.Writer, historyFile string) *lineEditor {
+  e := &lineEditor{
+    in:          in,
+    reader:      bufio.NewReader(in),
+    out:         out,
+    raw:         isTerminal(int(in.Fd())),
+    historyFile: historyFile,
+  }
+  e.loadHistory()
+  return e
+}
+
+// loadHistory đọc lịch sử đã lưu (bỏ qua nếu file chưa tồn tại)
+func (e *lineEditor) loadHistory() {
+  if e.historyFile == "" {
+    return
+  }
+  data, err := os.ReadFile(e.historyFile)
+  if err != nil {
+    return
+  }
+  for _, line := range strings.Split(string(data), "\n") {
+    if line != "" {
+      e.history = append(e.history, line)
+    }
+  }
+  if len(e.history) > maxHistory {
+    e.history = e.history[len(e.history)-maxHistory:]
+  }
+}
+
+// addHistory thêm một dòng vào lịch sử và ghi tiếp vào file lịch sử
+func (e *lineEditor) addHistory(line string) {
+  if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+    return
+  }
+  e.history = append(e.history, line)
+  if len(e.history) > maxHistory {
+    e.history = e.history[1:]
+  }
+  if e.historyFile == "" {
+    return
+  }
+  f, err := os.OpenFile(e.historyFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+  if err != nil {
+    return
+  }
+  defer f.Close()
+  fmt.Fprintln(f, line)
+}
+
+// readLine hiển thị prompt và đọc một dòng (không gồm ký tự xuống dòng).
+// Trả về io.EOF khi hết input hoặc nhấn Ctrl-D trên dòng trống, errInterrupted khi nhấn Ctrl-C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+  if e.raw {
+    if restore, err := makeRaw(int(e.in.Fd())); err == nil {
+      defer restore()
+      return e.editLine(prompt)
+    }
+  }
+
+  fmt.Fprint(e.out, prompt)
+  line, err := e.reader.ReadString('\n')
+  if err != nil && (err != io.EOF || line == "") {
+    return "", err
+  }
+  return strings.TrimRight(line, "\r\n"), nil
+}
+
+// editLine đọc một dòng ở chế độ raw, tự echo và xử lý các phím điều khiển
+func (e *lineEditor) editLine(prompt string) (string, error) {
+  var buf []rune
+  pos := 0
+  // Vị trí đang duyệt trong lịch sử; len(history) là dòng đang gõ (được giữ trong pending)
+  index := len(e.history)
+  var pending []rune
+
+  refresh := func() {
+    fmt.Fprintf(e.out, "\r%s%s\x1b[K\r", prompt, string(buf))
+    if n := len([]rune(prompt)) + pos; n > 0 {
+      fmt.Fprintf(e.out, "\x1b[%dC", n)
+    }
+  }
+  showHistory := func(i int) {
+    if i < 0 || i > len(e.history) || i == index {
+      return
+    }
+    if index == len(e.history) {
+      pending = buf
+    }
+    index = i
+    if i == len(e.history) {
+      buf = pending
+    } else {
+      buf = []rune(e.history[i])
+    }
+    pos = len(buf)
+    refresh()
+  }
+
+  refresh()
+  for {
+    r, _, err := e.reader.ReadRune()
+    if err != nil {
+      fmt.Fprint(e.out, "\r\n")
+      return "", err
+    }
+
+    switch r {
+    case '\r', '\n': // Enter
+      fmt.Fprint(e.out, "\r\n")
+      return string(buf), nil
+    case 3: // Ctrl-C
+      fmt.Fprint(e.out, "^C\r\n")
+      return "", errInterrupted
+    case 4: // Ctrl-D: thoát trên dòng trống, ngược lại xóa ký tự tại con trỏ
+      if len(buf) == 0 {
+        fmt.Fprint(e.out, "\r\n")
+        return "", io.EOF
+      }
+      if pos < len(buf) {
+        buf = append(buf[:pos], buf[pos+1:]...)
+      }
+    case 127, 8: // Backspace
+      if pos > 0 {
+        buf = append(buf[:pos-1], buf[pos:]...)
+        pos--
+      }
+    case 1: // Ctrl-A
+      pos = 0
+    case 5: // Ctrl-E
+      pos = len(buf)
+    case 2: // Ctrl-B
+      if pos > 0 {
+        pos--
+      }
+    case 6: // Ctrl-F
+      if pos < len(buf) {
+        pos++
+      }
+    case 11: // Ctrl-K: xóa tới cuối dòng
+      buf = buf[:pos]
+    case 21: // Ctrl-U: xóa cả dòng
+      buf, pos = nil, 0
+    case 23: // Ctrl-W: xóa từ trước con trỏ
+      start := pos
+      for start > 0 && buf[start-1] == ' ' {
+        start--
+      }
+      for start > 0 && buf[start-1] != ' ' {
+        start--
+      }
+      buf = append(buf[:start], buf[pos:]...)
+      pos = start
+    case 12: // Ctrl-L: xóa màn hình
+      fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+    case 16: // Ctrl-P
+      showHistory(index - 1)
+      continue
+    case 14: // Ctrl-N
+      showHistory(index + 1)
+      continue
+    case 27: // Chuỗi escape: mũi tên, Home/End, Delete
+      switch e.readEscape() {
+      case 'A':
+        showHistory(index - 1)
+      case 'B':
+        showHistory(index + 1)
+      case 'C':
+        if pos < len(buf) {
+          pos++
+        }
+      case 'D':
+        if pos > 0 {
+          pos--
+        }
+      case 'H':
+        pos = 0
+      case 'F':
+        pos = len(buf)
+      case '~': // Delete
+        if pos < len(buf) {
+          buf = append(buf[:pos], buf[pos+1:]...)
+        }
+      }
+    default:
+      if r < 32 {
+        continue
+      }
+      buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+      pos++
+    }
+    refresh()
+  }
+}
+
+// readEscape đọc phần còn lại của chuỗi escape sau ESC và trả về ký tự cuối
+// ('A'..'D' cho mũi tên, 'H'/'F' cho Home/End, '~' cho Delete; 0 nếu không nhận ra)
+func (e *lineEditor) readEscape() byte {
+  b, err := e.reader.ReadByte()
+  if err != nil || (b != '[' && b != 'O') {
+    return 0
+  }
+  b, err = e.reader.ReadByte()
+  if err != nil {
+    return 0
+  }
+  switch {
+  case b >= '0' && b <= '9':
+    // ESC [ n ~ : chỉ nhận Delete (3), Home (1, 7) và End (4, 8)
+    code := b
+    for b >= '0' && b <= '9' {
+      if b, err = e.reader.ReadByte(); err != nil {
+        return 0
+      }
+    }
+    if b != '~' {
+      return 0
+    }
+    switch code {
+    case '3':
+      return '~'
+    case '1', '7':
+      return 'H'
+    case '4', '8':
+      return 'F'
+    }
+    return 0
+  default:
+    return b
+  }
+}
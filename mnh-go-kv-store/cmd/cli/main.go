@@ -0,0 +1,351 @@
+package main
+
+import (
+  "crypto/tls"
+  "errors"
+  "flag"
+  "fmt"
+  "io"
+  "net"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/config"
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/pkg/client"
+)
+
+// pipeBatchSize là số lệnh được gửi trong mỗi pipeline ở chế độ -pipe
+const pipeBatchSize = 10000
+
+// cli là client dòng lệnh cho KV Store, dùng giống redis-cli:
+//   - không có đối số: REPL với lịch sử lệnh (lưu ở ~/.mnhcli_history hoặc $MNHCLI_HISTFILE)
+//   - có đối số: chạy một lệnh rồi thoát (mã thoát 1 nếu server trả về lỗi)
+//   - stdin không phải terminal: chạy lần lượt từng dòng lệnh đọc từ stdin
+//   - -pipe: gửi hàng loạt lệnh đọc từ stdin (RESP hoặc inline) để nạp dữ liệu
+func main() {
+  host := flag.String("h", "127.0.0.1", "server hostname")
+  port := flag.Int("p", 6379, "server port")
+  socket := flag.String("s", "", "server Unix socket (overrides -h and -p)")
+  password := flag.String("a", "", "password to use when connecting to the server")
+  user := flag.String("user", "", "ACL username to authenticate with (requires -a)")
+  raw := flag.Bool("raw", false, "use raw formatting for replies (default when stdout is not a terminal)")
+  resp3 := flag.Bool("3", false, "start the session in RESP3 protocol mode (HELLO 3)")
+  pipe := flag.Bool("pipe", false, "transfer commands read from stdin (RESP or inline) to the server in bulk")
+  useTLS := flag.Bool("tls", false, "establish a secure TLS connection")
+  cacert := flag.String("cacert", "", "CA certificate file to verify the server with (default: system CAs)")
+  cert := flag.String("cert", "", "client certificate to authenticate with (mutual TLS)")
+  key := flag.String("key", "", "private key file of the client certificate")
+  sni := flag.String("sni", "", "server name indication and name to verify in the server certificate (default: -h)")
+  insecure := flag.Bool("insecure", false, "skip verification of the server certificate")
+  flag.Usage = func() {
+    fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [cmd [arg [arg ...]]]\n", os.Args[0])
+    flag.PrintDefaults()
+  }
+  flag.Parse()
+
+  c := &cli{
+    addr:     net.JoinHostPort(*host, strconv.Itoa(*port)),
+    user:     *user,
+    password: *password,
+    resp3:    *resp3,
+    raw:      *raw || !isTerminal(int(os.Stdout.Fd())),
+  }
+  if *socket != "" {
+    c.addr = "unix://" + *socket
+  }
+  if *useTLS {
+    var err error
+    if c.tls, err = tlsConfig(*cacert, *cert, *key, *sni, *insecure); err != nil {
+      fmt.Fprintf(os.Stderr, "Could not set up TLS: %v\n", err)
+      os.Exit(1)
+    }
+  }
+
+  var status int
+  switch {
+  case *pipe:
+    status = c.pipe(os.Stdin)
+  case flag.NArg() > 0:
+    status = c.oneShot(flag.Args())
+  default:
+    status = c.repl()
+  }
+  c.close()
+  os.Exit(status)
+}
+
+// cli giữ kết nối tới server; kết nối được mở lại khi cần nếu bị mất
+type cli struct {
+  addr     string
+  user     string // User ACL ("" : user default)
+  password string
+  resp3    bool // Chọn RESP3 bằng HELLO 3 sau khi kết nối
+  raw      bool        // In phản hồi ở dạng thô thay vì dạng dễ đọc
+  tls      *tls.Config // nil: kết nối không mã hóa
+  client   *client.Client
+}
+
+// tlsConfig tạo cấu hình TLS cho -tls: CA để xác minh server và chứng chỉ của client (nếu có)
+func tlsConfig(cacert, cert, key, sni string, insecure bool) (*tls.Config, error) {
+  cfg := &tls.Config{ServerName: sni, InsecureSkipVerify: insecure}
+  if cacert != "" {
+    pool, err := config.LoadCertPool(cacert)
+    if err != nil {
+      return nil, err
+    }
+    cfg.RootCAs = pool
+  }
+  if cert != "" || key != "" {
+    pair, err := tls.LoadX509KeyPair(cert, key)
+    if err != nil {
+      return nil, fmt.Errorf("loading the client certificate: %w", err)
+    }
+    cfg.Certificates = []tls.Certificate{pair}
+  }
+  return cfg, nil
+}
+
+// connect mở kết nối, xác thực bằng user và mật khẩu (nếu có) và chọn RESP3 nếu được yêu cầu
+func (c *cli) connect() error {
+  var opts []client.Option
+  if c.tls != nil {
+    opts = append(opts, client.WithTLS(c.tls))
+  }
+  conn, err := client.NewClient(c.addr, opts...)
+  if err != nil {
+    return err
+  }
+  if c.password != "" {
+    auth := []string{"AUTH", c.password}
+    if c.user != "" {
+      auth = []string{"AUTH", c.user, c.password}
+    }
+    if err := c.setup(conn, auth...); err != nil {
+      return err
+    }
+  }
+  if c.resp3 {
+    if err := c.setup(conn, "HELLO", "3"); err != nil {
+      return err
+    }
+  }
+  c.client = conn
+  return nil
+}
+
+// setup gửi một lệnh khởi tạo kết nối; lỗi server trả về chỉ được in ra
+func (c *cli) setup(conn *client.Client, args ...string) error {
+  reply, err := conn.Do(args...)
+  if err != nil {
+    conn.Close()
+    return err
+  }
+  if reply.Typ == "error" {
+    fmt.Fprintf(os.Stderr, "%s failed: %s\n", args[0], reply.Str)
+  }
+  return nil
+}
+
+// close đóng kết nối hiện tại (nếu có)
+func (c *cli) close() {
+  if c.client != nil {
+    c.client.Close()
+    c.client = nil
+  }
+}
+
+// do gửi một lệnh, kết nối lại nếu cần. Lỗi kết nối làm đóng kết nối hiện tại.
+func (c *cli) do(args []string) (protocol.Value, error) {
+  if c.client == nil {
+    if err := c.connect(); err != nil {
+      return protocol.Value{}, err
+    }
+  }
+  reply, err := c.client.Do(args...)
+  if err != nil {
+    c.close()
+    return protocol.Value{}, err
+  }
+  return reply, nil
+}
+
+// run chạy một lệnh và in phản hồi, trả về false nếu có lỗi (kể cả lỗi server trả về)
+func (c *cli) run(args []string) bool {
+  reply, err := c.do(args)
+  if err != nil {
+    // SHUTDOWN thành công không có phản hồi: server đóng kết nối
+    if strings.EqualFold(args[0], "SHUTDOWN") && errors.Is(err, io.EOF) {
+      return true
+    }
+    fmt.Fprintf(os.Stderr, "Could not connect to server at %s: %v\n", c.addr, err)
+    return false
+  }
+
+  // User và mật khẩu của AUTH và phiên bản giao thức của HELLO được dùng lại khi kết nối lại
+  switch {
+  case strings.EqualFold(args[0], "AUTH") && len(args) > 1 && reply.Typ == "string":
+    c.user, c.password = "", args[len(args)-1]
+    if len(args) > 2 {
+      c.user = args[1]
+    }
+  case strings.EqualFold(args[0], "HELLO") && len(args) > 1 && reply.Typ != "error":
+    c.resp3 = args[1] == "3"
+  }
+  fmt.Print(formatReply(reply, c.raw))
+  return reply.Typ != "error"
+}
+
+// oneShot chạy lệnh từ đối số dòng lệnh
+func (c *cli) oneShot(args []string) int {
+  if !c.run(args) {
+    return 1
+  }
+  return 0
+}
+
+// repl đọc và chạy lệnh cho tới khi gặp quit/exit, Ctrl-C/Ctrl-D hoặc hết input.
+// Khi stdin không phải terminal thì không in prompt và không ghi lịch sử.
+func (c *cli) repl() int {
+  interactive := isTerminal(int(os.Stdin.Fd()))
+  historyFile := ""
+  if interactive {
+    historyFile = historyPath()
+    if err := c.connect(); err != nil {
+      fmt.Fprintf(os.Stderr, "Could not connect to server at %s: %v\n", c.addr, err)
+    }
+  }
+  editor := newLineEditor(os.Stdin, os.Stdout, historyFile)
+
+  status := 0
+  for {
+    prompt := ""
+    if interactive {
+      prompt = c.addr + "> "
+      if c.client == nil {
+        prompt = "not connected> "
+      }
+    }
+
+    line, err := editor.readLine(prompt)
+    if err != nil {
+      if err != io.EOF && !errors.Is(err, errInterrupted) {
+        fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+        return 1
+      }
+      return status
+    }
+
+    args, err := protocol.SplitArgs(line)
+    if err != nil {
+      fmt.Fprintln(os.Stderr, "Invalid argument(s)")
+      continue
+    }
+    if len(args) == 0 {
+      continue
+    }
+    // Không lưu mật khẩu vào file lịch sử
+    if !hasPassword(args) {
+      editor.addHistory(line)
+    }
+
+    switch strings.ToLower(args[0]) {
+    case "quit", "exit":
+      return status
+    case "clear":
+      fmt.Print("\x1b[H\x1b[2J")
+      continue
+    }
+    if !c.run(args) {
+      status = 1
+    }
+  }
+}
+
+// hasPassword cho biết lệnh có chứa mật khẩu: AUTH, HELLO ... AUTH user pass và ACL SETUSER
+func hasPassword(args []string) bool {
+  switch strings.ToUpper(args[0]) {
+  case "AUTH":
+    return true
+  case "HELLO":
+    for _, arg := range args[1:] {
+      if strings.EqualFold(arg, "AUTH") {
+        return true
+      }
+    }
+  case "ACL":
+    return len(args) > 1 && strings.EqualFold(args[1], "SETUSER")
+  }
+  return false
+}
+
+// historyPath trả về đường dẫn file lịch sử ("" nếu không lưu lịch sử)
+func historyPath() string {
+  if path, ok := os.LookupEnv("MNHCLI_HISTFILE"); ok {
+    return path
+  }
+  home, err := os.UserHomeDir()
+  if err != nil {
+    return ""
+  }
+  return filepath.Join(home, ".mnhcli_history")
+}
+
+// pipe đọc mọi lệnh từ r (RESP Array hoặc mỗi dòng một lệnh inline), gửi theo từng lô bằng
+// pipeline và đếm phản hồi. Các phản hồi lỗi được in ra stderr. Trả về 1 nếu có lỗi.
+func (c *cli) pipe(r io.Reader) int {
+  if err := c.connect(); err != nil {
+    fmt.Fprintf(os.Stderr, "Could not connect to server at %s: %v\n", c.addr, err)
+    return 1
+  }
+
+  var errCount, replyCount int
+  p := c.client.Pipeline()
+  flush := func() error {
+    replies, err := p.Exec()
+    for _, reply := range replies {
+      replyCount++
+      if reply.Value.Typ == "error" {
+        errCount++
+        fmt.Fprintln(os.Stderr, reply.Value.Str)
+      }
+    }
+    return err
+  }
+
+  input := protocol.NewResp(r)
+  for {
+    cmd, _, err := input.ReadCommand()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+      return 1
+    }
+
+    args := make([]string, len(cmd.Array))
+    for i, arg := range cmd.Array {
+      args[i] = arg.Bulk
+    }
+    p.Queue(args...)
+    if p.Len() >= pipeBatchSize {
+      if err := flush(); err != nil {
+        fmt.Fprintf(os.Stderr, "Error transferring data: %v\n", err)
+        return 1
+      }
+    }
+  }
+  if err := flush(); err != nil {
+    fmt.Fprintf(os.Stderr, "Error transferring data: %v\n", err)
+    return 1
+  }
+
+  fmt.Printf("All data transferred. errors: %d, replies: %d\n", errCount, replyCount)
+  if errCount > 0 {
+    return 1
+  }
+  return 0
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+  "net"
+  "reflect"
+  "strings"
+  "sync"
+  "testing"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// fakeServer nhận lệnh trên một cổng TCP, ghi lại các lệnh và trả lời +OK
+// (hoặc lỗi với lệnh FAIL), đủ để kiểm tra những gì cli gửi đi
+type fakeServer struct {
+  addr     string
+  mu       sync.Mutex
+  commands [][]string
+}
+
+func startFakeServer(t *testing.T) *fakeServer {
+  t.Helper()
+  listener, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { listener.Close() })
+
+  s := &fakeServer{addr: listener.Addr().String()}
+  go func() {
+    for {
+      conn, err := listener.Accept()
+      if err != nil {
+        return
+      }
+      go s.serve(conn)
+    }
+  }()
+  return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+  defer conn.Close()
+  r := protocol.NewResp(conn)
+  for {
+    cmd, _, err := r.ReadCommand()
+    if err != nil {
+      return
+    }
+    args := make([]string, len(cmd.Array))
+    for i, arg := range cmd.Array {
+      args[i] = arg.Bulk
+    }
+    s.mu.Lock()
+    s.commands = append(s.commands, args)
+    s.mu.Unlock()
+
+    reply := "+OK\r\n"
+    if args[0] == "FAIL" {
+      reply = "-ERR failed\r\n"
+    }
+    if _, err := conn.Write([]byte(reply)); err != nil {
+      return
+    }
+  }
+}
+
+func (s *fakeServer) received() [][]string {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  return s.commands
+}
+
+func TestPipe(t *testing.T) {
+  tests := []struct {
+    name       string
+    input      string
+    want       [][]string
+    wantStatus int
+  }{
+    {"inline", "SET a 1\nSET b \"two words\"\r\n\n", [][]string{{"SET", "a", "1"}, {"SET", "b", "two words"}}, 0},
+    {"resp and inline", "*2\r\n$3\r\nGET\r\n$1\r\na\r\nDEL a\n", [][]string{{"GET", "a"}, {"DEL", "a"}}, 0},
+    {"error replies are counted", "SET a 1\nFAIL\nSET b 2\n", [][]string{{"SET", "a", "1"}, {"FAIL"}, {"SET", "b", "2"}}, 1},
+    {"unbalanced quotes stop the transfer", "SET a 1\nSET b \"two\nSET c 3\n", nil, 1},
+    {"truncated resp", "SET a 1\n*2\r\n$3\r\nGET\r\n", nil, 1},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      server := startFakeServer(t)
+      c := &cli{addr: server.addr}
+      defer c.close()
+
+      if status := c.pipe(strings.NewReader(tt.input)); status != tt.wantStatus {
+        t.Errorf("pipe() = %d, want %d", status, tt.wantStatus)
+      }
+      // Lệnh chỉ được gửi theo lô: input lỗi thì không gửi gì
+      if got := server.received(); !reflect.DeepEqual(got, tt.want) {
+        t.Errorf("server received %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestRunRemembersAuth(t *testing.T) {
+  server := startFakeServer(t)
+  c := &cli{addr: server.addr, raw: true}
+  defer c.close()
+
+  for _, args := range [][]string{{"AUTH", "alice", "secret"}, {"HELLO", "3"}} {
+    if !c.run(args) {
+      t.Fatalf("run(%q) failed", args)
+    }
+  }
+  if c.run([]string{"FAIL"}) {
+    t.Error("run() succeeded with an error reply")
+  }
+  if c.user != "alice" || c.password != "secret" || !c.resp3 {
+    t.Fatalf("after AUTH and HELLO: user %q, password %q, resp3 %v", c.user, c.password, c.resp3)
+  }
+
+  // Kết nối lại sau khi mất kết nối: xác thực và chọn RESP3 trước lệnh tiếp theo
+  c.close()
+  if !c.run([]string{"PING"}) {
+    t.Fatal("run() failed after reconnecting")
+  }
+  want := [][]string{{"AUTH", "alice", "secret"}, {"HELLO", "3"}, {"FAIL"}, {"AUTH", "alice", "secret"}, {"HELLO", "3"}, {"PING"}}
+  if got := server.received(); !reflect.DeepEqual(got, want) {
+    t.Errorf("server received %q, want %q", got, want)
+  }
+}
+
+func TestHasPassword(t *testing.T) {
+  tests := []struct {
+    args []string
+    want bool
+  }{
+    {[]string{"auth", "secret"}, true},
+    {[]string{"AUTH", "user", "secret"}, true},
+    {[]string{"HELLO", "3", "auth", "user", "secret"}, true},
+    {[]string{"HELLO", "3"}, false},
+    {[]string{"acl", "setuser", "bob", ">secret"}, true},
+    {[]string{"ACL", "LIST"}, false},
+    {[]string{"SET", "auth", "value"}, false},
+  }
+  for _, tt := range tests {
+    if got := hasPassword(tt.args); got != tt.want {
+      t.Errorf("hasPassword(%q) = %v, want %v", tt.args, got, tt.want)
+    }
+  }
+}
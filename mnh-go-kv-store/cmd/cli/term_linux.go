@@ -0,0 +1,52 @@
+//go:build linux
+
+package main
+
+import (
+  "syscall"
+  "unsafe"
+)
+
+// getTermios đọc cấu hình terminal của fd
+func getTermios(fd int) (*syscall.Termios, error) {
+  var t syscall.Termios
+  if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCGETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+    return nil, errno
+  }
+  return &t, nil
+}
+
+// setTermios ghi cấu hình terminal của fd
+func setTermios(fd int, t *syscall.Termios) error {
+  if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCSETS, uintptr(unsafe.Pointer(t))); errno != 0 {
+    return errno
+  }
+  return nil
+}
+
+// isTerminal kiểm tra fd có phải là terminal không
+func isTerminal(fd int) bool {
+  _, err := getTermios(fd)
+  return err == nil
+}
+
+// makeRaw chuyển terminal sang chế độ raw (không echo, đọc từng phím, không xử lý Ctrl-C)
+// và trả về hàm khôi phục chế độ cũ
+func makeRaw(fd int) (func(), error) {
+  old, err := getTermios(fd)
+  if err != nil {
+    return nil, err
+  }
+
+  raw := *old
+  raw.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+  raw.Oflag &^= syscall.OPOST
+  raw.Cflag |= syscall.CS8
+  raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+  raw.Cc[syscall.VMIN] = 1
+  raw.Cc[syscall.VTIME] = 0
+  if err := setTermios(fd, &raw); err != nil {
+    return nil, err
+  }
+  return func() { setTermios(fd, old) }, nil
+}
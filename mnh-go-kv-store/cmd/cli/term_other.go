@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// isTerminal luôn trả về false: ngoài Linux CLI đọc dòng lệnh ở chế độ thường (không có phím tắt và lịch sử)
+func isTerminal(fd int) bool {
+  return false
+}
+
+// makeRaw không được hỗ trợ ngoài Linux
+func makeRaw(fd int) (func(), error) {
+  return nil, errors.New("raw terminal mode is not supported on this platform")
+}
@@ -1,31 +1,160 @@
 package main
 
 import (
+  "context"
+  "crypto/tls"
+  "errors"
+  "flag"
   "log"
+  "os"
+  "os/signal"
+  "syscall"
 
+  "mnhgo/mnh-go-kv-store/internal/config"
+  "mnhgo/mnh-go-kv-store/internal/logging"
   "mnhgo/mnh-go-kv-store/internal/store"
   "mnhgo/mnh-go-kv-store/service"
 )
 
 func main() {
-  // Khởi tạo Store và AOF
+  if err := run(); err != nil {
+    log.Fatalf("Server stopped with errors: %v", err)
+  }
+}
+
+// run khởi động server và chặn cho tới khi server dừng hẳn. Lỗi cấu hình và lỗi tải dữ liệu
+// làm tiến trình thoát ngay; lỗi khi chạy được trả về sau khi các tác vụ nền đã dừng và AOF đã đóng.
+func run() error {
+  // Cấu hình: giá trị mặc định < file cấu hình (-config) < các flag trên dòng lệnh
+  cfg := config.New()
+  configFile := flag.String("config", "", "path to a redis.conf style configuration file")
+  applyFlags := cfg.BindFlags(flag.CommandLine)
+  flag.Parse()
+
+  if *configFile != "" {
+    if err := cfg.LoadFile(*configFile); err != nil {
+      log.Fatalf("Invalid configuration: %v", err)
+    }
+  }
+  if err := applyFlags(); err != nil {
+    log.Fatalf("Invalid configuration: %v", err)
+  }
+  settings := cfg.Settings()
+  logging.SetLevel(settings.LogLevel)
+  if settings.Port == 0 && settings.TLSPort == 0 && settings.UnixSocket == "" {
+    log.Fatalf("Invalid configuration: port and tls-port are both 0 and no unixsocket is set, nothing to listen on")
+  }
+
+  // Chứng chỉ TLS được đọc trước khi đổi thư mục làm việc: đường dẫn tương đối tính từ thư mục khởi động
+  var tlsConfig *tls.Config
+  if settings.TLSPort != 0 {
+    var err error
+    if tlsConfig, err = settings.TLSConfig(); err != nil {
+      log.Fatalf("Invalid TLS configuration: %v", err)
+    }
+  }
+
+  // Mọi file AOF và RDB nằm trong thư mục làm việc
+  if err := os.MkdirAll(settings.Dir, 0755); err != nil {
+    log.Fatalf("Can't create working directory %s: %v", settings.Dir, err)
+  }
+  if err := os.Chdir(settings.Dir); err != nil {
+    log.Fatalf("Can't chdir to %s: %v", settings.Dir, err)
+  }
+
+  // Khởi tạo Store và AOF (AOF bị tắt khi appendonly no)
   myStore := store.NewStore()
-  myAOF, err := store.NewAOF("database.aof")
-  if err != nil {
-    log.Fatalf("Failed to initialize AOF: %v", err)
+  var myAOF *store.AOF
+  if settings.AppendOnly {
+    var err error
+    myAOF, err = store.NewAOF(settings.AppendDirName, settings.AppendFilename)
+    if err != nil {
+      log.Fatalf("Failed to initialize AOF: %v", err)
+    }
+    defer myAOF.Close()
+    myAOF.LoadTruncated = settings.AOFLoadTruncated
   }
 
+  myRDB := store.NewRDB(settings.DBFilename)
+
   // Khởi tạo CommandsHandler
   handler := service.NewCommandsHandler(myStore, myAOF)
+  handler.EnableRDB(myRDB)
+  handler.EnableConfig(cfg)
+
+  // Các user ACL: file ACL (nếu có) thay thế user default được tạo từ requirepass
+  if settings.ACLFile != "" {
+    if err := handler.EnableACLFile(settings.ACLFile); err != nil {
+      log.Fatalf("Failed to load the ACL file %s: %v", settings.ACLFile, err)
+    }
+  }
 
-  // Tải lại dữ liệu từ AOF khi khởi động
-  if err := myAOF.ReadAndLoad(handler); err != nil {
-    log.Printf("Warning: Failed to load AOF data: %v", err)
+  // Tải lại dữ liệu khi khởi động: AOF chứa mọi thay đổi nên được ưu tiên,
+  // snapshot RDB chỉ được dùng khi AOF còn trống (giống Redis).
+  // Key có thời điểm hết hạn đã qua trong lúc server dừng sẽ bị loại bỏ sau khi tải xong.
+  myStore.StartLoading()
+  if myAOF != nil && myAOF.Stats().CurrentSize > 0 {
+    // AOF hỏng thì không khởi động: ghi tiếp sau dữ liệu hỏng sẽ làm mất luôn các lệnh mới
+    if err := myAOF.ReadAndLoad(handler); err != nil {
+      log.Fatalf("Failed to load AOF data: %v. Make a backup of %s and repair it with aof-check -fix %s", err, myAOF.Dir(), myAOF.ManifestPath())
+    }
+  } else if keys, err := myRDB.Load(myStore); err != nil {
+    logging.Warningf("Failed to load RDB snapshot: %v", err)
+  } else if keys > 0 {
+    logging.Noticef("Loaded %d keys from RDB snapshot %s", keys, myRDB.Path())
+  }
+  if expired := myStore.FinishLoading(); expired > 0 {
+    logging.Noticef("Dropped %d keys that expired while the server was down", expired)
   }
 
+  // Xóa chủ động các key hết hạn trong nền
+  stopExpire := handler.StartActiveExpire()
+  defer stopExpire()
+
+  // Tự động ghi lại AOF khi file tăng quá lớn
+  stopAutoRewrite := handler.StartAutoRewrite()
+  defer stopAutoRewrite()
+
+  // Tự động lưu snapshot RDB theo các quy tắc save
+  stopSaveRules := handler.StartSaveRules()
+  defer stopSaveRules()
+
   // Khởi động Server
   server := service.NewServer(handler)
-  if err := server.Start(":6379"); err != nil {
-    log.Fatalf("Server failed to start: %v", err)
+
+  // Dừng server an toàn khi nhận SIGINT/SIGTERM; tín hiệu thứ hai trong lúc dừng thoát ngay lập tức.
+  // Nếu không lưu được snapshot, server tiếp tục chạy và tín hiệu tiếp theo thử dừng lại.
+  signals := make(chan os.Signal, 1)
+  signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+  go func() {
+    for sig := range signals {
+      signal.Stop(signals)
+      logging.Warningf("Received %s, scheduling shutdown...", sig)
+      ctx, cancel := context.WithTimeout(context.Background(), service.ShutdownTimeout)
+      err := server.Shutdown(ctx)
+      cancel()
+      if !errors.Is(err, service.ErrShutdownAborted) {
+        return
+      }
+      logging.Warningf("Errors trying to shut down the server, check the logs for more information")
+      signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+    }
+  }()
+
+  if settings.Port != 0 {
+    if err := server.Listen(settings.Addr()); err != nil {
+      return err
+    }
+  }
+  if tlsConfig != nil {
+    if err := server.ListenTLS(settings.TLSAddr(), tlsConfig); err != nil {
+      return err
+    }
+  }
+  if settings.UnixSocket != "" {
+    if err := server.ListenUnix(settings.UnixSocket, settings.UnixSocketPerm); err != nil {
+      return err
+    }
   }
+  return server.Serve()
 }
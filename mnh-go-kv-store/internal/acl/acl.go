@@ -0,0 +1,308 @@
+package acl
+
+import (
+  "crypto/sha256"
+  "crypto/subtle"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "sort"
+  "strings"
+  "sync"
+)
+
+// DefaultUser là user mà mọi kết nối mới dùng (giống Redis): khi user này bật và không cần mật khẩu,
+// kết nối được xác thực sẵn; requirepass là mật khẩu của user này
+const DefaultUser = "default"
+
+// Các lỗi xác thực và phân quyền
+var (
+  ErrWrongPass = errors.New("WRONGPASS invalid username-password pair or user is disabled.")
+  ErrNoUser    = errors.New("user does not exist")
+)
+
+// DeniedError được Check trả về khi user không có quyền chạy lệnh hoặc truy cập key
+type DeniedError struct {
+  User    string
+  Command string // Tên lệnh viết thường, ví dụ "get" hoặc "config|set"
+  Key     string // Key bị từ chối ("" nếu bị từ chối vì lệnh)
+}
+
+func (e *DeniedError) Error() string {
+  if e.Key != "" {
+    return "NOPERM No permissions to access a key"
+  }
+  return fmt.Sprintf("NOPERM User %s has no permissions to run the '%s' command", e.User, e.Command)
+}
+
+// Command mô tả một lệnh (hoặc một lệnh con dạng "CONFIG|GET") cùng các category của nó
+type Command struct {
+  Name       string
+  Categories []string
+}
+
+// ACL quản lý các user, quyền của họ và file ACL. An toàn khi dùng đồng thời.
+type ACL struct {
+  mu         sync.RWMutex
+  users      map[string]*user
+  file       string              // File ACL ("" nếu không dùng)
+  commands   map[string][]string // Tên lệnh (hoặc "LỆNH|CON") -> category
+  subs       map[string][]string // Lệnh có lệnh con -> các lệnh con dạng "LỆNH|CON"
+  categories map[string]bool
+}
+
+// New tạo ACL với các lệnh của server và user default (on nopass ~* +@all)
+func New(commands []Command) *ACL {
+  a := &ACL{
+    users:      make(map[string]*user),
+    commands:   make(map[string][]string, len(commands)),
+    subs:       make(map[string][]string),
+    categories: make(map[string]bool),
+  }
+  for _, cmd := range commands {
+    name := strings.ToUpper(cmd.Name)
+    a.commands[name] = cmd.Categories
+    if parent, _, ok := strings.Cut(name, "|"); ok {
+      a.subs[parent] = append(a.subs[parent], name)
+    }
+    for _, category := range cmd.Categories {
+      a.categories[category] = true
+    }
+  }
+  a.users[DefaultUser] = a.newDefaultUser()
+  return a
+}
+
+// newDefaultUser tạo user default có mọi quyền và không cần mật khẩu
+func (a *ACL) newDefaultUser() *user {
+  u := a.newUser(DefaultUser)
+  for _, rule := range []string{"on", "nopass", "allkeys", "allcommands"} {
+    u.apply(a, rule)
+  }
+  return u
+}
+
+// SetFile đặt đường dẫn file ACL dùng cho Load và Save
+func (a *ACL) SetFile(path string) {
+  a.mu.Lock()
+  defer a.mu.Unlock()
+  a.file = path
+}
+
+// File trả về đường dẫn file ACL ("" nếu không dùng)
+func (a *ACL) File() string {
+  a.mu.RLock()
+  defer a.mu.RUnlock()
+  return a.file
+}
+
+// SetUser tạo user (nếu chưa có) và áp dụng lần lượt các quy tắc. Nếu một quy tắc không hợp lệ,
+// user không bị thay đổi.
+func (a *ACL) SetUser(name string, rules ...string) error {
+  if name == "" || strings.ContainsAny(name, " \t\r\n") {
+    return fmt.Errorf("ERR Usernames can't contain spaces or null characters")
+  }
+
+  a.mu.Lock()
+  defer a.mu.Unlock()
+  u, ok := a.users[name]
+  if ok {
+    u = u.clone()
+  } else {
+    u = a.newUser(name)
+  }
+  for _, rule := range rules {
+    if err := u.apply(a, rule); err != nil {
+      return fmt.Errorf("ERR Error in ACL SETUSER modifier '%s': %v", rule, err)
+    }
+  }
+  a.users[name] = u
+  return nil
+}
+
+// SetDefaultPassword áp dụng requirepass: mật khẩu rỗng làm user default không cần mật khẩu,
+// ngược lại thay mọi mật khẩu của user default bằng password
+func (a *ACL) SetDefaultPassword(password string) {
+  rules := []string{"resetpass", "nopass"}
+  if password != "" {
+    rules[1] = ">" + password
+  }
+  a.SetUser(DefaultUser, rules...)
+}
+
+// DelUser xóa các user, trả về số user đã xóa. User default không thể bị xóa.
+func (a *ACL) DelUser(names ...string) (int, error) {
+  for _, name := range names {
+    if name == DefaultUser {
+      return 0, fmt.Errorf("ERR The '%s' user cannot be removed", DefaultUser)
+    }
+  }
+
+  a.mu.Lock()
+  defer a.mu.Unlock()
+  deleted := 0
+  for _, name := range names {
+    if _, ok := a.users[name]; ok {
+      delete(a.users, name)
+      deleted++
+    }
+  }
+  return deleted, nil
+}
+
+// UserInfo là thông tin của một user cho ACL GETUSER
+type UserInfo struct {
+  Flags     []string // "on"/"off", "nopass"
+  Passwords []string // SHA-256 dạng hex
+  Commands  string   // Các quy tắc lệnh, ví dụ "+@all -@dangerous"
+  Keys      string   // Các mẫu key, ví dụ "~cache:* ~session:*"
+}
+
+// GetUser trả về thông tin của user name
+func (a *ACL) GetUser(name string) (UserInfo, bool) {
+  a.mu.RLock()
+  defer a.mu.RUnlock()
+  u, ok := a.users[name]
+  if !ok {
+    return UserInfo{}, false
+  }
+
+  info := UserInfo{
+    Flags:     []string{"off"},
+    Passwords: append([]string{}, u.passwords...),
+    Commands:  strings.Join(u.commandRules, " "),
+    Keys:      strings.Join(u.keyRules(), " "),
+  }
+  if u.enabled {
+    info.Flags[0] = "on"
+  }
+  if u.nopass {
+    info.Flags = append(info.Flags, "nopass")
+  }
+  return info, true
+}
+
+// Users trả về tên các user theo thứ tự chữ cái
+func (a *ACL) Users() []string {
+  a.mu.RLock()
+  defer a.mu.RUnlock()
+  names := make([]string, 0, len(a.users))
+  for name := range a.users {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+// List trả về mô tả của mọi user theo cú pháp của file ACL ("user <tên> <quy tắc...>")
+func (a *ACL) List() []string {
+  a.mu.RLock()
+  defer a.mu.RUnlock()
+  return a.listLocked()
+}
+
+func (a *ACL) listLocked() []string {
+  names := make([]string, 0, len(a.users))
+  for name := range a.users {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  lines := make([]string, len(names))
+  for i, name := range names {
+    lines[i] = a.users[name].describe()
+  }
+  return lines
+}
+
+// Categories trả về tên các category theo thứ tự chữ cái
+func (a *ACL) Categories() []string {
+  categories := make([]string, 0, len(a.categories))
+  for category := range a.categories {
+    categories = append(categories, category)
+  }
+  sort.Strings(categories)
+  return categories
+}
+
+// CommandsInCategory trả về tên (viết thường) các lệnh thuộc category, false nếu category không tồn tại
+func (a *ACL) CommandsInCategory(category string) ([]string, bool) {
+  category = strings.ToLower(category)
+  if !a.categories[category] {
+    return nil, false
+  }
+  var names []string
+  for name, categories := range a.commands {
+    for _, c := range categories {
+      if c == category {
+        names = append(names, strings.ToLower(name))
+        break
+      }
+    }
+  }
+  sort.Strings(names)
+  return names, true
+}
+
+// Authenticate kiểm tra user tồn tại, đang bật và password đúng (hoặc user không cần mật khẩu)
+func (a *ACL) Authenticate(name, password string) error {
+  a.mu.RLock()
+  defer a.mu.RUnlock()
+  u, ok := a.users[name]
+  if !ok || !u.enabled {
+    return ErrWrongPass
+  }
+  if u.nopass {
+    return nil
+  }
+
+  sum := sha256.Sum256([]byte(password))
+  hash := []byte(hex.EncodeToString(sum[:]))
+  match := 0
+  for _, stored := range u.passwords {
+    match |= subtle.ConstantTimeCompare(hash, []byte(stored))
+  }
+  if match != 1 {
+    return ErrWrongPass
+  }
+  return nil
+}
+
+// NoPass cho biết user name đang bật và không cần mật khẩu
+func (a *ACL) NoPass(name string) bool {
+  a.mu.RLock()
+  defer a.mu.RUnlock()
+  u, ok := a.users[name]
+  return ok && u.enabled && u.nopass
+}
+
+// Check kiểm tra user name có được chạy lệnh cmd (lệnh con sub, "" nếu không có) trên các key keys không.
+// Trả về ErrNoUser nếu user đã bị xóa, *DeniedError nếu không có quyền.
+func (a *ACL) Check(name, cmd, sub string, keys []string) error {
+  a.mu.RLock()
+  defer a.mu.RUnlock()
+  u, ok := a.users[name]
+  if !ok {
+    return ErrNoUser
+  }
+
+  // Lệnh có lệnh con được phân quyền theo từng lệnh con. Lệnh và lệnh con không tồn tại được để
+  // server trả lỗi "unknown command".
+  cmd = strings.ToUpper(cmd)
+  unit := cmd
+  if _, ok := a.subs[cmd]; ok {
+    unit = cmd + "|" + strings.ToUpper(sub)
+  }
+  if _, known := a.commands[unit]; !known {
+    return nil
+  }
+  if !u.allowed[unit] {
+    return &DeniedError{User: name, Command: strings.ToLower(unit)}
+  }
+  for _, key := range keys {
+    if !u.keyAllowed(key) {
+      return &DeniedError{User: name, Command: strings.ToLower(unit), Key: key}
+    }
+  }
+  return nil
+}
@@ -0,0 +1,86 @@
+package acl
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "os"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// Load đọc file ACL và thay toàn bộ user bằng các user trong file. Mỗi dòng có dạng
+// "user <tên> <quy tắc...>"; dòng trống và dòng bắt đầu bằng "#" được bỏ qua. Nếu file có lỗi,
+// các user hiện tại được giữ nguyên. User default được tạo lại với mọi quyền nếu file không khai báo.
+func (a *ACL) Load() error {
+  a.mu.Lock()
+  defer a.mu.Unlock()
+  if a.file == "" {
+    return errors.New("no ACL file configured")
+  }
+
+  f, err := os.Open(a.file)
+  if err != nil {
+    return err
+  }
+  defer f.Close()
+
+  users := make(map[string]*user)
+  scanner := bufio.NewScanner(f)
+  scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+  for lineNum := 1; scanner.Scan(); lineNum++ {
+    text := strings.TrimSpace(scanner.Text())
+    if text == "" || text[0] == '#' {
+      continue
+    }
+
+    fields, err := protocol.SplitArgs(text)
+    if err != nil {
+      return fmt.Errorf("%s:%d: %v", a.file, lineNum, err)
+    }
+    if len(fields) < 2 || fields[0] != "user" {
+      return fmt.Errorf("%s:%d: should start with user keyword followed by the username", a.file, lineNum)
+    }
+    name := fields[1]
+    if strings.ContainsAny(name, " \t\r\n") {
+      return fmt.Errorf("%s:%d: usernames can't contain spaces or null characters", a.file, lineNum)
+    }
+    if _, dup := users[name]; dup {
+      return fmt.Errorf("%s:%d: duplicate user '%s'", a.file, lineNum, name)
+    }
+
+    u := a.newUser(name)
+    for _, rule := range fields[2:] {
+      if err := u.apply(a, rule); err != nil {
+        return fmt.Errorf("%s:%d: error in user declaration '%s' at rule '%s': %v", a.file, lineNum, name, rule, err)
+      }
+    }
+    users[name] = u
+  }
+  if err := scanner.Err(); err != nil {
+    return err
+  }
+
+  if _, ok := users[DefaultUser]; !ok {
+    users[DefaultUser] = a.newDefaultUser()
+  }
+  a.users = users
+  return nil
+}
+
+// Save ghi mọi user ra file ACL (ghi file tạm rồi đổi tên để file cũ không bị hỏng nếu có lỗi)
+func (a *ACL) Save() error {
+  a.mu.RLock()
+  defer a.mu.RUnlock()
+  if a.file == "" {
+    return errors.New("no ACL file configured")
+  }
+
+  content := strings.Join(a.listLocked(), "\n") + "\n"
+  tmpPath := a.file + ".tmp"
+  if err := os.WriteFile(tmpPath, []byte(content), 0600); err != nil {
+    return err
+  }
+  return os.Rename(tmpPath, a.file)
+}
@@ -0,0 +1,225 @@
+package acl
+
+import (
+  "crypto/sha256"
+  "encoding/hex"
+  "errors"
+  "maps"
+  "slices"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// user là một user của ACL. Quyền chạy lệnh được tính sẵn cho từng lệnh (allowed) mỗi khi quy tắc
+// thay đổi; commandRules giữ các quy tắc đã áp dụng để mô tả lại user (ACL LIST, file ACL).
+type user struct {
+  name         string
+  enabled      bool
+  nopass       bool
+  passwords    []string        // SHA-256 dạng hex, theo thứ tự được thêm
+  allowed      map[string]bool // Tên lệnh (hoặc "LỆNH|CON") -> được phép
+  commandRules []string
+  keys         []string // Các mẫu glob của key được truy cập ("*" : mọi key)
+}
+
+// newUser tạo user mới: tắt, không có mật khẩu, không có quyền với lệnh và key nào (giống Redis)
+func (a *ACL) newUser(name string) *user {
+  return &user{
+    name:         name,
+    allowed:      make(map[string]bool),
+    commandRules: []string{"-@all"},
+  }
+}
+
+// clone sao chép user để áp dụng quy tắc mà không ảnh hưởng bản gốc khi có lỗi
+func (u *user) clone() *user {
+  c := *u
+  c.passwords = slices.Clone(u.passwords)
+  c.allowed = maps.Clone(u.allowed)
+  c.commandRules = slices.Clone(u.commandRules)
+  c.keys = slices.Clone(u.keys)
+  return &c
+}
+
+// apply áp dụng một quy tắc theo cú pháp ACL SETUSER của Redis
+func (u *user) apply(a *ACL, rule string) error {
+  if rule == "" {
+    return errors.New("Syntax error")
+  }
+
+  switch strings.ToLower(rule) {
+  case "on":
+    u.enabled = true
+    return nil
+  case "off":
+    u.enabled = false
+    return nil
+  case "nopass":
+    u.nopass, u.passwords = true, nil
+    return nil
+  case "resetpass":
+    u.nopass, u.passwords = false, nil
+    return nil
+  case "allkeys":
+    u.keys = []string{"*"}
+    return nil
+  case "resetkeys":
+    u.keys = nil
+    return nil
+  case "allcommands":
+    return u.apply(a, "+@all")
+  case "nocommands":
+    return u.apply(a, "-@all")
+  case "reset":
+    *u = *a.newUser(u.name)
+    return nil
+  }
+
+  switch arg := rule[1:]; rule[0] {
+  case '>':
+    sum := sha256.Sum256([]byte(arg))
+    u.addPassword(hex.EncodeToString(sum[:]))
+  case '#':
+    if !isPasswordHash(arg) {
+      return errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+    }
+    u.addPassword(arg)
+  case '<':
+    sum := sha256.Sum256([]byte(arg))
+    return u.removePassword(hex.EncodeToString(sum[:]))
+  case '!':
+    if !isPasswordHash(arg) {
+      return errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+    }
+    return u.removePassword(arg)
+  case '~':
+    if slices.Contains(u.keys, "*") {
+      return errors.New("Adding a pattern after the * pattern (or the 'allkeys' flag) is not valid and does not have any effect. Try 'resetkeys' to start with an empty list of patterns")
+    }
+    if arg == "*" {
+      u.keys = []string{"*"}
+    } else if !slices.Contains(u.keys, arg) {
+      u.keys = append(u.keys, arg)
+    }
+  case '+', '-':
+    return u.applyCommandRule(a, rule[0] == '+', strings.ToLower(arg))
+  default:
+    return errors.New("Syntax error")
+  }
+  return nil
+}
+
+// applyCommandRule áp dụng +/-@category, +/-lệnh hoặc +/-lệnh|con
+func (u *user) applyCommandRule(a *ACL, allow bool, arg string) error {
+  sign := "-"
+  if allow {
+    sign = "+"
+  }
+
+  var units []string
+  switch {
+  case arg == "@all":
+    // Quy tắc cho mọi lệnh thay thế mọi quy tắc trước đó
+    for name := range a.commands {
+      units = append(units, name)
+    }
+    u.commandRules = nil
+  case strings.HasPrefix(arg, "@"):
+    category := arg[1:]
+    if !a.categories[category] {
+      return errors.New("Unknown command or category name in ACL")
+    }
+    for name, categories := range a.commands {
+      if slices.Contains(categories, category) {
+        units = append(units, name)
+      }
+    }
+  default:
+    name := strings.ToUpper(arg)
+    if subs, ok := a.subs[name]; ok {
+      units = subs
+    } else if _, ok := a.commands[name]; ok {
+      units = []string{name}
+    } else {
+      return errors.New("Unknown command or category name in ACL")
+    }
+  }
+
+  for _, unit := range units {
+    if allow {
+      u.allowed[unit] = true
+    } else {
+      delete(u.allowed, unit)
+    }
+  }
+  u.commandRules = append(u.commandRules, sign+arg)
+  return nil
+}
+
+// addPassword thêm một mật khẩu (đã băm); thêm mật khẩu nghĩa là user không còn nopass
+func (u *user) addPassword(hash string) {
+  u.nopass = false
+  if !slices.Contains(u.passwords, hash) {
+    u.passwords = append(u.passwords, hash)
+  }
+}
+
+// removePassword xóa một mật khẩu (đã băm)
+func (u *user) removePassword(hash string) error {
+  i := slices.Index(u.passwords, hash)
+  if i < 0 {
+    return errors.New("The password you are trying to remove from the user does not exist")
+  }
+  u.passwords = slices.Delete(u.passwords, i, i+1)
+  return nil
+}
+
+// keyAllowed kiểm tra key khớp với một trong các mẫu của user
+func (u *user) keyAllowed(key string) bool {
+  for _, pattern := range u.keys {
+    if pattern == "*" || store.GlobMatch(pattern, key) {
+      return true
+    }
+  }
+  return false
+}
+
+// keyRules trả về các mẫu key theo cú pháp quy tắc ("~pattern")
+func (u *user) keyRules() []string {
+  rules := make([]string, len(u.keys))
+  for i, pattern := range u.keys {
+    rules[i] = "~" + pattern
+  }
+  return rules
+}
+
+// describe mô tả user theo cú pháp của file ACL; mật khẩu được ghi dưới dạng băm (#hash)
+func (u *user) describe() string {
+  parts := []string{"user", u.name, "off"}
+  if u.enabled {
+    parts[2] = "on"
+  }
+  if u.nopass {
+    parts = append(parts, "nopass")
+  }
+  for _, hash := range u.passwords {
+    parts = append(parts, "#"+hash)
+  }
+  parts = append(parts, u.keyRules()...)
+  parts = append(parts, u.commandRules...)
+  return strings.Join(parts, " ")
+}
+
+// isPasswordHash kiểm tra s là SHA-256 dạng hex viết thường
+func isPasswordHash(s string) bool {
+  if len(s) != 64 {
+    return false
+  }
+  for i := 0; i < len(s); i++ {
+    if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+      return false
+    }
+  }
+  return true
+}
@@ -0,0 +1,589 @@
+package config
+
+import (
+  "bufio"
+  "errors"
+  "flag"
+  "fmt"
+  "net"
+  "os"
+  "path/filepath"
+  "sort"
+  "strconv"
+  "strings"
+  "sync"
+
+  "mnhgo/mnh-go-kv-store/internal/logging"
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// Settings là toàn bộ giá trị cấu hình của server tại một thời điểm
+type Settings struct {
+  Bind string // Địa chỉ lắng nghe ("" : mọi địa chỉ)
+  Port int    // Cổng TCP, 0: không nhận kết nối TCP không mã hóa
+  Dir  string // Thư mục làm việc, chứa AOF và snapshot RDB
+
+  TLSPort        int // Cổng TLS, 0: tắt TLS
+  TLSCertFile    string
+  TLSKeyFile     string
+  TLSCACertFile  string
+  TLSAuthClients string // Chứng chỉ của client: "yes" (bắt buộc), "optional" hoặc "no"
+
+  UnixSocket     string      // Đường dẫn Unix socket ("" : không dùng)
+  UnixSocketPerm os.FileMode // Quyền của file socket (0: theo umask)
+
+  AppendOnly               bool // Bật AOF
+  AppendDirName            string
+  AppendFilename           string
+  AppendFsync              store.FsyncPolicy
+  AOFUseRDBPreamble        bool
+  AOFLoadTruncated         bool
+  AutoAOFRewritePercentage int
+  AutoAOFRewriteMinSize    int64
+
+  DBFilename string
+  Save       []store.SaveRule
+
+  MaxMemory   int64 // Giới hạn bộ nhớ (byte), 0: không giới hạn
+  RequirePass string
+  ACLFile     string // File chứa các user ACL ("" : không dùng)
+  LogLevel    logging.Level
+}
+
+// Addr trả về địa chỉ TCP để lắng nghe, ví dụ ":6379" hoặc "127.0.0.1:6380"
+func (s Settings) Addr() string {
+  return net.JoinHostPort(s.Bind, strconv.Itoa(s.Port))
+}
+
+// TLSAddr trả về địa chỉ để lắng nghe kết nối TLS, ví dụ ":6380"
+func (s Settings) TLSAddr() string {
+  return net.JoinHostPort(s.Bind, strconv.Itoa(s.TLSPort))
+}
+
+// defaults trả về cấu hình mặc định
+func defaults() Settings {
+  rules, _ := store.ParseSaveRules(store.DefaultSaveRules)
+  return Settings{
+    Port:                     6379,
+    TLSAuthClients:           "yes",
+    Dir:                      ".",
+    AppendOnly:               true,
+    AppendDirName:            "appendonlydir",
+    AppendFilename:           "database.aof",
+    AppendFsync:              store.FsyncEverySec,
+    AOFUseRDBPreamble:        true,
+    AOFLoadTruncated:         true,
+    AutoAOFRewritePercentage: store.DefaultRewritePercentage,
+    AutoAOFRewriteMinSize:    store.DefaultRewriteMinSize,
+    DBFilename:               "dump.rdb",
+    Save:                     rules,
+    LogLevel:                 logging.LevelNotice,
+  }
+}
+
+// param mô tả một tham số cấu hình: tên (dùng chung cho file cấu hình, flag và CONFIG),
+// cách đọc/ghi giá trị dạng chuỗi và có được thay đổi khi server đang chạy hay không
+type param struct {
+  name    string
+  usage   string
+  mutable bool
+  get     func(s *Settings) string
+  set     func(s *Settings, value string) error
+}
+
+// params liệt kê các tham số theo thứ tự hiển thị
+var params = []param{
+  {"bind", "address to listen on (empty: all interfaces)", false,
+    func(s *Settings) string { return s.Bind },
+    func(s *Settings, v string) error { s.Bind = v; return nil }},
+  {"port", "TCP port to listen on (0: no plain TCP listener)", false,
+    func(s *Settings) string { return strconv.Itoa(s.Port) },
+    func(s *Settings, v string) error { return parseInt(v, 0, 65535, &s.Port) }},
+  {"unixsocket", "Unix socket path to listen on (empty: no Unix socket)", false,
+    func(s *Settings) string { return s.UnixSocket },
+    func(s *Settings, v string) error { s.UnixSocket = v; return nil }},
+  {"unixsocketperm", "octal permissions of the Unix socket file, e.g. 700 (0: use the umask)", false,
+    func(s *Settings) string { return strconv.FormatUint(uint64(s.UnixSocketPerm), 8) },
+    func(s *Settings, v string) error { return parsePerm(v, &s.UnixSocketPerm) }},
+  {"tls-port", "TLS port to listen on (0 disables TLS)", false,
+    func(s *Settings) string { return strconv.Itoa(s.TLSPort) },
+    func(s *Settings, v string) error { return parseInt(v, 0, 65535, &s.TLSPort) }},
+  {"tls-cert-file", "PEM certificate (chain) of the server", false,
+    func(s *Settings) string { return s.TLSCertFile },
+    func(s *Settings, v string) error { s.TLSCertFile = v; return nil }},
+  {"tls-key-file", "PEM private key of the server certificate", false,
+    func(s *Settings) string { return s.TLSKeyFile },
+    func(s *Settings, v string) error { s.TLSKeyFile = v; return nil }},
+  {"tls-ca-cert-file", "PEM CA certificates used to verify client certificates", false,
+    func(s *Settings) string { return s.TLSCACertFile },
+    func(s *Settings, v string) error { s.TLSCACertFile = v; return nil }},
+  {"tls-auth-clients", "client certificates: yes (required), optional or no", false,
+    func(s *Settings) string { return s.TLSAuthClients },
+    func(s *Settings, v string) error { return oneOf(v, []string{"yes", "optional", "no"}, &s.TLSAuthClients) }},
+  {"dir", "working directory for the AOF and RDB files", false,
+    func(s *Settings) string { return s.Dir },
+    func(s *Settings, v string) error { return nonEmpty(v, &s.Dir) }},
+  {"appendonly", "enable the append only file (yes/no)", false,
+    func(s *Settings) string { return formatBool(s.AppendOnly) },
+    func(s *Settings, v string) error { return parseBool(v, &s.AppendOnly) }},
+  {"appenddirname", "directory holding the AOF base, incremental files and manifest", false,
+    func(s *Settings) string { return s.AppendDirName },
+    func(s *Settings, v string) error { return fileName(v, &s.AppendDirName) }},
+  {"appendfilename", "base name of the AOF files", false,
+    func(s *Settings) string { return s.AppendFilename },
+    func(s *Settings, v string) error { return fileName(v, &s.AppendFilename) }},
+  {"appendfsync", "AOF fsync policy: always, everysec or no", true,
+    func(s *Settings) string { return s.AppendFsync.String() },
+    func(s *Settings, v string) (err error) { s.AppendFsync, err = store.ParseFsyncPolicy(v); return err }},
+  {"aof-use-rdb-preamble", "write the AOF base as a binary snapshot when rewriting (yes/no)", true,
+    func(s *Settings) string { return formatBool(s.AOFUseRDBPreamble) },
+    func(s *Settings, v string) error { return parseBool(v, &s.AOFUseRDBPreamble) }},
+  {"aof-load-truncated", "trim an incomplete last command from the AOF on startup instead of refusing to start (yes/no)", true,
+    func(s *Settings) string { return formatBool(s.AOFLoadTruncated) },
+    func(s *Settings, v string) error { return parseBool(v, &s.AOFLoadTruncated) }},
+  {"auto-aof-rewrite-percentage", "rewrite the AOF when it grows by this percentage (0 disables)", true,
+    func(s *Settings) string { return strconv.Itoa(s.AutoAOFRewritePercentage) },
+    func(s *Settings, v string) error { return parseInt(v, 0, 1<<31-1, &s.AutoAOFRewritePercentage) }},
+  {"auto-aof-rewrite-min-size", "minimum AOF size for an automatic rewrite (e.g. 64mb)", true,
+    func(s *Settings) string { return strconv.FormatInt(s.AutoAOFRewriteMinSize, 10) },
+    func(s *Settings, v string) error { return parseMemoryInto(v, &s.AutoAOFRewriteMinSize) }},
+  {"dbfilename", "RDB snapshot file", false,
+    func(s *Settings) string { return s.DBFilename },
+    func(s *Settings, v string) error { return fileName(v, &s.DBFilename) }},
+  {"save", `RDB save rules as "<seconds> <changes>" pairs ("" disables automatic saves)`, true,
+    func(s *Settings) string { return store.FormatSaveRules(s.Save) },
+    func(s *Settings, v string) (err error) { s.Save, err = store.ParseSaveRules(v); return err }},
+  {"maxmemory", "memory limit (e.g. 100mb, 0: unlimited); writes that need memory are rejected above it", true,
+    func(s *Settings) string { return strconv.FormatInt(s.MaxMemory, 10) },
+    func(s *Settings, v string) error { return parseMemoryInto(v, &s.MaxMemory) }},
+  {"requirepass", "password clients must send with AUTH (empty: no authentication)", true,
+    func(s *Settings) string { return s.RequirePass },
+    func(s *Settings, v string) error { s.RequirePass = v; return nil }},
+  {"aclfile", "file holding the ACL users, loaded at startup and by ACL LOAD, written by ACL SAVE", false,
+    func(s *Settings) string { return s.ACLFile },
+    func(s *Settings, v string) error { s.ACLFile = v; return nil }},
+  {"loglevel", "log verbosity: debug, verbose, notice or warning", true,
+    func(s *Settings) string { return s.LogLevel.String() },
+    func(s *Settings, v string) (err error) { s.LogLevel, err = logging.ParseLevel(v); return err }},
+}
+
+// lookup tìm tham số theo tên (không phân biệt hoa thường)
+func lookup(name string) (*param, bool) {
+  name = strings.ToLower(name)
+  for i := range params {
+    if params[i].name == name {
+      return &params[i], true
+    }
+  }
+  return nil, false
+}
+
+// Config quản lý cấu hình của server, an toàn khi dùng đồng thời
+type Config struct {
+  mu       sync.RWMutex
+  settings Settings
+  file     string // Đường dẫn tuyệt đối của file cấu hình ("" nếu không dùng)
+}
+
+// New tạo cấu hình với các giá trị mặc định
+func New() *Config {
+  return &Config{settings: defaults()}
+}
+
+// Settings trả về bản sao của cấu hình hiện tại
+func (c *Config) Settings() Settings {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  return c.settings
+}
+
+// File trả về đường dẫn file cấu hình ("" nếu server chạy không có file cấu hình)
+func (c *Config) File() string {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  return c.file
+}
+
+// Set đặt giá trị của một tham số khi khởi động (không kiểm tra tham số có được thay đổi khi chạy không)
+func (c *Config) Set(name, value string) error {
+  p, ok := lookup(name)
+  if !ok {
+    return fmt.Errorf("unknown config parameter '%s'", name)
+  }
+
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  if err := p.set(&c.settings, value); err != nil {
+    return fmt.Errorf("invalid value for '%s': %v", p.name, err)
+  }
+  return nil
+}
+
+// SetError là lỗi của CONFIG SET, kèm tên tham số gây lỗi
+type SetError struct {
+  Param string
+  Err   error
+}
+
+func (e *SetError) Error() string {
+  return fmt.Sprintf("ERR CONFIG SET failed (possibly related to argument '%s') - %v", e.Param, e.Err)
+}
+
+// SetRuntime đặt nhiều tham số cùng lúc khi server đang chạy (CONFIG SET name value [name value ...]).
+// Các giá trị được kiểm tra hết trước khi áp dụng: nếu có một tham số sai thì không tham số nào thay đổi.
+func (c *Config) SetRuntime(pairs ...string) error {
+  if len(pairs)%2 != 0 {
+    return errors.New("ERR wrong number of arguments for 'config|set' command")
+  }
+
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  updated := c.settings
+  seen := make(map[string]bool)
+  for i := 0; i < len(pairs); i += 2 {
+    p, ok := lookup(pairs[i])
+    if !ok {
+      return &SetError{Param: pairs[i], Err: errors.New("unknown option or number of arguments for CONFIG SET")}
+    }
+    if seen[p.name] {
+      return &SetError{Param: p.name, Err: errors.New("duplicate parameter")}
+    }
+    seen[p.name] = true
+    if !p.mutable {
+      return &SetError{Param: p.name, Err: errors.New("can't set immutable config")}
+    }
+    if err := p.set(&updated, pairs[i+1]); err != nil {
+      return &SetError{Param: p.name, Err: err}
+    }
+  }
+  c.settings = updated
+  return nil
+}
+
+// Get trả về các cặp (tên, giá trị) của những tham số khớp với một trong các mẫu glob, theo thứ tự tên
+func (c *Config) Get(patterns ...string) [][2]string {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+
+  var result [][2]string
+  for i := range params {
+    p := &params[i]
+    for _, pattern := range patterns {
+      if store.GlobMatch(strings.ToLower(pattern), p.name) {
+        result = append(result, [2]string{p.name, p.get(&c.settings)})
+        break
+      }
+    }
+  }
+  sort.Slice(result, func(i, j int) bool { return result[i][0] < result[j][0] })
+  return result
+}
+
+// LoadFile đọc file cấu hình theo cú pháp của redis.conf: mỗi dòng "tên giá-trị...",
+// dòng trống và dòng bắt đầu bằng '#' được bỏ qua, giá trị có thể đặt trong dấu nháy.
+// Nhiều dòng "save" được cộng dồn; save "" xóa các quy tắc trước đó.
+func (c *Config) LoadFile(path string) error {
+  abs, err := filepath.Abs(path)
+  if err != nil {
+    return err
+  }
+  f, err := os.Open(abs)
+  if err != nil {
+    return err
+  }
+  defer f.Close()
+
+  var saveSpecs []string
+  saveSeen := false
+  scanner := bufio.NewScanner(f)
+  for line := 1; scanner.Scan(); line++ {
+    args, name, err := parseLine(scanner.Text())
+    if err != nil {
+      return fmt.Errorf("%s:%d: %v", path, line, err)
+    }
+    if name == "" {
+      continue
+    }
+
+    value := strings.Join(args, " ")
+    if name == "save" {
+      if !saveSeen || value == "" {
+        saveSpecs = nil
+      }
+      saveSeen = true
+      if value != "" {
+        saveSpecs = append(saveSpecs, value)
+      }
+      continue
+    }
+    if err := c.Set(name, value); err != nil {
+      return fmt.Errorf("%s:%d: %v", path, line, err)
+    }
+  }
+  if err := scanner.Err(); err != nil {
+    return err
+  }
+  if saveSeen {
+    if err := c.Set("save", strings.Join(saveSpecs, " ")); err != nil {
+      return fmt.Errorf("%s: %v", path, err)
+    }
+  }
+
+  c.mu.Lock()
+  c.file = abs
+  c.mu.Unlock()
+  return nil
+}
+
+// parseLine tách một dòng của file cấu hình thành tên tham số (chữ thường) và các đối số.
+// Trả về tên rỗng với dòng trống hoặc chú thích.
+func parseLine(text string) ([]string, string, error) {
+  text = strings.TrimSpace(text)
+  if text == "" || strings.HasPrefix(text, "#") {
+    return nil, "", nil
+  }
+  args, err := protocol.SplitArgs(text)
+  if err != nil {
+    return nil, "", err
+  }
+  name := strings.ToLower(args[0])
+  if _, ok := lookup(name); !ok {
+    return nil, "", fmt.Errorf("bad directive '%s'", args[0])
+  }
+  return args[1:], name, nil
+}
+
+// BindFlags đăng ký một flag cho mỗi tham số (ví dụ -port 6380, -appendonly no).
+// Các flag được ghi nhận khi parse và chỉ được áp dụng khi gọi hàm trả về, để flag luôn
+// ghi đè giá trị trong file cấu hình bất kể thứ tự.
+func (c *Config) BindFlags(fs *flag.FlagSet) func() error {
+  var overrides [][2]string
+  def := defaults()
+  for i := range params {
+    p := &params[i]
+    usage := fmt.Sprintf("%s (default %q)", p.usage, p.get(&def))
+    fs.Func(p.name, usage, func(value string) error {
+      overrides = append(overrides, [2]string{p.name, value})
+      return nil
+    })
+  }
+
+  return func() error {
+    for _, o := range overrides {
+      if err := c.Set(o[0], o[1]); err != nil {
+        return err
+      }
+    }
+    return nil
+  }
+}
+
+// Rewrite ghi cấu hình hiện tại vào file cấu hình (CONFIG REWRITE): dòng của các tham số đã có
+// được thay bằng giá trị hiện tại, chú thích và các dòng khác được giữ nguyên, tham số chưa có
+// trong file mà khác giá trị mặc định được thêm vào cuối. File được thay thế một cách nguyên tử.
+func (c *Config) Rewrite() error {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+
+  if c.file == "" {
+    return errors.New("ERR The server is running without a config file")
+  }
+
+  content, err := os.ReadFile(c.file)
+  if err != nil && !errors.Is(err, os.ErrNotExist) {
+    return err
+  }
+
+  var out []string
+  written := make(map[string]bool)
+  lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+  if len(content) == 0 {
+    lines = nil
+  }
+  for _, text := range lines {
+    _, name, err := parseLine(text)
+    if err != nil || name == "" {
+      out = append(out, text)
+      continue
+    }
+    // Chỉ giữ lần xuất hiện đầu tiên của mỗi tham số (ví dụ nhiều dòng "save")
+    if written[name] {
+      continue
+    }
+    written[name] = true
+    p, _ := lookup(name)
+    out = append(out, formatLine(p, &c.settings))
+  }
+
+  def := defaults()
+  header := false
+  for i := range params {
+    p := &params[i]
+    if written[p.name] || p.get(&c.settings) == p.get(&def) {
+      continue
+    }
+    if !header {
+      out = append(out, "# Generated by CONFIG REWRITE")
+      header = true
+    }
+    out = append(out, formatLine(p, &c.settings))
+  }
+
+  tmpPath := c.file + ".tmp"
+  if err := os.WriteFile(tmpPath, []byte(strings.Join(out, "\n")+"\n"), 0644); err != nil {
+    return err
+  }
+  return os.Rename(tmpPath, c.file)
+}
+
+// formatLine tạo dòng cấu hình "tên giá-trị" cho tham số p
+func formatLine(p *param, s *Settings) string {
+  value := p.get(s)
+  // Quy tắc save được ghi thành các cặp số không cần dấu nháy, trừ khi rỗng
+  if p.name == "save" && value != "" {
+    return p.name + " " + value
+  }
+  return p.name + " " + quoteArg(value)
+}
+
+// quoteArg đặt giá trị trong dấu nháy kép khi cần để SplitArgs đọc lại đúng giá trị đó
+func quoteArg(value string) string {
+  if value != "" && !strings.ContainsAny(value, " \t\r\n\v\f\"'\\") {
+    return value
+  }
+  var b strings.Builder
+  b.WriteByte('"')
+  for i := 0; i < len(value); i++ {
+    switch c := value[i]; c {
+    case '\\', '"':
+      b.WriteByte('\\')
+      b.WriteByte(c)
+    case '\n':
+      b.WriteString(`\n`)
+    case '\r':
+      b.WriteString(`\r`)
+    case '\t':
+      b.WriteString(`\t`)
+    default:
+      if c < 0x20 || c == 0x7f {
+        fmt.Fprintf(&b, `\x%02x`, c)
+      } else {
+        b.WriteByte(c)
+      }
+    }
+  }
+  b.WriteByte('"')
+  return b.String()
+}
+
+// parseInt đọc số nguyên trong khoảng [min, max]
+func parseInt(value string, min, max int, dst *int) error {
+  n, err := strconv.Atoi(value)
+  if err != nil {
+    return fmt.Errorf("argument couldn't be parsed into an integer")
+  }
+  if n < min || n > max {
+    return fmt.Errorf("argument must be between %d and %d inclusive", min, max)
+  }
+  *dst = n
+  return nil
+}
+
+// parseBool đọc giá trị yes/no (chấp nhận cả true/false)
+func parseBool(value string, dst *bool) error {
+  switch strings.ToLower(value) {
+  case "yes", "true":
+    *dst = true
+  case "no", "false":
+    *dst = false
+  default:
+    return fmt.Errorf("argument must be 'yes' or 'no'")
+  }
+  return nil
+}
+
+// formatBool trả về "yes" hoặc "no"
+func formatBool(b bool) string {
+  if b {
+    return "yes"
+  }
+  return "no"
+}
+
+// oneOf kiểm tra giá trị là một trong các lựa chọn (không phân biệt hoa thường)
+func oneOf(value string, choices []string, dst *string) error {
+  value = strings.ToLower(value)
+  for _, choice := range choices {
+    if value == choice {
+      *dst = value
+      return nil
+    }
+  }
+  return fmt.Errorf("argument must be one of: %s", strings.Join(choices, ", "))
+}
+
+// parsePerm đọc quyền truy cập file dạng bát phân (ví dụ 700 hoặc 0770)
+func parsePerm(value string, dst *os.FileMode) error {
+  n, err := strconv.ParseUint(value, 8, 32)
+  if err != nil || n > 0777 {
+    return fmt.Errorf("argument must be an octal permission between 0 and 777")
+  }
+  *dst = os.FileMode(n)
+  return nil
+}
+
+// nonEmpty kiểm tra giá trị không rỗng
+func nonEmpty(value string, dst *string) error {
+  if value == "" {
+    return fmt.Errorf("argument can't be empty")
+  }
+  *dst = value
+  return nil
+}
+
+// fileName kiểm tra giá trị là một tên file đơn (không chứa đường dẫn)
+func fileName(value string, dst *string) error {
+  if value == "" || value == "." || value == ".." || value != filepath.Base(value) {
+    return fmt.Errorf("'%s' is not a valid file name", value)
+  }
+  *dst = value
+  return nil
+}
+
+// ParseMemory đọc dung lượng bộ nhớ theo cách viết của redis.conf:
+// 1k = 1000, 1kb = 1024, 1m = 1000000, 1mb = 1024*1024, 1g = 1000000000, 1gb = 1024*1024*1024
+func ParseMemory(value string) (int64, error) {
+  units := []struct {
+    suffix string
+    factor int64
+  }{
+    {"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+    {"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+    {"b", 1},
+  }
+
+  num, factor := strings.ToLower(value), int64(1)
+  for _, u := range units {
+    if strings.HasSuffix(num, u.suffix) {
+      num, factor = strings.TrimSuffix(num, u.suffix), u.factor
+      break
+    }
+  }
+  n, err := strconv.ParseInt(num, 10, 64)
+  if err != nil || n < 0 || n > (1<<63-1)/factor {
+    return 0, fmt.Errorf("argument must be a memory value")
+  }
+  return n * factor, nil
+}
+
+// parseMemoryInto đọc dung lượng bộ nhớ vào dst
+func parseMemoryInto(value string, dst *int64) error {
+  n, err := ParseMemory(value)
+  if err != nil {
+    return err
+  }
+  *dst = n
+  return nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+  "flag"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+// writeConfig ghi nội dung file cấu hình vào thư mục tạm của test
+func writeConfig(t *testing.T, content string) string {
+  t.Helper()
+  path := filepath.Join(t.TempDir(), "mnh-kv.conf")
+  if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+    t.Fatal(err)
+  }
+  return path
+}
+
+// values trả về giá trị dạng chuỗi của các tham số như CONFIG GET
+func values(c *Config, names ...string) map[string]string {
+  result := make(map[string]string)
+  for _, pair := range c.Get(names...) {
+    result[pair[0]] = pair[1]
+  }
+  return result
+}
+
+func TestLoadFile(t *testing.T) {
+  tests := []struct {
+    name    string
+    content string
+    want    map[string]string // nil: file lỗi
+  }{
+    {"values", "# comment\n\nport 6380\n  BIND 127.0.0.1  \nappendonly no\nmaxmemory 100mb\nunixsocketperm 0770\n",
+      map[string]string{"port": "6380", "bind": "127.0.0.1", "appendonly": "no", "maxmemory": "104857600", "unixsocketperm": "770"}},
+    {"quoted values", "requirepass \"p a\\\"ss\"\ndir 'data dir'\n",
+      map[string]string{"requirepass": `p a"ss`, "dir": "data dir"}},
+    {"empty quoted value", "requirepass \"\"\n", map[string]string{"requirepass": ""}},
+    {"save lines are accumulated", "save 900 1\nsave 60 10000\n", map[string]string{"save": "900 1 60 10000"}},
+    {"save \"\" clears earlier rules", "save 900 1\nsave \"\"\nsave 30 5\n", map[string]string{"save": "30 5"}},
+    {"save \"\" disables saving", "save \"\"\n", map[string]string{"save": ""}},
+    {"unknown directive", "port 6380\nmaxclients 10\n", nil},
+    {"port out of range", "port 70000\n", nil},
+    {"bad bool", "appendonly maybe\n", nil},
+    {"bad fsync policy", "appendfsync sometimes\n", nil},
+    {"bad memory", "maxmemory lots\n", nil},
+    {"bad permission", "unixsocketperm 999\n", nil},
+    {"path as file name", "dbfilename ../dump.rdb\n", nil},
+    {"empty dir", "dir \"\"\n", nil},
+    {"unbalanced quotes", "requirepass \"secret\n", nil},
+    {"bad save rule", "save 900\n", nil},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      c := New()
+      path := writeConfig(t, tt.content)
+      err := c.LoadFile(path)
+      if tt.want == nil {
+        if err == nil {
+          t.Fatalf("LoadFile() accepted %q", tt.content)
+        }
+        // Lỗi chỉ ra file và dòng gây lỗi
+        if !strings.Contains(err.Error(), path) {
+          t.Errorf("LoadFile() error %q does not name the file", err)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatal(err)
+      }
+      names := make([]string, 0, len(tt.want))
+      for name := range tt.want {
+        names = append(names, name)
+      }
+      for name, got := range values(c, names...) {
+        if got != tt.want[name] {
+          t.Errorf("%s = %q, want %q", name, got, tt.want[name])
+        }
+      }
+      if c.File() != path {
+        t.Errorf("File() = %q, want %q", c.File(), path)
+      }
+    })
+  }
+}
+
+func TestBindFlagsOverrideFile(t *testing.T) {
+  c := New()
+  fs := flag.NewFlagSet("test", flag.ContinueOnError)
+  apply := c.BindFlags(fs)
+  if err := fs.Parse([]string{"-port", "7000", "-appendfsync", "always"}); err != nil {
+    t.Fatal(err)
+  }
+  // Flag ghi đè file cấu hình dù file được đọc sau khi parse flag
+  if err := c.LoadFile(writeConfig(t, "port 6380\nmaxmemory 1kb\n")); err != nil {
+    t.Fatal(err)
+  }
+  if err := apply(); err != nil {
+    t.Fatal(err)
+  }
+  want := map[string]string{"port": "7000", "appendfsync": "always", "maxmemory": "1024"}
+  for name, got := range values(c, "port", "appendfsync", "maxmemory") {
+    if got != want[name] {
+      t.Errorf("%s = %q, want %q", name, got, want[name])
+    }
+  }
+
+  fs = flag.NewFlagSet("test", flag.ContinueOnError)
+  apply = New().BindFlags(fs)
+  if err := fs.Parse([]string{"-port", "port"}); err != nil {
+    t.Fatal(err)
+  }
+  if err := apply(); err == nil {
+    t.Error("an invalid flag value was accepted")
+  }
+}
+
+func TestRewrite(t *testing.T) {
+  path := writeConfig(t, "# keep this comment\nport 6380\nsave 900 1\nsave 60 100\nmaxmemory 1mb\n")
+  c := New()
+  if err := c.LoadFile(path); err != nil {
+    t.Fatal(err)
+  }
+  if err := c.SetRuntime("maxmemory", "2mb", "requirepass", "two words", "save", ""); err != nil {
+    t.Fatal(err)
+  }
+  if err := c.Rewrite(); err != nil {
+    t.Fatal(err)
+  }
+
+  content, err := os.ReadFile(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := "# keep this comment\nport 6380\nsave \"\"\nmaxmemory 2097152\n# Generated by CONFIG REWRITE\nrequirepass \"two words\"\n"
+  if string(content) != want {
+    t.Fatalf("rewritten file:\n%s\nwant:\n%s", content, want)
+  }
+
+  // Đọc lại file đã ghi cho cùng cấu hình
+  reloaded := New()
+  if err := reloaded.LoadFile(path); err != nil {
+    t.Fatal(err)
+  }
+  got := values(reloaded, "*")
+  for name, value := range values(c, "*") {
+    if got[name] != value {
+      t.Errorf("%s after reloading = %q, want %q", name, got[name], value)
+    }
+  }
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+  "crypto/tls"
+  "crypto/x509"
+  "errors"
+  "fmt"
+  "os"
+)
+
+// TLSConfig tạo cấu hình TLS cho listener từ tls-cert-file, tls-key-file, tls-ca-cert-file và
+// tls-auth-clients. Với tls-auth-clients yes/optional, chứng chỉ của client được xác minh bằng
+// các CA trong tls-ca-cert-file (mutual TLS).
+func (s Settings) TLSConfig() (*tls.Config, error) {
+  if s.TLSCertFile == "" || s.TLSKeyFile == "" {
+    return nil, errors.New("tls-cert-file and tls-key-file are required when tls-port is set")
+  }
+  cert, err := tls.LoadX509KeyPair(s.TLSCertFile, s.TLSKeyFile)
+  if err != nil {
+    return nil, fmt.Errorf("loading the server certificate: %w", err)
+  }
+  config := &tls.Config{
+    Certificates: []tls.Certificate{cert},
+    MinVersion:   tls.VersionTLS12,
+  }
+
+  switch s.TLSAuthClients {
+  case "no":
+    config.ClientAuth = tls.NoClientCert
+    return config, nil
+  case "optional":
+    config.ClientAuth = tls.VerifyClientCertIfGiven
+  default:
+    config.ClientAuth = tls.RequireAndVerifyClientCert
+  }
+
+  if s.TLSCACertFile == "" {
+    return nil, errors.New("tls-ca-cert-file is required to verify client certificates (or set tls-auth-clients no)")
+  }
+  pool, err := LoadCertPool(s.TLSCACertFile)
+  if err != nil {
+    return nil, err
+  }
+  config.ClientCAs = pool
+  return config, nil
+}
+
+// LoadCertPool đọc các chứng chỉ CA dạng PEM trong file path
+func LoadCertPool(path string) (*x509.CertPool, error) {
+  pem, err := os.ReadFile(path)
+  if err != nil {
+    return nil, fmt.Errorf("loading the CA certificates: %w", err)
+  }
+  pool := x509.NewCertPool()
+  if !pool.AppendCertsFromPEM(pem) {
+    return nil, fmt.Errorf("no PEM certificate found in %s", path)
+  }
+  return pool, nil
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+  "fmt"
+  "log"
+  "strings"
+  "sync/atomic"
+)
+
+// Level là mức độ chi tiết của log (giống loglevel của Redis)
+type Level int32
+
+const (
+  LevelDebug   Level = iota // Rất chi tiết, dùng khi phát triển/gỡ lỗi
+  LevelVerbose              // Nhiều thông tin ít quan trọng (kết nối mở/đóng, ...)
+  LevelNotice               // Mức mặc định, phù hợp môi trường production
+  LevelWarning              // Chỉ các thông báo quan trọng/cảnh báo
+)
+
+var level atomic.Int32
+
+func init() {
+  level.Store(int32(LevelNotice))
+}
+
+// ParseLevel đọc giá trị của loglevel (debug, verbose, notice, warning)
+func ParseLevel(value string) (Level, error) {
+  switch strings.ToLower(value) {
+  case "debug":
+    return LevelDebug, nil
+  case "verbose":
+    return LevelVerbose, nil
+  case "notice":
+    return LevelNotice, nil
+  case "warning":
+    return LevelWarning, nil
+  }
+  return LevelNotice, fmt.Errorf("invalid loglevel %q (expected debug, verbose, notice or warning)", value)
+}
+
+func (l Level) String() string {
+  switch l {
+  case LevelDebug:
+    return "debug"
+  case LevelVerbose:
+    return "verbose"
+  case LevelWarning:
+    return "warning"
+  }
+  return "notice"
+}
+
+// SetLevel thay đổi mức log khi đang chạy
+func SetLevel(l Level) {
+  level.Store(int32(l))
+}
+
+// GetLevel trả về mức log hiện tại
+func GetLevel() Level {
+  return Level(level.Load())
+}
+
+func logf(l Level, format string, args ...interface{}) {
+  if l >= GetLevel() {
+    log.Printf(format, args...)
+  }
+}
+
+// Debugf ghi log ở mức debug
+func Debugf(format string, args ...interface{}) {
+  logf(LevelDebug, format, args...)
+}
+
+// Verbosef ghi log ở mức verbose
+func Verbosef(format string, args ...interface{}) {
+  logf(LevelVerbose, format, args...)
+}
+
+// Noticef ghi log ở mức notice
+func Noticef(format string, args ...interface{}) {
+  logf(LevelNotice, format, args...)
+}
+
+// Warningf ghi log ở mức warning
+func Warningf(format string, args ...interface{}) {
+  logf(LevelWarning, format, args...)
+}
@@ -0,0 +1,108 @@
+package protocol
+
+import (
+  "errors"
+  "strconv"
+)
+
+// ErrUnbalancedQuotes được trả về khi dấu nháy không được đóng đúng cách
+var ErrUnbalancedQuotes = errors.New("unbalanced quotes")
+
+// SplitArgs tách một dòng thành các đối số theo quy tắc của Redis (sdssplitargs):
+// các đối số cách nhau bởi khoảng trắng; trong "..." hỗ trợ \n \r \t \b \a \\ \" và \xHH;
+// trong '...' chỉ hỗ trợ \'. Dấu nháy đóng phải được theo sau bởi khoảng trắng hoặc kết thúc dòng.
+func SplitArgs(line string) ([]string, error) {
+  var args []string
+  i, n := 0, len(line)
+  for {
+    for i < n && isSpace(line[i]) {
+      i++
+    }
+    if i >= n {
+      return args, nil
+    }
+
+    var cur []byte
+    inDouble, inSingle := false, false
+    for done := false; !done; {
+      if i >= n {
+        if inDouble || inSingle {
+          return nil, ErrUnbalancedQuotes
+        }
+        break
+      }
+      c := line[i]
+      switch {
+      case inDouble:
+        switch {
+        case c == '\\' && i+3 < n && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]):
+          v, _ := strconv.ParseUint(line[i+2:i+4], 16, 8)
+          cur = append(cur, byte(v))
+          i += 3
+        case c == '\\' && i+1 < n:
+          i++
+          cur = append(cur, unescape(line[i]))
+        case c == '"':
+          // Dấu nháy đóng phải được theo sau bởi khoảng trắng hoặc kết thúc dòng
+          if i+1 < n && !isSpace(line[i+1]) {
+            return nil, ErrUnbalancedQuotes
+          }
+          done = true
+        default:
+          cur = append(cur, c)
+        }
+      case inSingle:
+        switch {
+        case c == '\\' && i+1 < n && line[i+1] == '\'':
+          i++
+          cur = append(cur, '\'')
+        case c == '\'':
+          if i+1 < n && !isSpace(line[i+1]) {
+            return nil, ErrUnbalancedQuotes
+          }
+          done = true
+        default:
+          cur = append(cur, c)
+        }
+      default:
+        switch {
+        case isSpace(c):
+          done = true
+        case c == '"':
+          inDouble = true
+        case c == '\'':
+          inSingle = true
+        default:
+          cur = append(cur, c)
+        }
+      }
+      i++
+    }
+    args = append(args, string(cur))
+  }
+}
+
+// unescape trả về ký tự tương ứng với chuỗi thoát \c trong dấu nháy kép
+func unescape(c byte) byte {
+  switch c {
+  case 'n':
+    return '\n'
+  case 'r':
+    return '\r'
+  case 't':
+    return '\t'
+  case 'b':
+    return '\b'
+  case 'a':
+    return '\a'
+  }
+  return c
+}
+
+func isSpace(c byte) bool {
+  return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isHex(c byte) bool {
+  return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
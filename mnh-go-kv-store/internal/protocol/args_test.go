@@ -0,0 +1,45 @@
+package protocol
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+  tests := []struct {
+    line    string
+    want    []string
+    wantErr bool
+  }{
+    {"", nil, false},
+    {"   \t ", nil, false},
+    {"PING", []string{"PING"}, false},
+    {"  SET  key\tvalue  ", []string{"SET", "key", "value"}, false},
+    {`SET key "hello world"`, []string{"SET", "key", "hello world"}, false},
+    {`SET key ""`, []string{"SET", "key", ""}, false},
+    {`SET key "a\"b\\c\n\r\t\b\a"`, []string{"SET", "key", "a\"b\\c\n\r\t\b\a"}, false},
+    {`GET "\x00\xff\x4A"`, []string{"GET", "\x00\xff\x4a"}, false},
+    {`GET "\xZZ"`, []string{"GET", "xZZ"}, false},
+    {`SET k 'it\'s "raw" \n'`, []string{"SET", "k", `it's "raw" \n`}, false},
+    {`SET k ''`, []string{"SET", "k", ""}, false},
+    {`a"b c"`, []string{"ab c"}, false},
+    {`a"b"c`, nil, true},
+    {`SET "unbalanced`, nil, true},
+    {`SET 'unbalanced`, nil, true},
+    {`SET "a"b`, nil, true},
+    {`SET 'a'b`, nil, true},
+    {`SET "ends with backslash\`, nil, true},
+  }
+  for _, tt := range tests {
+    got, err := SplitArgs(tt.line)
+    if tt.wantErr {
+      if err != ErrUnbalancedQuotes {
+        t.Errorf("SplitArgs(%q) = %q, %v, want ErrUnbalancedQuotes", tt.line, got, err)
+      }
+      continue
+    }
+    if err != nil || !reflect.DeepEqual(got, tt.want) {
+      t.Errorf("SplitArgs(%q) = %q, %v, want %q", tt.line, got, err, tt.want)
+    }
+  }
+}
@@ -0,0 +1,182 @@
+package protocol
+
+import (
+  "bytes"
+  "io"
+)
+
+// ReplyWriter ghi các phản hồi đã được handler mã hóa theo phiên bản giao thức mà kết nối chọn bằng HELLO.
+// Handler mã hóa map, set, double, ... bằng kiểu RESP3 và null bằng "$-1". Với RESP2 các kiểu RESP3 được
+// chuyển về kiểu RESP2 tương ứng giống Redis (Map thành Array key/value xen kẽ, Double và Big Number thành
+// Bulk String, Boolean thành Integer 1/0, Attribute bị bỏ đi); với RESP3 các null "$-1" và "*-1" thành "_".
+// Phần không cần chuyển đổi được ghi thẳng từ phản hồi gốc, không sao chép thêm.
+type ReplyWriter struct {
+  Proto int // Phiên bản giao thức của kết nối: 2 (mặc định) hoặc 3
+
+  w        io.Writer
+  src      []byte // Phản hồi đang ghi
+  span     int    // Đầu đoạn của src chưa được ghi
+  skipping int    // > 0 khi đang bỏ qua một Attribute (không ghi gì)
+  hdr      []byte // Buffer dùng lại cho phần được thay thế
+  err      error
+}
+
+// NewReplyWriter tạo ReplyWriter ghi vào w với giao thức RESP2
+func NewReplyWriter(w io.Writer) *ReplyWriter {
+  return &ReplyWriter{Proto: 2, w: w}
+}
+
+// WriteReply ghi một phản hồi (có thể gồm nhiều giá trị RESP liên tiếp) sau khi chuyển sang giao thức
+// của kết nối. Phản hồi không đúng định dạng được ghi nguyên vẹn từ chỗ không đọc được.
+func (rw *ReplyWriter) WriteReply(reply []byte) error {
+  rw.src, rw.span, rw.skipping, rw.err = reply, 0, 0, nil
+  for pos := 0; pos >= 0 && pos < len(reply); {
+    pos = rw.walk(pos)
+  }
+  rw.flush(len(reply))
+  rw.src = nil
+  return rw.err
+}
+
+// flush ghi phần src[span:end] chưa được ghi
+func (rw *ReplyWriter) flush(end int) {
+  if rw.err == nil && end > rw.span {
+    _, rw.err = rw.w.Write(rw.src[rw.span:end])
+  }
+  rw.span = end
+}
+
+// replace ghi repl thay cho src[start:end]
+func (rw *ReplyWriter) replace(start, end int, repl []byte) {
+  if rw.skipping > 0 {
+    return
+  }
+  rw.flush(start)
+  if rw.err == nil && len(repl) > 0 {
+    _, rw.err = rw.w.Write(repl)
+  }
+  rw.span = end
+}
+
+// header trả về nội dung dòng đầu của giá trị tại pos (sau byte kiểu) và vị trí ngay sau CRLF
+func (rw *ReplyWriter) header(pos int) ([]byte, int) {
+  i := bytes.IndexByte(rw.src[pos:], '\n')
+  if i < 2 || rw.src[pos+i-1] != '\r' {
+    return nil, -1
+  }
+  return rw.src[pos+1 : pos+i-1], pos + i + 1
+}
+
+// walk duyệt giá trị tại pos, chuyển đổi những phần cần thiết, và trả về vị trí ngay sau giá trị
+// (-1 nếu không đúng định dạng)
+func (rw *ReplyWriter) walk(pos int) int {
+  line, next := rw.header(pos)
+  if next < 0 {
+    return -1
+  }
+
+  switch typ := rw.src[pos]; typ {
+  case STRING, ERROR, INTEGER:
+    return next
+
+  case BULK, BLOBERROR, VERBATIM:
+    n, ok := parseInt(line)
+    if !ok {
+      return -1
+    }
+    if n < 0 {
+      if rw.Proto == 3 {
+        rw.replace(pos, next, []byte("_\r\n"))
+      }
+      return next
+    }
+    end := next + n + 2
+    if end > len(rw.src) {
+      return -1
+    }
+    if rw.Proto == 2 {
+      switch {
+      case typ == VERBATIM && n >= 4:
+        // Bỏ phần "fmt:" ở đầu nội dung
+        rw.hdr = appendHeader(rw.hdr[:0], BULK, n-4)
+        rw.replace(pos, next+4, rw.hdr)
+      case typ == BLOBERROR:
+        rw.hdr = appendLine(append(rw.hdr[:0], ERROR), string(rw.src[next:next+n]))
+        rw.replace(pos, end, rw.hdr)
+      }
+    }
+    return end
+
+  case ARRAY, SET, PUSH, MAP:
+    n, ok := parseInt(line)
+    if !ok {
+      return -1
+    }
+    if n < 0 {
+      if rw.Proto == 3 {
+        rw.replace(pos, next, []byte("_\r\n"))
+      }
+      return next
+    }
+    if typ == MAP {
+      n *= 2
+    }
+    if rw.Proto == 2 && typ != ARRAY {
+      rw.hdr = appendHeader(rw.hdr[:0], ARRAY, n)
+      rw.replace(pos, next, rw.hdr)
+    }
+    for i := 0; i < n && next >= 0; i++ {
+      next = rw.walk(next)
+    }
+    return next
+
+  case ATTRIBUTE:
+    n, ok := parseInt(line)
+    if !ok || n < 0 {
+      return -1
+    }
+    // RESP2 không có Attribute: bỏ cả phần Attribute, chỉ giữ giá trị đi sau nó
+    if rw.Proto == 2 {
+      rw.skipping++
+    }
+    for i := 0; i < 2*n && next >= 0; i++ {
+      next = rw.walk(next)
+    }
+    if rw.Proto == 2 {
+      rw.skipping--
+      if next >= 0 {
+        rw.replace(pos, next, nil)
+      }
+    }
+    if next < 0 || next >= len(rw.src) {
+      return -1
+    }
+    return rw.walk(next)
+
+  case NULL:
+    if rw.Proto == 2 {
+      rw.replace(pos, next, []byte("$-1\r\n"))
+    }
+    return next
+
+  case DOUBLE, BIGNUMBER:
+    if rw.Proto == 2 {
+      rw.hdr = appendBulk(rw.hdr[:0], string(line))
+      rw.replace(pos, next, rw.hdr)
+    }
+    return next
+
+  case BOOLEAN:
+    if rw.Proto == 2 {
+      if len(line) == 1 && line[0] == 't' {
+        rw.replace(pos, next, []byte(":1\r\n"))
+      } else {
+        rw.replace(pos, next, []byte(":0\r\n"))
+      }
+    }
+    return next
+
+  default:
+    return -1
+  }
+}
@@ -0,0 +1,149 @@
+package protocol
+
+import (
+  "bytes"
+  "io"
+  "strings"
+  "testing"
+)
+
+func TestWriteReplyRESP2(t *testing.T) {
+  tests := []struct {
+    in   string
+    want string
+  }{
+    {"+OK\r\n", "+OK\r\n"},
+    {"$-1\r\n", "$-1\r\n"},
+    {"_\r\n", "$-1\r\n"},
+    {",1.5\r\n", "$3\r\n1.5\r\n"},
+    {"#t\r\n", ":1\r\n"},
+    {"#f\r\n", ":0\r\n"},
+    {"%1\r\n$1\r\na\r\n:1\r\n", "*2\r\n$1\r\na\r\n:1\r\n"},
+    {"~2\r\n$1\r\na\r\n$1\r\nb\r\n", "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+    {"=7\r\ntxt:abc\r\n", "$3\r\nabc\r\n"},
+    {"!3\r\nerr\r\n", "-err\r\n"},
+    {"|1\r\n+ttl\r\n:3\r\n:42\r\n", ":42\r\n"},
+  }
+  for _, tt := range tests {
+    var out bytes.Buffer
+    if err := NewReplyWriter(&out).WriteReply([]byte(tt.in)); err != nil {
+      t.Fatal(err)
+    }
+    if out.String() != tt.want {
+      t.Errorf("WriteReply(%q) = %q, want %q", tt.in, out.String(), tt.want)
+    }
+  }
+}
+
+func TestWriteReplyRESP3(t *testing.T) {
+  tests := []struct {
+    in   string
+    want string
+  }{
+    {"$-1\r\n", "_\r\n"},
+    {"*-1\r\n", "_\r\n"},
+    {"%1\r\n$1\r\na\r\n$-1\r\n", "%1\r\n$1\r\na\r\n_\r\n"},
+    {",1.5\r\n", ",1.5\r\n"},
+  }
+  for _, tt := range tests {
+    var out bytes.Buffer
+    rw := NewReplyWriter(&out)
+    rw.Proto = 3
+    if err := rw.WriteReply([]byte(tt.in)); err != nil {
+      t.Fatal(err)
+    }
+    if out.String() != tt.want {
+      t.Errorf("WriteReply(%q) = %q, want %q", tt.in, out.String(), tt.want)
+    }
+  }
+}
+
+// readAll đọc mọi giá trị trong data, trả về lỗi nếu data không gồm trọn vẹn các giá trị RESP
+func readAll(data []byte) ([]Value, error) {
+  r := NewResp(bytes.NewReader(data))
+  var values []Value
+  for {
+    v, _, err := r.Read()
+    if err == io.EOF {
+      return values, nil
+    }
+    if err != nil {
+      return nil, err
+    }
+    values = append(values, v)
+  }
+}
+
+func FuzzWriteReply(f *testing.F) {
+  for _, seed := range respSeeds {
+    f.Add([]byte(seed))
+  }
+  f.Fuzz(func(t *testing.T, data []byte) {
+    in, readErr := readAll(data)
+    for _, proto := range []int{2, 3} {
+      var out bytes.Buffer
+      rw := NewReplyWriter(&out)
+      rw.Proto = proto
+      if err := rw.WriteReply(data); err != nil {
+        t.Fatal(err)
+      }
+      if readErr != nil {
+        continue
+      }
+      // Phản hồi hợp lệ phải vẫn hợp lệ sau khi chuyển đổi, với cùng số giá trị
+      got, err := readAll(out.Bytes())
+      if err != nil {
+        t.Fatalf("RESP%d output %q of %q is not valid: %v", proto, out.Bytes(), data, err)
+      }
+      if len(got) != len(in) {
+        t.Fatalf("RESP%d output %q of %q has %d values, want %d", proto, out.Bytes(), data, len(got), len(in))
+      }
+      if proto == 2 && out.Len() > 0 && bytes.IndexByte([]byte("_,#%~=!(|>"), out.Bytes()[0]) >= 0 {
+        t.Fatalf("RESP2 output %q of %q starts with a RESP3 type", out.Bytes(), data)
+      }
+    }
+  })
+}
+
+// benchmarkWriteReply đo việc ghi reply với giao thức proto
+func benchmarkWriteReply(b *testing.B, reply []byte, proto int) {
+  rw := NewReplyWriter(io.Discard)
+  rw.Proto = proto
+  b.SetBytes(int64(len(reply)))
+  b.ReportAllocs()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    if err := rw.WriteReply(reply); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
+
+// mapReply tạo một Map n cặp field/value (như HGETALL), mã hóa bằng RESP3
+func mapReply(n int) []byte {
+  items := make([]Value, 0, 2*n)
+  for i := 0; i < n; i++ {
+    items = append(items, Value{Typ: "bulk", Bulk: "field-" + strings.Repeat("f", i%16)}, Value{Typ: "bulk", Bulk: "value"})
+  }
+  return Value{Typ: "map", Array: items}.Marshal()
+}
+
+func BenchmarkWriteReplyBulk(b *testing.B) {
+  benchmarkWriteReply(b, Value{Typ: "bulk", Bulk: strings.Repeat("x", 1024)}.Marshal(), 2)
+}
+
+func BenchmarkWriteReplyArray(b *testing.B) {
+  items := make([]Value, 100)
+  for i := range items {
+    items[i] = Value{Typ: "bulk", Bulk: "member-" + strings.Repeat("x", i%32)}
+  }
+  benchmarkWriteReply(b, Value{Typ: "array", Array: items}.Marshal(), 2)
+}
+
+func BenchmarkWriteReplyMapRESP2(b *testing.B) {
+  benchmarkWriteReply(b, mapReply(100), 2)
+}
+
+func BenchmarkWriteReplyMapRESP3(b *testing.B) {
+  benchmarkWriteReply(b, mapReply(100), 3)
+}
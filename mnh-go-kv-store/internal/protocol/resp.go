@@ -2,8 +2,10 @@ package protocol
 
 import (
   "bufio"
-  "fmt"
+  "bytes"
+  "errors"
   "io"
+  "math"
   "strconv"
 )
 
@@ -16,24 +18,95 @@ const (
   ARRAY   = '*'
 )
 
+// Các kiểu chỉ có trong RESP3 (chọn bằng HELLO 3)
+const (
+  NULL      = '_'
+  DOUBLE    = ','
+  BOOLEAN   = '#'
+  BIGNUMBER = '('
+  BLOBERROR = '!'
+  VERBATIM  = '='
+  MAP       = '%'
+  SET       = '~'
+  ATTRIBUTE = '|'
+  PUSH      = '>'
+)
+
+// Giới hạn mặc định khi đọc (giống proto-max-bulk-len của Redis)
+const (
+  DefaultMaxBulkLen  = 512 * 1024 * 1024 // Kích thước tối đa của một Bulk String
+  DefaultMaxArrayLen = 16 * 1024 * 1024  // Số phần tử tối đa của một Array
+  maxLineLen         = 64 * 1024         // Độ dài tối đa của một dòng (Simple String, Error, độ dài)
+  maxNestingDepth    = 32                // Số tầng Array lồng nhau tối đa
+  bulkChunkSize      = 1024 * 1024       // Bulk lớn được đọc từng phần để bộ nhớ chỉ tăng theo dữ liệu thực nhận
+  maxRetainedBuffer  = 64 * 1024         // Buffer đọc lớn hơn mức này không được giữ lại để dùng lại
+)
+
+// ProtocolError là lỗi khi dữ liệu nhận được không đúng định dạng RESP hoặc vượt quá giới hạn.
+// Sau lỗi này luồng dữ liệu không còn đồng bộ, kết nối nên được đóng.
+type ProtocolError struct {
+  Msg string
+}
+
+func (e *ProtocolError) Error() string {
+  return "Protocol error: " + e.Msg
+}
+
+// Các lỗi giao thức thường gặp
+var (
+  ErrInvalidBulkLength  = &ProtocolError{Msg: "invalid bulk length"}
+  ErrInvalidArrayLength = &ProtocolError{Msg: "invalid multibulk length"}
+  ErrLineTooLong        = &ProtocolError{Msg: "too big line"}
+  ErrTooDeep            = &ProtocolError{Msg: "too many nested arrays"}
+  errExpectedCRLF       = &ProtocolError{Msg: "expected CRLF ending"}
+  errBadInteger         = &ProtocolError{Msg: "invalid integer"}
+  errBadDouble          = &ProtocolError{Msg: "invalid double"}
+  errBadBoolean         = &ProtocolError{Msg: "invalid boolean"}
+  errBadBigNumber       = &ProtocolError{Msg: "invalid big number"}
+  errBadVerbatim        = &ProtocolError{Msg: "invalid verbatim string"}
+  errBadNull            = &ProtocolError{Msg: "invalid null"}
+)
+
+// Value là một giá trị RESP. Typ là một trong "string", "error", "integer", "bulk", "null", "array"
+// (RESP2) hoặc "map", "set", "double", "boolean", "bignum", "verbatim", "push" (RESP3).
+// "null" luôn được mã hóa thành "$-1" và được chuyển thành "_" cho kết nối RESP3 (xem ReplyWriter).
 type Value struct {
-  Typ   string
-  Str   string
-  Num   int
-  Bulk  string
-  Array []Value
+  Typ    string
+  Str    string  // Simple String, Error, chữ số của Big Number, định dạng của Verbatim String ("txt", "mkd")
+  Num    int
+  Bulk   string  // Bulk String an toàn với dữ liệu nhị phân (chuỗi Go có thể chứa byte bất kỳ), nội dung Verbatim String
+  Double float64 // Double
+  Bool   bool    // Boolean
+  Array  []Value // Array, Set, Push; với Map là key và value xen kẽ
+  Attrs  []Value // Attribute (RESP3) đi kèm giá trị, key và value xen kẽ
 }
 
 type Resp struct {
   reader *bufio.Reader
+  buf    []byte // Buffer dùng lại khi đọc Bulk String
+  line   []byte // Buffer dùng lại cho dòng dài hơn buffer của reader
+
+  MaxBulkLen  int // Kích thước tối đa của Bulk String được chấp nhận
+  MaxArrayLen int // Số phần tử tối đa của Array được chấp nhận
 }
 
 func NewResp(rd io.Reader) *Resp {
-  return &Resp{reader: bufio.NewReader(rd)}
+  return &Resp{
+    reader:      bufio.NewReader(rd),
+    MaxBulkLen:  DefaultMaxBulkLen,
+    MaxArrayLen: DefaultMaxArrayLen,
+  }
 }
 
+// Buffered trả về số byte đã nhận nhưng chưa được đọc (ví dụ các lệnh tiếp theo của một pipeline)
+func (r *Resp) Buffered() int {
+  return r.reader.Buffered()
+}
+
+// readLine đọc một dòng kết thúc bằng CRLF, trả về nội dung không gồm CRLF.
+// Kết quả chỉ hợp lệ tới lần đọc tiếp theo.
 func (r *Resp) readLine() (line []byte, n int, err error) {
-  line, err = r.reader.ReadBytes('\n')
+  line, err = r.readRawLine()
   if err != nil {
     return nil, 0, err
   }
@@ -43,7 +116,25 @@ func (r *Resp) readLine() (line []byte, n int, err error) {
     return line[:n-2], n, nil
   }
 
-  return line, n, fmt.Errorf("bad format: expected CRLF ending")
+  return line, n, errExpectedCRLF
+}
+
+// readRawLine đọc tới hết ký tự '\n' (bao gồm cả '\n'), giới hạn ở maxLineLen
+func (r *Resp) readRawLine() (line []byte, err error) {
+  line, err = r.reader.ReadSlice('\n')
+  if err == bufio.ErrBufferFull {
+    // Dòng dài hơn buffer của reader: gom các phần vào buffer riêng
+    r.line = append(r.line[:0], line...)
+    for err == bufio.ErrBufferFull {
+      line, err = r.reader.ReadSlice('\n')
+      r.line = append(r.line, line...)
+      if len(r.line) > maxLineLen {
+        return nil, ErrLineTooLong
+      }
+    }
+    line = r.line
+  }
+  return line, err
 }
 
 func (r *Resp) readInteger() (x int, n int, err error) {
@@ -52,14 +143,110 @@ func (r *Resp) readInteger() (x int, n int, err error) {
     return 0, 0, err
   }
 
-  num, err := strconv.Atoi(string(line))
-  if err != nil {
-    return 0, readBytes, err
+  num, ok := parseInt(line)
+  if !ok {
+    return 0, readBytes, errBadInteger
   }
 
   return num, readBytes, nil
 }
 
+// parseInt đọc số nguyên thập phân có dấu mà không cấp phát bộ nhớ
+func parseInt(b []byte) (int, bool) {
+  if len(b) == 0 {
+    return 0, false
+  }
+  neg := b[0] == '-'
+  if neg {
+    b = b[1:]
+    if len(b) == 0 {
+      return 0, false
+    }
+  }
+
+  // Tối đa 19 chữ số nên không thể tràn uint64
+  if len(b) > 19 {
+    return 0, false
+  }
+
+  var n uint64
+  for _, c := range b {
+    if c < '0' || c > '9' {
+      return 0, false
+    }
+    n = n*10 + uint64(c-'0')
+  }
+  if neg {
+    if n > 1<<63 {
+      return 0, false
+    }
+    return int(-int64(n)), true
+  }
+  if n > 1<<63-1 {
+    return 0, false
+  }
+  return int(n), true
+}
+
+// readFull đọc đúng n byte vào buffer dùng lại. Bulk lớn được đọc từng phần nên một độ dài khai báo
+// lớn mà không kèm dữ liệu không chiếm trước bộ nhớ. Kết quả chỉ hợp lệ tới lần đọc tiếp theo.
+func (r *Resp) readFull(n int) ([]byte, error) {
+  buf := r.buf[:0]
+  for len(buf) < n {
+    chunk := min(n-len(buf), bulkChunkSize)
+    if cap(buf)-len(buf) < chunk {
+      grown := make([]byte, len(buf), len(buf)+chunk)
+      copy(grown, buf)
+      buf = grown
+    }
+    m, err := io.ReadFull(r.reader, buf[len(buf):len(buf)+chunk])
+    buf = buf[:len(buf)+m]
+    if err != nil {
+      if err == io.EOF && len(buf) > 0 {
+        err = io.ErrUnexpectedEOF
+      }
+      return nil, err
+    }
+  }
+  if cap(buf) <= maxRetainedBuffer {
+    r.buf = buf
+  }
+  return buf, nil
+}
+
+// parseDouble đọc một Double của RESP3 ("inf", "-inf" và "nan" được chấp nhận)
+func parseDouble(b []byte) (float64, error) {
+  switch string(b) {
+  case "inf", "+inf":
+    return math.Inf(1), nil
+  case "-inf":
+    return math.Inf(-1), nil
+  case "nan":
+    return math.NaN(), nil
+  }
+  f, err := strconv.ParseFloat(string(b), 64)
+  if err != nil {
+    return 0, errBadDouble
+  }
+  return f, nil
+}
+
+// isBigNumber kiểm tra b là số nguyên thập phân có dấu với độ dài bất kỳ
+func isBigNumber(b []byte) bool {
+  if len(b) > 0 && (b[0] == '-' || b[0] == '+') {
+    b = b[1:]
+  }
+  if len(b) == 0 {
+    return false
+  }
+  for _, c := range b {
+    if c < '0' || c > '9' {
+      return false
+    }
+  }
+  return true
+}
+
 func (r *Resp) readBulk() (Value, int, error) {
   bulkLen, n, err := r.readInteger()
   if err != nil {
@@ -71,57 +258,135 @@ func (r *Resp) readBulk() (Value, int, error) {
   if bulkLen == -1 {
     return Value{Typ: "null"}, totalBytes, nil
   }
+  if bulkLen < 0 || bulkLen > r.MaxBulkLen {
+    return Value{}, 0, ErrInvalidBulkLength
+  }
 
-  bulk := make([]byte, bulkLen)
-  n, err = r.reader.Read(bulk)
+  bulk, err := r.readFull(bulkLen)
   if err != nil {
     return Value{}, 0, err
   }
-  totalBytes += n
+  totalBytes += bulkLen
+  // Chuyển thành string (một lần sao chép) trước khi đọc tiếp vì buffer sẽ được dùng lại
+  value := Value{Typ: "bulk", Bulk: string(bulk)}
 
   line, n, err := r.readLine()
+  if err == io.EOF {
+    return Value{}, 0, err
+  }
   if err != nil || len(line) != 0 {
-    return Value{}, 0, fmt.Errorf("protocol error: expected CRLF after bulk data")
+    return Value{}, 0, &ProtocolError{Msg: "expected CRLF after bulk data"}
   }
   totalBytes += n
 
-  return Value{Typ: "bulk", Bulk: string(bulk)}, totalBytes, nil
+  return value, totalBytes, nil
 }
 
-func (r *Resp) readArray() (Value, int, error) {
+// readAggregate đọc Array, Set, Push (per = 1) hoặc Map, Attribute (per = 2, độ dài là số cặp)
+func (r *Resp) readAggregate(typ string, per int, depth int) (Value, int, error) {
   arrayLen, n, err := r.readInteger()
   if err != nil {
     return Value{}, 0, err
   }
   totalBytes := n
 
-  if arrayLen == -1 {
+  if arrayLen == -1 && typ == "array" {
     return Value{Typ: "null"}, totalBytes, nil
   }
+  if arrayLen < 0 || arrayLen > r.MaxArrayLen/per {
+    return Value{}, 0, ErrInvalidArrayLength
+  }
+  if depth >= maxNestingDepth {
+    return Value{}, 0, ErrTooDeep
+  }
 
-  array := make([]Value, arrayLen)
+  // Không cấp phát trước toàn bộ độ dài khai báo: mảng chỉ lớn dần theo phần tử thực nhận
+  arrayLen *= per
+  array := make([]Value, 0, min(arrayLen, 1024))
   for i := 0; i < arrayLen; i++ {
-    val, n, err := r.Read()
+    typ, err := r.reader.ReadByte()
     if err != nil {
       return Value{}, 0, err
     }
-    array[i] = val
+    val, n, err := r.readValue(typ, depth+1)
+    if err != nil {
+      return Value{}, 0, err
+    }
+    array = append(array, val)
     totalBytes += n
   }
 
-  return Value{Typ: "array", Array: array}, totalBytes, nil
+  return Value{Typ: typ, Array: array}, totalBytes, nil
 }
 
+// Read đọc một giá trị RESP hoàn chỉnh. io.EOF chỉ được trả về khi dữ liệu kết thúc ngay trước
+// giá trị; dữ liệu kết thúc giữa chừng một giá trị trả về io.ErrUnexpectedEOF.
+// Dữ liệu sai định dạng hoặc vượt giới hạn trả về *ProtocolError.
 func (r *Resp) Read() (Value, int, error) {
-  typ, err := r.reader.Peek(1)
+  typ, err := r.reader.ReadByte()
+  if err != nil {
+    return Value{}, 0, err
+  }
+
+  val, n, err := r.readValue(typ, 0)
+  if err == io.EOF {
+    err = io.ErrUnexpectedEOF
+  }
+  return val, n, err
+}
+
+// ReadCommand đọc một lệnh của client: RESP Array, hoặc lệnh inline (một dòng văn bản, các đối số
+// cách nhau bởi khoảng trắng và có thể đặt trong dấu nháy như redis-cli) để có thể gõ lệnh trực tiếp
+// qua telnet/nc. Dòng inline trống được bỏ qua. Lệnh luôn được trả về dưới dạng Array các Bulk String.
+func (r *Resp) ReadCommand() (Value, int, error) {
+  for {
+    typ, err := r.reader.Peek(1)
+    if err != nil {
+      return Value{}, 0, err
+    }
+    if typ[0] == ARRAY {
+      return r.Read()
+    }
+
+    val, n, err := r.readInline()
+    if err != nil || len(val.Array) > 0 {
+      return val, n, err
+    }
+  }
+}
+
+// readInline đọc một lệnh inline, chấp nhận dòng kết thúc bằng CRLF hoặc chỉ LF
+func (r *Resp) readInline() (Value, int, error) {
+  raw, err := r.readRawLine()
+  if err == ErrLineTooLong {
+    return Value{}, 0, &ProtocolError{Msg: "too big inline request"}
+  }
   if err != nil {
+    if err == io.EOF && len(raw) > 0 {
+      err = io.ErrUnexpectedEOF
+    }
     return Value{}, 0, err
   }
 
-  r.reader.ReadByte()
+  n := len(raw)
+  line := bytes.TrimSuffix(raw[:n-1], []byte{'\r'})
+  args, err := SplitArgs(string(line))
+  if err != nil {
+    return Value{}, 0, &ProtocolError{Msg: "unbalanced quotes in request"}
+  }
+
+  array := make([]Value, len(args))
+  for i, arg := range args {
+    array[i] = Value{Typ: "bulk", Bulk: arg}
+  }
+  return Value{Typ: "array", Array: array}, n, nil
+}
+
+// readValue đọc phần còn lại của một giá trị có kiểu typ (byte đầu tiên đã được đọc)
+func (r *Resp) readValue(typ byte, depth int) (Value, int, error) {
   totalBytes := 1
 
-  switch typ[0] {
+  switch typ {
   case STRING:
     line, n, err := r.readLine()
     return Value{Typ: "string", Str: string(line)}, totalBytes + n, err
@@ -135,41 +400,220 @@ func (r *Resp) Read() (Value, int, error) {
     val, n, err := r.readBulk()
     return val, totalBytes + n, err
   case ARRAY:
-    val, n, err := r.readArray()
+    val, n, err := r.readAggregate("array", 1, depth)
+    return val, totalBytes + n, err
+  case SET:
+    val, n, err := r.readAggregate("set", 1, depth)
+    return val, totalBytes + n, err
+  case PUSH:
+    val, n, err := r.readAggregate("push", 1, depth)
+    return val, totalBytes + n, err
+  case MAP:
+    val, n, err := r.readAggregate("map", 2, depth)
     return val, totalBytes + n, err
+  case ATTRIBUTE:
+    // Attribute không phải một giá trị riêng mà đi kèm giá trị ngay sau nó
+    attrs, n, err := r.readAggregate("map", 2, depth)
+    if err != nil {
+      return Value{}, 0, err
+    }
+    typ, err := r.reader.ReadByte()
+    if err != nil {
+      return Value{}, 0, err
+    }
+    val, m, err := r.readValue(typ, depth+1)
+    val.Attrs = attrs.Array
+    return val, totalBytes + n + m, err
+  case NULL:
+    line, n, err := r.readLine()
+    if err == nil && len(line) != 0 {
+      err = errBadNull
+    }
+    return Value{Typ: "null"}, totalBytes + n, err
+  case DOUBLE:
+    line, n, err := r.readLine()
+    if err != nil {
+      return Value{}, 0, err
+    }
+    f, err := parseDouble(line)
+    return Value{Typ: "double", Double: f}, totalBytes + n, err
+  case BOOLEAN:
+    line, n, err := r.readLine()
+    if err != nil {
+      return Value{}, 0, err
+    }
+    if len(line) != 1 || (line[0] != 't' && line[0] != 'f') {
+      return Value{}, 0, errBadBoolean
+    }
+    return Value{Typ: "boolean", Bool: line[0] == 't'}, totalBytes + n, nil
+  case BIGNUMBER:
+    line, n, err := r.readLine()
+    if err != nil {
+      return Value{}, 0, err
+    }
+    if !isBigNumber(line) {
+      return Value{}, 0, errBadBigNumber
+    }
+    return Value{Typ: "bignum", Str: string(line)}, totalBytes + n, nil
+  case BLOBERROR:
+    val, n, err := r.readBulk()
+    if err == nil && val.Typ != "bulk" {
+      err = ErrInvalidBulkLength
+    }
+    return Value{Typ: "error", Str: val.Bulk}, totalBytes + n, err
+  case VERBATIM:
+    // Nội dung có dạng "fmt:dữ liệu" với fmt gồm đúng 3 ký tự
+    val, n, err := r.readBulk()
+    if err != nil {
+      return Value{}, 0, err
+    }
+    if val.Typ != "bulk" || len(val.Bulk) < 4 || val.Bulk[3] != ':' {
+      return Value{}, 0, errBadVerbatim
+    }
+    return Value{Typ: "verbatim", Str: val.Bulk[:3], Bulk: val.Bulk[4:]}, totalBytes + n, nil
   default:
-    return Value{}, 0, fmt.Errorf("unknown type: %c", typ[0])
+    return Value{}, 0, &ProtocolError{Msg: "unknown type " + strconv.QuoteRune(rune(typ))}
   }
 }
 
+// IsProtocolError cho biết err có phải lỗi định dạng RESP (không phải lỗi kết nối) hay không
+func IsProtocolError(err error) bool {
+  var protoErr *ProtocolError
+  return errors.As(err, &protoErr)
+}
+
+// Marshal mã hóa giá trị thành RESP
 func (v Value) Marshal() []byte {
+  return v.AppendTo(make([]byte, 0, v.marshalSizeHint()))
+}
+
+// AppendTo mã hóa giá trị thành RESP và nối vào dst, trả về slice mới (giống các hàm Append của strconv).
+// Dùng với một buffer được dùng lại để tránh cấp phát cho mỗi phản hồi.
+func (v Value) AppendTo(dst []byte) []byte {
+  if len(v.Attrs) > 0 {
+    dst = appendItems(appendHeader(dst, ATTRIBUTE, len(v.Attrs)/2), v.Attrs)
+  }
   switch v.Typ {
   case "string":
-    return []byte(fmt.Sprintf("+%s\r\n", v.Str))
+    return appendLine(append(dst, STRING), v.Str)
   case "error":
-    return []byte(fmt.Sprintf("-%s\r\n", v.Str))
+    return appendLine(append(dst, ERROR), v.Str)
   case "integer":
-    return []byte(fmt.Sprintf(":%d\r\n", v.Num))
+    dst = strconv.AppendInt(append(dst, INTEGER), int64(v.Num), 10)
+    return append(dst, '\r', '\n')
   case "bulk":
-    return []byte(fmt.Sprintf("$%d\r\n%s\r\n", len(v.Bulk), v.Bulk))
+    return appendBulk(dst, v.Bulk)
   case "null":
-    return []byte("$-1\r\n")
+    return append(dst, "$-1\r\n"...)
   case "array":
-    result := fmt.Sprintf("*%d\r\n", len(v.Array))
+    return appendItems(appendHeader(dst, ARRAY, len(v.Array)), v.Array)
+  case "map":
+    return appendItems(appendHeader(dst, MAP, len(v.Array)/2), v.Array)
+  case "set":
+    return appendItems(appendHeader(dst, SET, len(v.Array)), v.Array)
+  case "push":
+    return appendItems(appendHeader(dst, PUSH, len(v.Array)), v.Array)
+  case "double":
+    dst = AppendDouble(append(dst, DOUBLE), v.Double)
+    return append(dst, '\r', '\n')
+  case "boolean":
+    if v.Bool {
+      return append(dst, "#t\r\n"...)
+    }
+    return append(dst, "#f\r\n"...)
+  case "bignum":
+    return appendLine(append(dst, BIGNUMBER), v.Str)
+  case "verbatim":
+    format := v.Str
+    if len(format) != 3 {
+      format = "txt"
+    }
+    dst = appendHeader(dst, VERBATIM, len(v.Bulk)+4)
+    dst = append(append(append(dst, format...), ':'), v.Bulk...)
+    return append(dst, '\r', '\n')
+  default:
+    return append(dst, "-ERR unknown type\r\n"...)
+  }
+}
+
+// appendItems nối lần lượt các phần tử của một giá trị tổng hợp
+func appendItems(dst []byte, items []Value) []byte {
+  for _, item := range items {
+    dst = item.AppendTo(dst)
+  }
+  return dst
+}
+
+// AppendDouble nối biểu diễn của một Double giống Redis: "inf", "-inf", "nan",
+// còn lại là biểu diễn ngắn nhất mà vẫn đọc lại chính xác
+func AppendDouble(dst []byte, f float64) []byte {
+  switch {
+  case math.IsInf(f, 1):
+    return append(dst, "inf"...)
+  case math.IsInf(f, -1):
+    return append(dst, "-inf"...)
+  case math.IsNaN(f):
+    return append(dst, "nan"...)
+  }
+  return strconv.AppendFloat(dst, f, 'g', -1, 64)
+}
+
+// marshalSizeHint ước lượng kích thước sau khi mã hóa để cấp phát một lần
+func (v Value) marshalSizeHint() int {
+  switch v.Typ {
+  case "bulk", "verbatim":
+    return len(v.Bulk) + 16
+  case "array", "map", "set", "push":
+    size := 16
     for _, item := range v.Array {
-      result += string(item.Marshal())
+      size += item.marshalSizeHint()
     }
-    return []byte(result)
+    return size
   default:
-    return []byte("-ERR unknown type\r\n")
+    return len(v.Str) + 24
+  }
+}
+
+// appendHeader nối "<prefix><n>\r\n"
+func appendHeader(dst []byte, prefix byte, n int) []byte {
+  dst = strconv.AppendInt(append(dst, prefix), int64(n), 10)
+  return append(dst, '\r', '\n')
+}
+
+// appendBulk nối một Bulk String; dữ liệu được ghi nguyên vẹn (kể cả CR, LF và byte 0)
+func appendBulk(dst []byte, s string) []byte {
+  dst = appendHeader(dst, BULK, len(s))
+  dst = append(dst, s...)
+  return append(dst, '\r', '\n')
+}
+
+// appendLine nối nội dung của Simple String/Error. CR và LF được thay bằng khoảng trắng
+// vì chúng sẽ kết thúc dòng sớm và làm lệch luồng dữ liệu (giống Redis).
+func appendLine(dst []byte, s string) []byte {
+  for i := 0; i < len(s); i++ {
+    c := s[i]
+    if c == '\r' || c == '\n' {
+      c = ' '
+    }
+    dst = append(dst, c)
   }
+  return append(dst, '\r', '\n')
 }
 
 // MarshalCommand creates a RESP array from command parts (for AOF writing)
 func MarshalCommand(parts []string) []byte {
-  array := make([]Value, len(parts))
-  for i, part := range parts {
-    array[i] = Value{Typ: "bulk", Bulk: part}
+  size := 16
+  for _, part := range parts {
+    size += len(part) + 16
+  }
+  return AppendCommand(make([]byte, 0, size), parts)
+}
+
+// AppendCommand nối lệnh parts dưới dạng RESP Array các Bulk String vào dst
+func AppendCommand(dst []byte, parts []string) []byte {
+  dst = appendHeader(dst, ARRAY, len(parts))
+  for _, part := range parts {
+    dst = appendBulk(dst, part)
   }
-  return Value{Typ: "array", Array: array}.Marshal()
+  return dst
 }
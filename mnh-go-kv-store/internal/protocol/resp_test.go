@@ -0,0 +1,327 @@
+package protocol
+
+import (
+  "bytes"
+  "errors"
+  "io"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+// Giới hạn nhỏ dùng khi fuzz để các nhánh vượt giới hạn được chạm tới với dữ liệu ngắn
+const (
+  fuzzMaxBulkLen  = 1024
+  fuzzMaxArrayLen = 64
+)
+
+// respSeeds là các frame hợp lệ (RESP2, RESP3, inline) và các frame vượt giới hạn dùng làm seed
+var respSeeds = []string{
+  // RESP2
+  "+OK\r\n",
+  "-ERR unknown command\r\n",
+  ":-42\r\n",
+  "$5\r\nhello\r\n",
+  "$0\r\n\r\n",
+  "$-1\r\n",
+  "*-1\r\n",
+  "*0\r\n",
+  "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n",
+  "*2\r\n*1\r\n:1\r\n$1\r\nx\r\n",
+  "*1\r\n$4\r\nPING\r\n*1\r\n$4\r\nPING\r\n",
+  // RESP3
+  "_\r\n",
+  ",3.14\r\n",
+  ",-inf\r\n",
+  ",nan\r\n",
+  "#t\r\n",
+  "#f\r\n",
+  "(3492890328409238509324850943850943825024385\r\n",
+  "!21\r\nSYNTAX invalid syntax\r\n",
+  "=15\r\ntxt:Some string\r\n",
+  "%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n",
+  "~3\r\n+a\r\n+b\r\n+c\r\n",
+  ">2\r\n+message\r\n$5\r\nhello\r\n",
+  "|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.1923\r\n*1\r\n:2039\r\n",
+  // Inline
+  "PING\r\n",
+  "SET key \"hello world\"\n",
+  "SET k 'it''s'\r\n",
+  "GET \"\\x00\\xff\"\r\n",
+  "\r\n\r\nPING\r\n",
+  // Sai định dạng và vượt giới hạn
+  "$2000\r\n" + strings.Repeat("x", 2000) + "\r\n",
+  "*100\r\n",
+  "*99999999999999999999\r\n",
+  "$-2\r\n",
+  "$5\r\nhello",
+  "$5\r\nhelloXX",
+  strings.Repeat("*1\r\n", 40) + ":1\r\n",
+  "+" + strings.Repeat("a", maxLineLen+10) + "\r\n",
+  "SET \"unbalanced\r\n",
+  strings.Repeat("x", maxLineLen+10) + "\r\n",
+}
+
+// errProtocol đánh dấu trường hợp chờ một lỗi giao thức bất kỳ trong các bảng test
+var errProtocol = errors.New("protocol error")
+
+// checkReadError kiểm tra lỗi khi đọc chỉ là EOF hoặc lỗi giao thức
+func checkReadError(t *testing.T, err error) {
+  t.Helper()
+  if err == io.EOF || err == io.ErrUnexpectedEOF || IsProtocolError(err) {
+    return
+  }
+  t.Fatalf("unexpected error type %T: %v", err, err)
+}
+
+// newFuzzResp tạo Resp đọc data với các giới hạn nhỏ
+func newFuzzResp(data []byte) *Resp {
+  r := NewResp(bytes.NewReader(data))
+  r.MaxBulkLen = fuzzMaxBulkLen
+  r.MaxArrayLen = fuzzMaxArrayLen
+  return r
+}
+
+// isBulkArray cho biết v là Array chỉ gồm Bulk String (dạng của một lệnh)
+func isBulkArray(v Value) bool {
+  if v.Typ != "array" {
+    return false
+  }
+  for _, item := range v.Array {
+    if item.Typ != "bulk" {
+      return false
+    }
+  }
+  return true
+}
+
+// checkCommandRoundTrip mã hóa lại một lệnh và kiểm tra đọc lại được đúng các đối số
+func checkCommandRoundTrip(t *testing.T, v Value) {
+  t.Helper()
+  again, _, err := NewResp(bytes.NewReader(v.Marshal())).Read()
+  if err != nil {
+    t.Fatalf("re-reading %q: %v", v.Marshal(), err)
+  }
+  if len(again.Array) != len(v.Array) {
+    t.Fatalf("round trip changed the length: %d != %d", len(again.Array), len(v.Array))
+  }
+  for i := range v.Array {
+    if again.Array[i].Bulk != v.Array[i].Bulk {
+      t.Fatalf("round trip changed argument %d: %q != %q", i, again.Array[i].Bulk, v.Array[i].Bulk)
+    }
+  }
+}
+
+func FuzzRead(f *testing.F) {
+  for _, seed := range respSeeds {
+    f.Add([]byte(seed))
+  }
+  f.Fuzz(func(t *testing.T, data []byte) {
+    r := newFuzzResp(data)
+    consumed := 0
+    for {
+      v, n, err := r.Read()
+      if err != nil {
+        checkReadError(t, err)
+        return
+      }
+      consumed += n
+      if consumed > len(data) {
+        t.Fatalf("consumed %d bytes of %d", consumed, len(data))
+      }
+      if v.Typ == "bulk" && len(v.Bulk) > fuzzMaxBulkLen {
+        t.Fatalf("bulk of %d bytes exceeds the limit", len(v.Bulk))
+      }
+      if (v.Typ == "array" || v.Typ == "set" || v.Typ == "push") && len(v.Array) > fuzzMaxArrayLen {
+        t.Fatalf("aggregate of %d elements exceeds the limit", len(v.Array))
+      }
+      if isBulkArray(v) {
+        checkCommandRoundTrip(t, v)
+      }
+    }
+  })
+}
+
+func FuzzReadCommand(f *testing.F) {
+  for _, seed := range respSeeds {
+    f.Add([]byte(seed))
+  }
+  f.Fuzz(func(t *testing.T, data []byte) {
+    r := newFuzzResp(data)
+    for {
+      v, _, err := r.ReadCommand()
+      if err != nil {
+        checkReadError(t, err)
+        return
+      }
+      // Lệnh inline luôn là Array các Bulk String; lệnh RESP có thể chứa kiểu khác (server sẽ từ chối)
+      if v.Typ != "array" && v.Typ != "null" {
+        if !(len(data) > 0 && data[0] == ARRAY) {
+          t.Fatalf("command of type %q", v.Typ)
+        }
+      }
+      if isBulkArray(v) {
+        checkCommandRoundTrip(t, v)
+      }
+    }
+  })
+}
+
+func TestReadCommandInline(t *testing.T) {
+  tests := []struct {
+    name    string
+    input   string
+    want    [][]string // Các lệnh đọc được trước lỗi (nếu có)
+    wantErr error      // nil: đọc hết tới EOF; errProtocol: lỗi giao thức bất kỳ
+  }{
+    {"crlf", "PING\r\n", [][]string{{"PING"}}, nil},
+    {"lf only", "SET key value\nGET key\n", [][]string{{"SET", "key", "value"}, {"GET", "key"}}, nil},
+    {"quoted arguments", "SET key \"hello world\" 'a b'\r\n", [][]string{{"SET", "key", "hello world", "a b"}}, nil},
+    {"blank lines are skipped", "\r\n  \n\r\nPING\r\n\n", [][]string{{"PING"}}, nil},
+    {"mixed with RESP", "PING\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\nECHO x\r\n",
+      [][]string{{"PING"}, {"GET", "k"}, {"ECHO", "x"}}, nil},
+    {"unbalanced quotes", "PING\r\nSET \"k v\r\nPING\r\n", [][]string{{"PING"}}, errProtocol},
+    {"missing newline", "PING\r\nGET k", [][]string{{"PING"}}, io.ErrUnexpectedEOF},
+    {"too long", strings.Repeat("x", maxLineLen+10) + "\r\n", nil, errProtocol},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      r := NewResp(strings.NewReader(tt.input))
+      var got [][]string
+      var err error
+      for {
+        var v Value
+        if v, _, err = r.ReadCommand(); err != nil {
+          break
+        }
+        args := make([]string, len(v.Array))
+        for i, arg := range v.Array {
+          args[i] = arg.Bulk
+        }
+        got = append(got, args)
+      }
+
+      switch {
+      case tt.wantErr == nil && err != io.EOF:
+        t.Errorf("ReadCommand() error = %v, want io.EOF", err)
+      case tt.wantErr == errProtocol && !IsProtocolError(err):
+        t.Errorf("ReadCommand() error = %v, want a protocol error", err)
+      case tt.wantErr != nil && tt.wantErr != errProtocol && err != tt.wantErr:
+        t.Errorf("ReadCommand() error = %v, want %v", err, tt.wantErr)
+      }
+      if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+        t.Errorf("ReadCommand() read %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestReadLimits(t *testing.T) {
+  tests := []struct {
+    name  string
+    input string
+    want  error
+  }{
+    {"bulk too big", "$2000\r\n", ErrInvalidBulkLength},
+    {"array too big", "*100\r\n", ErrInvalidArrayLength},
+    {"too deep", strings.Repeat("*1\r\n", 40) + ":1\r\n", ErrTooDeep},
+    {"line too long", "+" + strings.Repeat("a", maxLineLen+10) + "\r\n", ErrLineTooLong},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      _, _, err := newFuzzResp([]byte(tt.input)).Read()
+      if !errors.Is(err, tt.want) {
+        t.Fatalf("Read() error = %v, want %v", err, tt.want)
+      }
+    })
+  }
+}
+
+// loopReader trả về data lặp lại vô hạn, để benchmark đọc không phụ thuộc vào việc tạo input
+type loopReader struct {
+  data []byte
+  pos  int
+}
+
+func (l *loopReader) Read(p []byte) (int, error) {
+  n := 0
+  for n < len(p) {
+    c := copy(p[n:], l.data[l.pos:])
+    n += c
+    l.pos = (l.pos + c) % len(l.data)
+  }
+  return n, nil
+}
+
+// benchmarkRead đo việc đọc liên tục frame bằng read
+func benchmarkRead(b *testing.B, frame []byte, read func(*Resp) (Value, int, error)) {
+  r := NewResp(&loopReader{data: frame})
+  b.SetBytes(int64(len(frame)))
+  b.ReportAllocs()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    if _, _, err := read(r); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
+
+func BenchmarkReadBulkSmall(b *testing.B) {
+  benchmarkRead(b, []byte("$16\r\n0123456789abcdef\r\n"), (*Resp).Read)
+}
+
+func BenchmarkReadBulkLarge(b *testing.B) {
+  payload := strings.Repeat("x", 64*1024)
+  benchmarkRead(b, Value{Typ: "bulk", Bulk: payload}.Marshal(), (*Resp).Read)
+}
+
+func BenchmarkReadArrayCommand(b *testing.B) {
+  benchmarkRead(b, MarshalCommand([]string{"SET", "key:000123", "some value of moderate size"}), (*Resp).ReadCommand)
+}
+
+func BenchmarkReadArrayLarge(b *testing.B) {
+  parts := make([]string, 1000)
+  for i := range parts {
+    parts[i] = "member-" + strings.Repeat("x", i%32)
+  }
+  benchmarkRead(b, MarshalCommand(parts), (*Resp).Read)
+}
+
+func BenchmarkReadInline(b *testing.B) {
+  benchmarkRead(b, []byte("SET key:000123 \"some value\"\r\n"), (*Resp).ReadCommand)
+}
+
+func BenchmarkWriteBulk(b *testing.B) {
+  v := Value{Typ: "bulk", Bulk: strings.Repeat("x", 1024)}
+  var buf []byte
+  b.SetBytes(int64(len(v.Marshal())))
+  b.ReportAllocs()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    buf = v.AppendTo(buf[:0])
+  }
+}
+
+func BenchmarkWriteArray(b *testing.B) {
+  items := make([]Value, 100)
+  for i := range items {
+    items[i] = Value{Typ: "bulk", Bulk: "member-" + strings.Repeat("x", i%32)}
+  }
+  v := Value{Typ: "array", Array: items}
+  var buf []byte
+  b.SetBytes(int64(len(v.Marshal())))
+  b.ReportAllocs()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    buf = v.AppendTo(buf[:0])
+  }
+}
+
+func BenchmarkWriteCommand(b *testing.B) {
+  parts := []string{"SET", "key:000123", "some value of moderate size"}
+  var buf []byte
+  b.ReportAllocs()
+  for i := 0; i < b.N; i++ {
+    buf = AppendCommand(buf[:0], parts)
+  }
+}
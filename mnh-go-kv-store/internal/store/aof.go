@@ -2,11 +2,13 @@ package store
 
 import (
   "bufio"
+  "errors"
   "fmt"
-  "io"
   "os"
+  "path/filepath"
   "sync"
 
+  "mnhgo/mnh-go-kv-store/internal/logging"
   "mnhgo/mnh-go-kv-store/internal/protocol"
 )
 
@@ -14,82 +16,439 @@ import (
 // Nó được sử dụng để tái tạo trạng thái Store khi đọc file AOF.
 type AOFCommandExecutor interface {
   ExecuteAOFCommand(cmdValue protocol.Value) // cmdValue là lệnh đã được parse
+  LoadSnapshot(entries map[string]Entry)     // entries là phần snapshot nhị phân ở đầu file (nếu có)
 }
 
+// Ngưỡng mặc định của việc tự động ghi lại AOF (giống auto-aof-rewrite-* của Redis)
+const (
+  DefaultRewritePercentage = 100              // File phải lớn gấp đôi kích thước sau lần ghi lại trước
+  DefaultRewriteMinSize    = 64 * 1024 * 1024 // Không tự động ghi lại khi file nhỏ hơn 64MB
+)
+
+// maxCmdBuffer: buffer mã hóa lệnh lớn hơn mức này không được giữ lại sau khi ghi
+const maxCmdBuffer = 64 * 1024
+
+// ErrRewriteInProgress được trả về khi đã có một lần ghi lại AOF đang chạy
+var ErrRewriteInProgress = errors.New("ERR Background append only file rewriting already in progress")
+
 // AOF struct quản lý file và buffer để ghi dữ liệu AOF
 type AOF struct {
-  file   *os.File
-  mu     sync.Mutex
-  writer *bufio.Writer
+  dir      string    // Thư mục chứa các file AOF (appenddirname)
+  name     string    // Tên gốc của các file AOF (appendfilename)
+  manifest *Manifest // Danh sách file hiện tại; chỉ thay thế (không sửa tại chỗ) khi giữ mu
+  file     *os.File  // File tăng dần đang được ghi (file cuối cùng trong manifest)
+  mu       sync.Mutex
+  writer   *bufio.Writer
+  cmdBuf   []byte // Buffer dùng lại để mã hóa lệnh (AppendCommand), chỉ dùng khi giữ mu
+
+  fsync       FsyncPolicy
+  syncMu      sync.Mutex // Giữ trong lúc fsync hoặc thay thế file; luôn lấy trước mu
+  syncCond    *sync.Cond // Báo cho các Goroutine chờ fsync (dùng với mu)
+  syncing     bool       // Đang có leader thực hiện fsync
+  writeSeq    uint64     // Số thứ tự của lệnh cuối cùng đã ghi vào buffer
+  syncedSeq   uint64     // Số thứ tự của lệnh cuối cùng đã được fsync
+  lastSyncErr error      // Lỗi fsync gần nhất
+  syncDone    chan struct{}
+  syncStopped chan struct{}
+  closeOnce   sync.Once
+
+  size           int64 // Tổng kích thước các file trong manifest
+  baseSize       int64 // Tổng kích thước sau lần ghi lại gần nhất (hoặc khi khởi động)
+  rewriting      bool  // Đang ghi lại AOF
+  rewriteIncrSeq int   // File tăng dần đầu tiên được mở khi bắt đầu lần ghi lại hiện tại
+  rewrites       int64 // Số lần ghi lại thành công
+  lastErr        error // Lỗi của lần ghi lại gần nhất
+
+  RewritePercentage int   // Tự động ghi lại khi file tăng quá tỉ lệ này so với baseSize (0: tắt)
+  RewriteMinSize    int64 // Kích thước tối thiểu để tự động ghi lại
+  // UseRDBPreamble: khi ghi lại, phần dữ liệu hiện có được ghi dưới dạng snapshot nhị phân (RDB)
+  // ở đầu file thay vì chuỗi lệnh RESP, giúp file nhỏ hơn và khởi động nhanh hơn
+  UseRDBPreamble bool
+  // LoadTruncated: khi khởi động, cắt bỏ lệnh cuối cùng bị ghi dở thay vì từ chối tải AOF
+  LoadTruncated bool
+}
+
+// AOFStats là trạng thái của AOF dùng cho giám sát (INFO)
+type AOFStats struct {
+  CurrentSize       int64
+  BaseSize          int64
+  RewriteInProgress bool
+  Rewrites          int64
+  LastRewriteErr    error
+  FsyncPolicy       FsyncPolicy
+  LastFsyncErr      error
+  PendingFsync      bool // Có lệnh đã ghi vào file nhưng chưa được fsync
 }
 
-// NewAOF khởi tạo hoặc mở file AOF
-func NewAOF(path string) (*AOF, error) {
+// NewAOF mở AOF nhiều phần trong thư mục dir: file nền cùng các file tăng dần được liệt kê trong
+// manifest "<name>.manifest". Nếu chưa có manifest, file AOF đơn cũ tại đường dẫn name (nếu có)
+// được chuyển vào dir làm file nền. Lệnh mới luôn được ghi vào file tăng dần cuối cùng.
+func NewAOF(dir, name string) (*AOF, error) {
+  if err := os.MkdirAll(dir, 0755); err != nil {
+    return nil, err
+  }
+  manifest, err := loadManifest(dir, name, name)
+  if err != nil {
+    return nil, fmt.Errorf("loading AOF manifest: %v", err)
+  }
+
   // os.O_APPEND|os.O_CREATE|os.O_WRONLY: Mở, ghi, tạo nếu không tồn tại, ghi tiếp vào cuối
-  f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+  incr := manifest.Incrs[len(manifest.Incrs)-1]
+  f, err := os.OpenFile(filepath.Join(dir, incr.Name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+  if err != nil {
+    return nil, err
+  }
+
+  size, err := filesSize(dir, manifest.Files())
   if err != nil {
+    f.Close()
     return nil, err
   }
 
   aof := &AOF{
-    file:   f,
-    writer: bufio.NewWriter(f),
+    dir:               dir,
+    name:              name,
+    manifest:          manifest,
+    file:              f,
+    writer:            bufio.NewWriter(f),
+    size:              size,
+    baseSize:          size,
+    RewritePercentage: DefaultRewritePercentage,
+    RewriteMinSize:    DefaultRewriteMinSize,
+    UseRDBPreamble:    true,
+    LoadTruncated:     true,
+    fsync:             FsyncEverySec,
+    syncDone:          make(chan struct{}),
+    syncStopped:       make(chan struct{}),
   }
+  aof.syncCond = sync.NewCond(&aof.mu)
+  go aof.syncLoop()
   return aof, nil
 }
 
+// filesSize trả về tổng kích thước các file trong dir (file chưa tồn tại được tính là rỗng)
+func filesSize(dir string, files []AOFFileInfo) (int64, error) {
+  var total int64
+  for _, info := range files {
+    st, err := os.Stat(filepath.Join(dir, info.Name))
+    if errors.Is(err, os.ErrNotExist) {
+      continue
+    }
+    if err != nil {
+      return 0, err
+    }
+    total += st.Size()
+  }
+  return total, nil
+}
+
+// Dir trả về thư mục chứa các file AOF
+func (a *AOF) Dir() string {
+  return a.dir
+}
+
+// ManifestPath trả về đường dẫn file manifest
+func (a *AOF) ManifestPath() string {
+  return filepath.Join(a.dir, ManifestName(a.name))
+}
+
 // WriteCommand ghi một lệnh RESP đã mã hóa vào file AOF.
+// Với FsyncAlways, hàm chỉ trả về sau khi lệnh đã được fsync xuống đĩa.
 func (a *AOF) WriteCommand(cmd []byte) error {
   a.mu.Lock()
-  defer a.mu.Unlock()
+  return a.writeLocked(cmd)
+}
 
-  _, err := a.writer.Write(cmd)
-  if err != nil {
-    return err
+// AppendCommand mã hóa lệnh parts vào buffer dùng lại của AOF rồi ghi như WriteCommand,
+// tránh cấp phát một slice mới cho mỗi lệnh
+func (a *AOF) AppendCommand(parts ...string) error {
+  a.mu.Lock()
+  a.cmdBuf = protocol.AppendCommand(a.cmdBuf[:0], parts)
+  cmd := a.cmdBuf
+  if cap(a.cmdBuf) > maxCmdBuffer {
+    a.cmdBuf = nil
   }
+  return a.writeLocked(cmd)
+}
 
-  // Flush dữ liệu từ buffer ra đĩa.
-  return a.writer.Flush()
+// AppendCommandNoWait ghi lệnh như AppendCommand nhưng không chờ fsync, dùng khi người gọi đang giữ
+// khóa của Store (không để Store bị chặn trong lúc fsync xuống đĩa). Với FsyncAlways, số thứ tự
+// trả về phải được truyền cho WaitSync (sau khi nhả khóa) trước khi trả lời client; lệnh không ai chờ
+// được syncLoop fsync trong vòng một giây. Với các chế độ khác, số thứ tự trả về là 0.
+func (a *AOF) AppendCommandNoWait(parts ...string) (uint64, error) {
+  a.mu.Lock()
+  a.cmdBuf = protocol.AppendCommand(a.cmdBuf[:0], parts)
+  cmd := a.cmdBuf
+  if cap(a.cmdBuf) > maxCmdBuffer {
+    a.cmdBuf = nil
+  }
+  return a.bufferLocked(cmd)
 }
 
-// ReadAndLoad đọc file AOF khi khởi động server để tái tạo trạng thái Store.
-func (a *AOF) ReadAndLoad(executor AOFCommandExecutor) error {
-  // 1. Đóng file hiện tại và mở lại ở chế độ đọc
-  if err := a.file.Close(); err != nil {
-    return err
+// WaitSync chờ cho tới khi lệnh có số thứ tự seq (do AppendCommandNoWait trả về) đã được fsync.
+// seq bằng 0 trả về ngay.
+func (a *AOF) WaitSync(seq uint64) error {
+  if seq == 0 {
+    return nil
   }
-  f, err := os.OpenFile(a.file.Name(), os.O_RDONLY, 0644)
+  return a.syncUpTo(seq)
+}
+
+// writeLocked ghi cmd vào buffer và fsync theo appendfsync.
+// Người gọi phải giữ a.mu; a.mu được nhả trước khi hàm trả về.
+func (a *AOF) writeLocked(cmd []byte) error {
+  seq, err := a.bufferLocked(cmd)
   if err != nil {
     return err
   }
-  defer f.Close()
+  return a.WaitSync(seq)
+}
+
+// bufferLocked ghi cmd vào buffer. Với FsyncAlways, trả về số thứ tự của lệnh để người gọi chờ fsync;
+// với các chế độ khác, buffer được flush ra file ngay (fsync do syncLoop hoặc hệ điều hành đảm nhận)
+// và trả về 0. Người gọi phải giữ a.mu; a.mu được nhả trước khi hàm trả về.
+func (a *AOF) bufferLocked(cmd []byte) (uint64, error) {
+  defer a.mu.Unlock()
+  n, err := a.writer.Write(cmd)
+  a.size += int64(n)
+  if err != nil {
+    return 0, err
+  }
 
-  // 2. Khởi tạo RESP Reader từ file để tái sử dụng logic parsing
-  respReader := protocol.NewResp(f)
+  a.writeSeq++
+  if a.fsync != FsyncAlways {
+    return 0, a.writer.Flush()
+  }
+  return a.writeSeq, nil
+}
 
-  // 3. Vòng lặp đọc và thực thi lệnh từ file AOF
-  for {
-    // Dùng Resp.Read() để đọc và parse một lệnh RESP hoàn chỉnh
-    cmdValue, _, err := respReader.Read()
+// ReadAndLoad đọc các file AOF theo thứ tự trong manifest khi khởi động server để tái tạo trạng thái Store.
+// File nền có thể bắt đầu bằng một snapshot nhị phân (RDB preamble), phần còn lại là các lệnh RESP.
+// Nếu lệnh cuối cùng của file tăng dần cuối cùng bị ghi dở (server dừng đột ngột) và LoadTruncated bật,
+// phần dở dang được cắt khỏi file để các lệnh mới không bị ghi nối sau dữ liệu hỏng;
+// mọi lỗi khác trả về *AOFFormatError (kèm tên file).
+func (a *AOF) ReadAndLoad(executor AOFCommandExecutor) error {
+  a.mu.Lock()
+  files := a.manifest.Files()
+  a.mu.Unlock()
 
+  for i, info := range files {
+    path := filepath.Join(a.dir, info.Name)
+    f, err := os.Open(path)
     if err != nil {
-      if err == io.EOF {
-        break // Đã đọc hết file
-      }
-      // Báo cáo lỗi parsing trong file AOF và dừng tải
-      return fmt.Errorf("error parsing AOF command: %v", err)
+      return err
     }
+    _, validSize, loadErr := readAOF(f, executor.LoadSnapshot, executor.ExecuteAOFCommand)
+    f.Close()
 
-    executor.ExecuteAOFCommand(cmdValue)
+    var formatErr *AOFFormatError
+    last := i == len(files)-1
+    if last && errors.As(loadErr, &formatErr) && formatErr.Truncated() && a.LoadTruncated {
+      logging.Warningf("!!! Warning: short read while loading the AOF file %s at offset %d: %v", path, validSize, formatErr.Err)
+      logging.Warningf("!!! Truncating the AOF at offset %d !!!", validSize)
+      if err := os.Truncate(path, validSize); err != nil {
+        return fmt.Errorf("error truncating AOF: %v", err)
+      }
+      loadErr = nil
+    }
+    if loadErr != nil {
+      return fmt.Errorf("%s: %w", info.Name, loadErr)
+    }
   }
 
-  // 5. Sau khi đọc xong, mở lại file ở chế độ ghi/append
-  f, err = os.OpenFile(a.file.Name(), os.O_APPEND|os.O_WRONLY, 0644)
+  size, err := filesSize(a.dir, files)
   if err != nil {
     return err
   }
+  a.mu.Lock()
+  a.size = size
+  a.baseSize = size
+  a.mu.Unlock()
+  return nil
+}
+
+// BeginRewrite bắt đầu ghi lại AOF: lấy snapshot của Store và từ cùng thời điểm đó chuyển sang ghi
+// vào một file tăng dần mới (đã được thêm vào manifest). Hai việc này diễn ra khi giữ khóa của Store
+// nên các lệnh được ghi AOF trong lúc giữ khóa ghi của Store (xóa key hết hạn, phục vụ BLPOP)
+// nằm trọn ở một phía. Người gọi phải đảm bảo không có lệnh nào đã thay đổi Store mà chưa kịp
+// ghi AOF, rồi gọi FinishRewrite với snapshot trả về (thường trong một Goroutine riêng).
+func (a *AOF) BeginRewrite(s *Store) (map[string]Entry, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+  a.syncMu.Lock()
+  defer a.syncMu.Unlock()
+  a.mu.Lock()
+  defer a.mu.Unlock()
+
+  if a.rewriting {
+    return nil, ErrRewriteInProgress
+  }
+
+  // Mở file tăng dần mới và ghi manifest trước khi ghi lệnh nào vào file đó,
+  // để nếu server dừng giữa chừng thì các lệnh này vẫn được phát lại
+  incr := a.manifest.nextIncr(a.name)
+  f, err := os.OpenFile(filepath.Join(a.dir, incr.Name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+  if err != nil {
+    return nil, err
+  }
+  manifest := a.manifest.clone()
+  manifest.add(incr)
+  if err := writeManifest(a.dir, a.name, manifest); err != nil {
+    f.Close()
+    os.Remove(f.Name())
+    return nil, err
+  }
+
+  // File cũ được fsync trước khi đóng: mọi lệnh đã ghi tính tới lúc này đều bền vững
+  err = a.writer.Flush()
+  if err == nil {
+    err = a.file.Sync()
+  }
+  if err != nil {
+    a.lastSyncErr = err
+  } else {
+    a.syncedSeq = a.writeSeq
+    a.syncCond.Broadcast()
+  }
+  a.file.Close()
   a.file = f
   a.writer = bufio.NewWriter(f)
+  a.manifest = manifest
+  a.rewriting = true
+  a.rewriteIncrSeq = incr.Seq
+  return s.snapshotLocked(), nil
+}
 
-  return nil
+// FinishRewrite ghi snapshot thành file nền mới, rồi cập nhật manifest một cách nguyên tử để
+// chỉ còn file nền mới và các file tăng dần được mở từ lúc bắt đầu ghi lại.
+// Các file cũ không còn trong manifest bị xóa.
+func (a *AOF) FinishRewrite(snapshot map[string]Entry) error {
+  obsolete, err := a.finishRewrite(snapshot)
+
+  a.mu.Lock()
+  a.rewriting = false
+  a.lastErr = err
+  if err == nil {
+    a.rewrites++
+  }
+  a.mu.Unlock()
+
+  for _, info := range obsolete {
+    if err := os.Remove(filepath.Join(a.dir, info.Name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+      logging.Warningf("Failed to remove obsolete AOF file %s: %v", info.Name, err)
+    }
+  }
+  return err
+}
+
+// finishRewrite trả về các file không còn trong manifest mới
+func (a *AOF) finishRewrite(snapshot map[string]Entry) ([]AOFFileInfo, error) {
+  a.mu.Lock()
+  usePreamble := a.UseRDBPreamble
+  ext := "aof"
+  if usePreamble {
+    ext = "rdb"
+  }
+  base := a.manifest.nextBase(a.name, ext)
+  a.mu.Unlock()
+
+  basePath := filepath.Join(a.dir, base.Name)
+  tmpPath := filepath.Join(a.dir, "temp-rewrite-"+base.Name)
+  tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+  if err != nil {
+    return nil, err
+  }
+  // Xóa file tạm nếu có lỗi (sau khi rename thành công thì Remove không còn tác dụng)
+  defer os.Remove(tmpPath)
+  defer tmp.Close()
+
+  // Toàn bộ dữ liệu được ghi khi không giữ khóa, các lệnh mới vẫn tiếp tục được ghi vào file tăng dần
+  if usePreamble {
+    err = WriteRDB(tmp, snapshot)
+  } else {
+    err = WriteAOFSnapshot(tmp, snapshot)
+  }
+  if err != nil {
+    return nil, err
+  }
+  if err := tmp.Sync(); err != nil {
+    return nil, err
+  }
+  info, err := tmp.Stat()
+  if err != nil {
+    return nil, err
+  }
+  if err := os.Rename(tmpPath, basePath); err != nil {
+    return nil, err
+  }
+
+  a.mu.Lock()
+  defer a.mu.Unlock()
+
+  manifest := &Manifest{baseSeq: a.manifest.baseSeq, incrSeq: a.manifest.incrSeq}
+  manifest.add(base)
+  var obsolete []AOFFileInfo
+  if a.manifest.Base != nil {
+    obsolete = append(obsolete, *a.manifest.Base)
+  }
+  for _, incr := range a.manifest.Incrs {
+    if incr.Seq >= a.rewriteIncrSeq {
+      manifest.add(incr)
+    } else {
+      obsolete = append(obsolete, incr)
+    }
+  }
+
+  removed, err := filesSize(a.dir, obsolete)
+  if err == nil {
+    err = writeManifest(a.dir, a.name, manifest)
+  }
+  if err != nil {
+    os.Remove(basePath)
+    return nil, err
+  }
+  a.manifest = manifest
+
+  // Kích thước mới: file nền mới cộng các file tăng dần còn lại
+  a.size += info.Size() - removed
+  a.baseSize = a.size
+  return obsolete, nil
+}
+
+// SetRewriteOptions thay đổi các ngưỡng tự động ghi lại và việc dùng snapshot nhị phân khi đang chạy
+func (a *AOF) SetRewriteOptions(percentage int, minSize int64, useRDBPreamble bool) {
+  a.mu.Lock()
+  defer a.mu.Unlock()
+  a.RewritePercentage = percentage
+  a.RewriteMinSize = minSize
+  a.UseRDBPreamble = useRDBPreamble
+}
+
+// ShouldRewrite cho biết file AOF đã tăng đủ lớn để tự động ghi lại hay chưa
+func (a *AOF) ShouldRewrite() bool {
+  a.mu.Lock()
+  defer a.mu.Unlock()
+
+  if a.rewriting || a.RewritePercentage <= 0 || a.size < a.RewriteMinSize {
+    return false
+  }
+  base := max(a.baseSize, 1)
+  return (a.size-base)*100/base >= int64(a.RewritePercentage)
+}
+
+// Stats trả về trạng thái hiện tại của AOF
+func (a *AOF) Stats() AOFStats {
+  a.mu.Lock()
+  defer a.mu.Unlock()
+
+  return AOFStats{
+    CurrentSize:       a.size,
+    BaseSize:          a.baseSize,
+    RewriteInProgress: a.rewriting,
+    Rewrites:          a.rewrites,
+    LastRewriteErr:    a.lastErr,
+    FsyncPolicy:       a.fsync,
+    LastFsyncErr:      a.lastSyncErr,
+    PendingFsync:      a.syncedSeq < a.writeSeq,
+  }
 }
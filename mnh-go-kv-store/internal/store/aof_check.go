@@ -0,0 +1,116 @@
+package store
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// AOFFormatError mô tả vị trí của mục hỏng đầu tiên trong file AOF.
+// Mọi dữ liệu trước Offset đều hợp lệ.
+type AOFFormatError struct {
+  Offset   int64 // Vị trí (byte) bắt đầu của mục hỏng
+  Preamble bool  // Lỗi nằm trong snapshot nhị phân ở đầu file
+  Err      error
+}
+
+func (e *AOFFormatError) Error() string {
+  return fmt.Sprintf("bad AOF entry at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *AOFFormatError) Unwrap() error {
+  return e.Err
+}
+
+// Truncated cho biết file chỉ bị cắt cụt ở lệnh cuối cùng (ghi dở), không phải dữ liệu hỏng ở giữa file.
+// Snapshot ở đầu file bị cắt cụt không bao giờ được coi là có thể cắt bỏ.
+func (e *AOFFormatError) Truncated() bool {
+  return !e.Preamble && errors.Is(e.Err, io.ErrUnexpectedEOF)
+}
+
+// AOFCheckResult là kết quả kiểm tra một file AOF
+type AOFCheckResult struct {
+  Size      int64 // Kích thước file
+  ValidSize int64 // Số byte hợp lệ tính từ đầu file
+  Preamble  bool  // File bắt đầu bằng snapshot nhị phân (RDB preamble)
+  Commands  int   // Số lệnh RESP hợp lệ
+}
+
+// readAOF đọc lần lượt snapshot ở đầu file (nếu có) và các lệnh RESP của f, gọi onSnapshot/onCommand
+// (có thể nil) cho từng phần hợp lệ. Trả về số lệnh đã đọc, số byte hợp lệ tính từ đầu file
+// và *AOFFormatError nếu gặp mục hỏng.
+func readAOF(f *os.File, onSnapshot func(map[string]Entry), onCommand func(protocol.Value)) (int, int64, error) {
+  reader := bufio.NewReader(f)
+  // offset trả về vị trí logic trong file: phần đã đọc từ file trừ phần còn nằm trong buffer
+  offset := func() (int64, error) {
+    pos, err := f.Seek(0, io.SeekCurrent)
+    return pos - int64(reader.Buffered()), err
+  }
+
+  // Phần snapshot nhị phân ở đầu file (nếu có). ReadRDB dùng chung bufio.Reader
+  // nên sau khi đọc xong checksum, reader dừng đúng ở lệnh RESP đầu tiên của phần đuôi.
+  if hasRDBPreamble(reader) {
+    info, err := f.Stat()
+    if err != nil {
+      return 0, 0, err
+    }
+    entries, err := ReadRDB(reader, info.Size())
+    if err != nil {
+      return 0, 0, &AOFFormatError{Offset: 0, Preamble: true, Err: fmt.Errorf("bad RDB preamble: %w", err)}
+    }
+    if onSnapshot != nil {
+      onSnapshot(entries)
+    }
+  }
+
+  // Khởi tạo RESP Reader để tái sử dụng logic parsing
+  respReader := protocol.NewResp(reader)
+  commands := 0
+  for {
+    start, err := offset()
+    if err != nil {
+      return commands, 0, err
+    }
+
+    // Dùng Resp.Read() để đọc và parse một lệnh RESP hoàn chỉnh
+    cmdValue, _, err := respReader.Read()
+    if err == io.EOF {
+      return commands, start, nil // Đã đọc hết file
+    }
+    if err == nil && (cmdValue.Typ != "array" || len(cmdValue.Array) == 0) {
+      err = fmt.Errorf("expected a command array, got %s", cmdValue.Typ)
+    }
+    if err != nil {
+      return commands, start, &AOFFormatError{Offset: start, Err: err}
+    }
+
+    if onCommand != nil {
+      onCommand(cmdValue)
+    }
+    commands++
+  }
+}
+
+// CheckAOF kiểm tra file AOF tại path mà không thực thi lệnh nào.
+// Lỗi định dạng được trả về dưới dạng *AOFFormatError cùng với kết quả đã kiểm tra tới đó.
+func CheckAOF(path string) (AOFCheckResult, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return AOFCheckResult{}, err
+  }
+  defer f.Close()
+
+  info, err := f.Stat()
+  if err != nil {
+    return AOFCheckResult{}, err
+  }
+
+  result := AOFCheckResult{Size: info.Size()}
+  result.Preamble = hasRDBPreamble(bufio.NewReader(io.NewSectionReader(f, 0, info.Size())))
+  result.Commands, result.ValidSize, err = readAOF(f, nil, nil)
+  return result, err
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+  "bytes"
+  "errors"
+  "os"
+  "path/filepath"
+  "testing"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// testCommands mã hóa các lệnh thành RESP như khi ghi vào AOF
+func testCommands(cmds ...[]string) []byte {
+  var buf []byte
+  for _, cmd := range cmds {
+    buf = protocol.AppendCommand(buf, cmd)
+  }
+  return buf
+}
+
+func TestCheckAOF(t *testing.T) {
+  commands := testCommands([]string{"SET", "a", "1"}, []string{"SET", "b", "2"})
+  last := testCommands([]string{"DEL", "a"})
+  tail := len(commands) // Vị trí của lệnh cuối cùng
+
+  tests := []struct {
+    name      string
+    data      []byte
+    preamble  bool
+    commands  int
+    validSize int
+    corrupt   bool // Có lỗi định dạng
+    truncated bool // Lỗi chỉ là lệnh cuối cùng bị ghi dở
+    badRDB    bool // Lỗi nằm trong snapshot ở đầu file
+  }{
+    {"empty", nil, false, 0, 0, false, false, false},
+    {"valid", append(bytes.Clone(commands), last...), false, 3, tail + len(last), false, false, false},
+    {"truncated last command", append(bytes.Clone(commands), last[:len(last)-3]...), false, 2, tail, true, true, false},
+    {"truncated header", append(bytes.Clone(commands), "*2\r"...), false, 2, tail, true, true, false},
+    {"garbage in the middle", append(append(bytes.Clone(commands), "+OK\r\n"...), last...), false, 2, tail, true, false, false},
+    {"bad length", append(bytes.Clone(commands), "*2\r\n$x\r\nDEL\r\n"...), false, 2, tail, true, false, false},
+    {"valid preamble", append(testSnapshot(t), commands...), true, 2, len(testSnapshot(t)) + tail, false, false, false},
+    {"truncated preamble", testSnapshot(t)[:20], true, 0, 0, true, false, true},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      path := filepath.Join(t.TempDir(), "appendonly.aof")
+      if err := os.WriteFile(path, tt.data, 0644); err != nil {
+        t.Fatal(err)
+      }
+
+      result, err := CheckAOF(path)
+      var formatErr *AOFFormatError
+      if tt.corrupt != errors.As(err, &formatErr) {
+        t.Fatalf("CheckAOF() error = %v, corrupt = %v", err, tt.corrupt)
+      }
+      if formatErr != nil {
+        if formatErr.Truncated() != tt.truncated {
+          t.Errorf("Truncated() = %v, want %v (%v)", formatErr.Truncated(), tt.truncated, formatErr)
+        }
+        if formatErr.Offset != int64(tt.validSize) || formatErr.Preamble != tt.badRDB {
+          t.Errorf("error at offset %d (preamble %v), want %d", formatErr.Offset, formatErr.Preamble, tt.validSize)
+        }
+      }
+      want := AOFCheckResult{Size: int64(len(tt.data)), ValidSize: int64(tt.validSize), Preamble: tt.preamble, Commands: tt.commands}
+      if result != want {
+        t.Errorf("CheckAOF() = %+v, want %+v", result, want)
+      }
+    })
+  }
+}
+
+// countingExecutor đếm các lệnh và key trong snapshot được phát lại từ AOF
+type countingExecutor struct {
+  commands int
+  keys     int
+}
+
+func (e *countingExecutor) ExecuteAOFCommand(protocol.Value) {
+  e.commands++
+}
+
+func (e *countingExecutor) LoadSnapshot(entries map[string]Entry) {
+  e.keys += len(entries)
+}
+
+func TestReadAndLoadTruncatedTail(t *testing.T) {
+  commands := testCommands([]string{"SET", "a", "1"}, []string{"SET", "b", "2"})
+  tests := []struct {
+    name          string
+    tail          string
+    loadTruncated bool
+    wantErr       bool
+  }{
+    {"truncated tail is trimmed", "*2\r\n$3\r\nDEL", true, false},
+    {"strict mode rejects a truncated tail", "*2\r\n$3\r\nDEL", false, true},
+    {"corrupt tail is never trimmed", "-ERR\r\n", true, true},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      dir := t.TempDir()
+      aof, err := NewAOF(dir, "appendonly.aof")
+      if err != nil {
+        t.Fatal(err)
+      }
+      defer aof.Close()
+      aof.LoadTruncated = tt.loadTruncated
+      path := filepath.Join(dir, aof.manifest.Incrs[0].Name)
+      if err := os.WriteFile(path, append(bytes.Clone(commands), tt.tail...), 0644); err != nil {
+        t.Fatal(err)
+      }
+
+      var executor countingExecutor
+      err = aof.ReadAndLoad(&executor)
+      if (err != nil) != tt.wantErr {
+        t.Fatalf("ReadAndLoad() = %v, wantErr %v", err, tt.wantErr)
+      }
+      if executor.commands != 2 {
+        t.Errorf("replayed %d commands, want 2", executor.commands)
+      }
+
+      // Phần đuôi chỉ bị cắt khi được phép và khi nó chỉ là lệnh ghi dở
+      info, err := os.Stat(path)
+      if err != nil {
+        t.Fatal(err)
+      }
+      wantSize := len(commands) + len(tt.tail)
+      if !tt.wantErr {
+        wantSize = len(commands)
+      }
+      if info.Size() != int64(wantSize) {
+        t.Errorf("AOF size after loading = %d, want %d", info.Size(), wantSize)
+      }
+    })
+  }
+}
@@ -0,0 +1,138 @@
+package store
+
+// KeyWaiter đại diện cho một kết nối đang bị chặn (BLPOP, BRPOP, BLMOVE) chờ dữ liệu
+// trên một hoặc nhiều key. Mỗi kết nối chỉ có tối đa một KeyWaiter tại một thời điểm.
+type KeyWaiter struct {
+  keys  []string
+  ready chan struct{}                   // Đóng lại khi waiter đã được phục vụ
+  serve func(key string) (bool, error) // Thực hiện thao tác lấy phần tử, gọi khi đang giữ khóa ghi
+  done  bool
+
+  Key   string // Key đã phục vụ waiter
+  Value string // Phần tử đã lấy được
+  Err   error  // Lỗi xảy ra khi phục vụ (ví dụ dst của BLMOVE sai kiểu)
+}
+
+// Ready trả về channel được đóng khi waiter đã được phục vụ
+func (w *KeyWaiter) Ready() <-chan struct{} {
+  return w.ready
+}
+
+// BPOP thử lấy một phần tử từ List đầu tiên không rỗng trong keys (theo thứ tự).
+// Nếu tất cả đều rỗng, một KeyWaiter được đăng ký vào hàng đợi của từng key trong cùng
+// một lần giữ khóa, nên không thể bỏ lỡ tín hiệu từ lệnh PUSH xảy ra ngay sau đó.
+// onServed được gọi (khi đang giữ khóa ghi) lúc waiter được phục vụ, dùng để ghi AOF
+// cùng lúc với thay đổi trên Store; onServed không được chờ fsync vì mọi lệnh khác đang chờ khóa.
+func (s *Store) BPOP(keys []string, front bool, onServed func(key, value string)) (string, string, *KeyWaiter, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  for _, key := range keys {
+    values, err := s.popListLocked(key, 1, front)
+    if err != nil {
+      return "", "", nil, err
+    }
+    if len(values) > 0 {
+      return key, values[0], nil, nil
+    }
+  }
+
+  w := &KeyWaiter{ready: make(chan struct{})}
+  w.serve = func(key string) (bool, error) {
+    values, err := s.popListLocked(key, 1, front)
+    if err != nil || len(values) == 0 {
+      return false, err
+    }
+    w.Key, w.Value = key, values[0]
+    onServed(key, values[0])
+    return true, nil
+  }
+  s.registerWaiterLocked(keys, w)
+  return "", "", w, nil
+}
+
+// BLMOVE giống LMOVE nhưng đăng ký KeyWaiter trên src nếu src đang rỗng.
+// onServed có ý nghĩa giống như trong BPOP.
+func (s *Store) BLMOVE(src, dst string, srcFront, dstFront bool, onServed func(key, value string)) (string, bool, *KeyWaiter, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  value, ok, err := s.lmoveLocked(src, dst, srcFront, dstFront)
+  if err != nil || ok {
+    return value, ok, nil, err
+  }
+
+  w := &KeyWaiter{ready: make(chan struct{})}
+  w.serve = func(key string) (bool, error) {
+    value, ok, err := s.lmoveLocked(src, dst, srcFront, dstFront)
+    if err != nil || !ok {
+      return false, err
+    }
+    w.Key, w.Value = key, value
+    onServed(key, value)
+    return true, nil
+  }
+  s.registerWaiterLocked([]string{src}, w)
+  return "", false, w, nil
+}
+
+// registerWaiterLocked thêm waiter vào cuối hàng đợi của từng key.
+// Người gọi phải giữ khóa ghi s.mu.
+func (s *Store) registerWaiterLocked(keys []string, w *KeyWaiter) {
+  w.keys = keys
+  for _, key := range keys {
+    s.waiters[key] = append(s.waiters[key], w)
+  }
+}
+
+// unregisterWaiterLocked xóa waiter khỏi hàng đợi của tất cả các key mà nó đang chờ
+func (s *Store) unregisterWaiterLocked(w *KeyWaiter) {
+  for _, key := range w.keys {
+    queue := s.waiters[key]
+    for i, other := range queue {
+      if other == w {
+        queue = append(queue[:i], queue[i+1:]...)
+        break
+      }
+    }
+    if len(queue) == 0 {
+      delete(s.waiters, key)
+    } else {
+      s.waiters[key] = queue
+    }
+  }
+}
+
+// CancelWait hủy đăng ký waiter (khi hết thời gian chờ).
+// Trả về true nếu waiter đã kịp được phục vụ trước đó, khi đó kết quả vẫn phải được dùng.
+func (s *Store) CancelWait(w *KeyWaiter) bool {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if w.done {
+    return true
+  }
+  s.unregisterWaiterLocked(w)
+  return false
+}
+
+// SignalKeyReady phục vụ các kết nối đang chờ trên key theo đúng thứ tự FIFO cho tới khi
+// List hết phần tử. Được gọi sau khi lệnh ghi vào List đã được ghi AOF, để lệnh POP của
+// waiter (ghi trong onServed) luôn nằm sau lệnh PUSH trong file AOF.
+func (s *Store) SignalKeyReady(key string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  for len(s.waiters[key]) > 0 {
+    w := s.waiters[key][0]
+    served, err := w.serve(key)
+    if !served && err == nil {
+      return // List đã rỗng
+    }
+
+    s.unregisterWaiterLocked(w)
+    w.Err = err
+    w.done = true
+    close(w.ready)
+  }
+}
@@ -0,0 +1,268 @@
+package store
+
+import (
+  "sync"
+  "time"
+)
+
+const (
+  // ActiveExpireInterval là chu kỳ chạy vòng xóa key hết hạn chủ động (10 lần/giây như Redis)
+  ActiveExpireInterval = 100 * time.Millisecond
+  // ActiveExpireBudget là thời gian tối đa của một vòng (25% chu kỳ)
+  ActiveExpireBudget = 25 * time.Millisecond
+
+  activeExpireSamples      = 20 // Số key có TTL được lấy mẫu mỗi lượt
+  activeExpireStalePercent = 25 // Lấy mẫu tiếp nếu hơn 25% số mẫu đã hết hạn
+)
+
+// ExpireStats là các bộ đếm về việc xóa key hết hạn, dùng cho giám sát (INFO)
+type ExpireStats struct {
+  ExpiredKeys    int64         // Tổng số key đã bị xóa do hết hạn
+  TimeCapReached int64         // Số vòng xóa chủ động bị dừng do hết ngân sách thời gian
+  CycleTime      time.Duration // Tổng thời gian đã dùng cho các vòng xóa chủ động
+}
+
+// ExpireOptions là các cờ điều kiện của lệnh EXPIRE/PEXPIRE/EXPIREAT/PEXPIREAT.
+// Key không có TTL được coi như có TTL vô hạn khi so sánh GT/LT (giống Redis).
+type ExpireOptions struct {
+  NX bool // Chỉ đặt khi key chưa có TTL
+  XX bool // Chỉ đặt khi key đã có TTL
+  GT bool // Chỉ đặt khi thời điểm hết hạn mới lớn hơn hiện tại
+  LT bool // Chỉ đặt khi thời điểm hết hạn mới nhỏ hơn hiện tại
+}
+
+// allows kiểm tra thời điểm hết hạn mới at có thỏa điều kiện so với current hay không
+func (opts ExpireOptions) allows(current, at time.Time) bool {
+  hasTTL := !current.IsZero()
+  switch {
+  case opts.NX && hasTTL:
+    return false
+  case opts.XX && !hasTTL:
+    return false
+  case opts.GT && (!hasTTL || !at.After(current)):
+    return false
+  case opts.LT && hasTTL && !at.Before(current):
+    return false
+  }
+  return true
+}
+
+// EXPIREAT: Đặt thời điểm hết hạn tuyệt đối cho key thuộc bất kỳ kiểu dữ liệu nào.
+// Trả về true nếu TTL đã được đặt; thời điểm đã qua thì key bị xóa ngay.
+func (s *Store) EXPIREAT(key string, at time.Time, opts ExpireOptions) bool {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  entry, ok := s.lookupWrite(key)
+  if !ok || !opts.allows(entry.ExpiresAt, at) {
+    return false
+  }
+
+  entry.ExpiresAt = at
+  if s.expiredLocked(entry) {
+    delete(s.data, key)
+  } else {
+    s.data[key] = entry
+    s.trackExpiryLocked(key, entry)
+  }
+  return true
+}
+
+// PERSIST: Xóa TTL của key, trả về true nếu key có TTL trước đó
+func (s *Store) PERSIST(key string) bool {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  entry, ok := s.lookupWrite(key)
+  if !ok || entry.ExpiresAt.IsZero() {
+    return false
+  }
+
+  entry.ExpiresAt = time.Time{}
+  s.data[key] = entry
+  return true
+}
+
+// PTTL: Thời gian sống còn lại của key tính bằng mili giây.
+// Trả về -2 nếu key không tồn tại, -1 nếu key không có TTL.
+func (s *Store) PTTL(key string) int64 {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  entry, ok := s.lookupRead(key)
+  if !ok {
+    return -2
+  }
+  if entry.ExpiresAt.IsZero() {
+    return -1
+  }
+  return max(time.Until(entry.ExpiresAt).Milliseconds(), 0)
+}
+
+// PEXPIRETIME: Thời điểm hết hạn tuyệt đối của key (Unix mili giây).
+// Trả về -2 nếu key không tồn tại, -1 nếu key không có TTL.
+func (s *Store) PEXPIRETIME(key string) int64 {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  entry, ok := s.lookupRead(key)
+  if !ok {
+    return -2
+  }
+  if entry.ExpiresAt.IsZero() {
+    return -1
+  }
+  return entry.ExpiresAt.UnixMilli()
+}
+
+// trackExpiryLocked ghi nhận key vào tập volatile nếu entry có TTL (phải giữ khóa ghi).
+// Tập này không cần xóa ngay khi key bị xóa/PERSIST: vòng xóa chủ động tự dọn các key cũ.
+func (s *Store) trackExpiryLocked(key string, entry Entry) {
+  if !entry.ExpiresAt.IsZero() {
+    s.volatile[key] = struct{}{}
+  }
+}
+
+// activeExpireBatch lấy mẫu tối đa activeExpireSamples key có TTL và xóa các key đã hết hạn.
+// onExpire được gọi cho từng key bị xóa khi vẫn đang giữ khóa ghi, để lệnh DEL được ghi AOF
+// trước bất kỳ lệnh nào khác tạo lại key đó (onExpire không được chờ fsync).
+// Trả về số key đã lấy mẫu và số key đã xóa.
+func (s *Store) activeExpireBatch(onExpire func(key string)) (int, int) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  now := time.Now()
+  sampled, expired := 0, 0
+  // Thứ tự duyệt map của Go bắt đầu từ vị trí ngẫu nhiên nên có thể dùng để lấy mẫu
+  for key := range s.volatile {
+    if sampled >= activeExpireSamples {
+      break
+    }
+    sampled++
+
+    entry, ok := s.data[key]
+    switch {
+    case !ok || entry.ExpiresAt.IsZero():
+      delete(s.volatile, key)
+    case entry.isExpired(now):
+      delete(s.data, key)
+      delete(s.volatile, key)
+      expired++
+      if onExpire != nil {
+        onExpire(key)
+      }
+    }
+  }
+
+  s.expiredKeys.Add(int64(expired))
+  return sampled, expired
+}
+
+// ActiveExpireCycle chạy một vòng xóa chủ động: lấy mẫu theo từng lượt cho tới khi tỉ lệ key
+// hết hạn trong mẫu xuống dưới activeExpireStalePercent hoặc hết ngân sách thời gian budget.
+// Khóa chỉ được giữ trong từng lượt nên các lệnh khác không bị chặn cả vòng.
+// Trả về tổng số key đã xóa.
+func (s *Store) ActiveExpireCycle(budget time.Duration, onExpire func(key string)) int {
+  start := time.Now()
+  defer func() { s.expireCycleNs.Add(int64(time.Since(start))) }()
+
+  total := 0
+  for {
+    sampled, expired := s.activeExpireBatch(onExpire)
+    total += expired
+    if sampled == 0 || expired*100 <= sampled*activeExpireStalePercent {
+      return total
+    }
+    if time.Since(start) >= budget {
+      s.timeCapReached.Add(1)
+      return total
+    }
+  }
+}
+
+// StartActiveExpire chạy ActiveExpireCycle định kỳ trong một Goroutine riêng.
+// Trả về hàm stop để dừng Goroutine (chờ vòng đang chạy kết thúc).
+func (s *Store) StartActiveExpire(interval, budget time.Duration, onExpire func(key string)) func() {
+  done := make(chan struct{})
+  var wg sync.WaitGroup
+  wg.Add(1)
+
+  go func() {
+    defer wg.Done()
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+      select {
+      case <-done:
+        return
+      case <-ticker.C:
+        s.ActiveExpireCycle(budget, onExpire)
+      }
+    }
+  }()
+
+  var once sync.Once
+  return func() {
+    once.Do(func() { close(done) })
+    wg.Wait()
+  }
+}
+
+// ExpireStats trả về các bộ đếm hiện tại về việc xóa key hết hạn
+func (s *Store) ExpireStats() ExpireStats {
+  return ExpireStats{
+    ExpiredKeys:    s.expiredKeys.Load(),
+    TimeCapReached: s.timeCapReached.Load(),
+    CycleTime:      time.Duration(s.expireCycleNs.Load()),
+  }
+}
+
+// KeyspaceStats trả về số key còn hiệu lực và số key trong đó có TTL
+func (s *Store) KeyspaceStats() (int, int) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  now := time.Now()
+  keys, expires := 0, 0
+  for _, entry := range s.data {
+    if entry.isExpired(now) {
+      continue
+    }
+    keys++
+    if !entry.ExpiresAt.IsZero() {
+      expires++
+    }
+  }
+  return keys, expires
+}
+
+// StartLoading đánh dấu Store đang tải dữ liệu khi khởi động (tạm ngưng việc hết hạn key)
+func (s *Store) StartLoading() {
+  s.loading.Store(true)
+}
+
+// Loading cho biết Store có đang tải dữ liệu khi khởi động (phát lại AOF) hay không
+func (s *Store) Loading() bool {
+  return s.loading.Load()
+}
+
+// FinishLoading kết thúc quá trình tải dữ liệu và xóa các key có thời điểm hết hạn đã qua,
+// để key hết hạn trong lúc server dừng không bị "hồi sinh". Trả về số key đã xóa.
+func (s *Store) FinishLoading() int {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.loading.Store(false)
+  now := time.Now()
+  expired := 0
+  for key, entry := range s.data {
+    if entry.isExpired(now) {
+      delete(s.data, key)
+      delete(s.volatile, key)
+      expired++
+    }
+  }
+  s.expiredKeys.Add(int64(expired))
+  return expired
+}
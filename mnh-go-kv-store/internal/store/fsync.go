@@ -0,0 +1,169 @@
+package store
+
+import (
+  "fmt"
+  "strings"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/logging"
+)
+
+// FsyncPolicy quyết định khi nào dữ liệu AOF được fsync xuống đĩa (appendfsync của Redis)
+type FsyncPolicy int
+
+const (
+  FsyncEverySec FsyncPolicy = iota // fsync trong nền mỗi giây (mặc định), mất tối đa ~1 giây dữ liệu
+  FsyncAlways                      // fsync trước khi trả lời client, các lệnh đồng thời dùng chung một lần fsync
+  FsyncNo                          // Không fsync, để hệ điều hành tự quyết định
+)
+
+// fsyncInterval là chu kỳ fsync của chế độ everysec
+const fsyncInterval = time.Second
+
+// ParseFsyncPolicy đọc giá trị của appendfsync (always, everysec, no)
+func ParseFsyncPolicy(value string) (FsyncPolicy, error) {
+  switch strings.ToLower(value) {
+  case "always":
+    return FsyncAlways, nil
+  case "everysec":
+    return FsyncEverySec, nil
+  case "no":
+    return FsyncNo, nil
+  }
+  return FsyncEverySec, fmt.Errorf("invalid appendfsync policy %q (expected always, everysec or no)", value)
+}
+
+func (p FsyncPolicy) String() string {
+  switch p {
+  case FsyncAlways:
+    return "always"
+  case FsyncNo:
+    return "no"
+  }
+  return "everysec"
+}
+
+// SetFsyncPolicy thay đổi chế độ fsync khi đang chạy
+func (a *AOF) SetFsyncPolicy(policy FsyncPolicy) {
+  a.mu.Lock()
+  defer a.mu.Unlock()
+  a.fsync = policy
+}
+
+// FsyncPolicy trả về chế độ fsync hiện tại
+func (a *AOF) FsyncPolicy() FsyncPolicy {
+  a.mu.Lock()
+  defer a.mu.Unlock()
+  return a.fsync
+}
+
+// flushAndSync ghi buffer ra file rồi fsync. Trả về số thứ tự của lệnh cuối cùng đã bền vững.
+// syncMu đảm bảo file không bị thay thế (ghi lại AOF, tải lại) trong lúc fsync.
+func (a *AOF) flushAndSync() (uint64, error) {
+  a.syncMu.Lock()
+  defer a.syncMu.Unlock()
+
+  a.mu.Lock()
+  target := a.writeSeq
+  err := a.writer.Flush()
+  f := a.file
+  a.mu.Unlock()
+  if err != nil {
+    return 0, err
+  }
+
+  // fsync khi không giữ a.mu để các lệnh khác vẫn ghi tiếp vào buffer (gom cho lần fsync sau)
+  if err := f.Sync(); err != nil {
+    return 0, err
+  }
+  return target, nil
+}
+
+// syncUpTo chờ cho tới khi lệnh có số thứ tự seq đã được fsync (group commit).
+// Tại mỗi thời điểm chỉ một Goroutine (leader) thực hiện fsync cho tất cả lệnh đã ghi vào buffer,
+// các Goroutine khác chờ kết quả thay vì tự gọi fsync.
+func (a *AOF) syncUpTo(seq uint64) error {
+  a.mu.Lock()
+  defer a.mu.Unlock()
+
+  for a.syncedSeq < seq {
+    if a.syncing {
+      a.syncCond.Wait()
+      continue
+    }
+
+    a.syncing = true
+    a.mu.Unlock()
+    target, err := a.flushAndSync()
+    a.mu.Lock()
+    a.syncing = false
+    if err == nil {
+      a.syncedSeq = max(a.syncedSeq, target)
+    }
+    a.syncCond.Broadcast()
+    if err != nil {
+      a.lastSyncErr = err
+      return err
+    }
+  }
+  return nil
+}
+
+// Sync ghi buffer ra file và fsync mọi lệnh đã ghi (bất kể appendfsync), dùng khi dừng server
+func (a *AOF) Sync() error {
+  a.mu.Lock()
+  seq := a.writeSeq
+  a.mu.Unlock()
+  return a.syncUpTo(seq)
+}
+
+// syncLoop fsync định kỳ ở chế độ everysec (và các lệnh chưa được fsync ở chế độ always) cho tới
+// khi AOF được đóng
+func (a *AOF) syncLoop() {
+  defer close(a.syncStopped)
+  ticker := time.NewTicker(fsyncInterval)
+  defer ticker.Stop()
+
+  for {
+    select {
+    case <-a.syncDone:
+      return
+    case <-ticker.C:
+      switch a.FsyncPolicy() {
+      case FsyncNo:
+        continue
+      case FsyncAlways:
+        // Chỉ fsync các lệnh ghi bằng AppendCommandNoWait mà không ai chờ (ví dụ DEL của key hết hạn)
+        if !a.Stats().PendingFsync {
+          continue
+        }
+      }
+      target, err := a.flushAndSync()
+      a.mu.Lock()
+      if err != nil {
+        a.lastSyncErr = err
+        logging.Warningf("AOF fsync failed: %v", err)
+      } else {
+        a.syncedSeq = max(a.syncedSeq, target)
+      }
+      a.mu.Unlock()
+    }
+  }
+}
+
+// Close dừng fsync nền, ghi và fsync toàn bộ dữ liệu còn lại rồi đóng file AOF
+func (a *AOF) Close() error {
+  a.closeOnce.Do(func() { close(a.syncDone) })
+  <-a.syncStopped
+
+  _, err := a.flushAndSync()
+
+  a.syncMu.Lock()
+  defer a.syncMu.Unlock()
+  a.mu.Lock()
+  defer a.mu.Unlock()
+  if closeErr := a.file.Close(); err == nil {
+    err = closeErr
+  }
+  return err
+}
@@ -0,0 +1,93 @@
+package store
+
+// GlobMatch kiểm tra str có khớp với pattern kiểu glob của Redis hay không.
+// Hỗ trợ "*", "?", "[abc]", "[^abc]", "[a-z]" và "\" để thoát ký tự đặc biệt.
+func GlobMatch(pattern, str string) bool {
+  p, s := 0, 0
+  starP, starS := -1, 0
+
+  for s < len(str) {
+    if p < len(pattern) {
+      if pattern[p] == '*' {
+        // Ghi nhớ vị trí "*" để quay lui khi các ký tự sau không khớp
+        starP, starS = p, s
+        p++
+        continue
+      }
+      if n, ok := matchOne(pattern[p:], str[s]); ok {
+        p += n
+        s++
+        continue
+      }
+    }
+    if starP < 0 {
+      return false
+    }
+    // Cho "*" nuốt thêm một ký tự rồi thử lại
+    starS++
+    s = starS
+    p = starP + 1
+  }
+
+  for p < len(pattern) && pattern[p] == '*' {
+    p++
+  }
+  return p == len(pattern)
+}
+
+// matchOne so khớp một "token" ở đầu pattern (ký tự thường, "?", "\x" hoặc "[...]")
+// với ký tự c. Trả về độ dài của token và kết quả so khớp.
+func matchOne(pattern string, c byte) (int, bool) {
+  switch pattern[0] {
+  case '?':
+    return 1, true
+  case '\\':
+    if len(pattern) > 1 {
+      return 2, pattern[1] == c
+    }
+    return 1, c == '\\'
+  case '[':
+    return matchClass(pattern, c)
+  }
+  return 1, pattern[0] == c
+}
+
+// matchClass so khớp nhóm ký tự "[...]" (hỗ trợ phủ định "^" và khoảng "a-z")
+func matchClass(pattern string, c byte) (int, bool) {
+  i := 1
+  negate := i < len(pattern) && pattern[i] == '^'
+  if negate {
+    i++
+  }
+
+  matched := false
+  for i < len(pattern) && pattern[i] != ']' {
+    switch {
+    case pattern[i] == '\\' && i+1 < len(pattern):
+      if pattern[i+1] == c {
+        matched = true
+      }
+      i += 2
+    case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+      lo, hi := pattern[i], pattern[i+2]
+      if lo > hi {
+        lo, hi = hi, lo
+      }
+      if c >= lo && c <= hi {
+        matched = true
+      }
+      i += 3
+    default:
+      if pattern[i] == c {
+        matched = true
+      }
+      i++
+    }
+  }
+
+  // Bỏ qua dấu "]" đóng (nếu pattern thiếu "]" thì nhóm kéo dài tới hết pattern)
+  if i < len(pattern) {
+    i++
+  }
+  return i, matched != negate
+}
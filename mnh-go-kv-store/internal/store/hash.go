@@ -0,0 +1,343 @@
+package store
+
+import (
+  "errors"
+  "hash/fnv"
+  "math"
+  "sort"
+  "strconv"
+)
+
+var (
+  // ErrHashNotInteger được trả về khi HINCRBY gặp giá trị không phải số nguyên
+  ErrHashNotInteger = errors.New("ERR hash value is not an integer")
+  // ErrHashNotFloat được trả về khi HINCRBYFLOAT gặp giá trị không phải số thực
+  ErrHashNotFloat = errors.New("ERR hash value is not a float")
+  // ErrOverflow được trả về khi phép cộng số nguyên bị tràn số
+  ErrOverflow = errors.New("ERR increment or decrement would overflow")
+  // ErrNaNOrInf được trả về khi phép cộng số thực cho kết quả NaN hoặc vô cùng
+  ErrNaNOrInf = errors.New("ERR increment would produce NaN or Infinity")
+)
+
+// getHashWrite trả về Hash của key (phải giữ khóa ghi).
+// Nếu create = true và key chưa tồn tại thì tạo Hash mới.
+func (s *Store) getHashWrite(key string, create bool) (map[string]string, error) {
+  entry, ok := s.lookupWrite(key)
+  if !ok {
+    if !create {
+      return nil, nil
+    }
+    hash := make(map[string]string)
+    s.data[key] = Entry{Value: hash}
+    return hash, nil
+  }
+
+  hash, isHash := entry.Value.(map[string]string)
+  if !isHash {
+    // Lỗi: Key tồn tại nhưng không phải là Hash (ví dụ: là String)
+    return nil, ErrWrongType
+  }
+  return hash, nil
+}
+
+// getHashRead trả về Hash của key (chỉ cần khóa đọc), nil nếu key không tồn tại
+func (s *Store) getHashRead(key string) (map[string]string, error) {
+  entry, ok := s.lookupRead(key)
+  if !ok {
+    return nil, nil
+  }
+
+  hash, isHash := entry.Value.(map[string]string)
+  if !isHash {
+    return nil, ErrWrongType
+  }
+  return hash, nil
+}
+
+// HSET: Thiết lập giá trị cho một trường (field) trong Hash.
+// Trả về true nếu field là field mới.
+func (s *Store) HSET(key string, field string, value string) (bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  hash, err := s.getHashWrite(key, true)
+  if err != nil {
+    return false, err
+  }
+
+  _, exists := hash[field]
+  hash[field] = value
+  return !exists, nil
+}
+
+// HSETNX: Chỉ thiết lập field khi field chưa tồn tại
+func (s *Store) HSETNX(key string, field string, value string) (bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  hash, err := s.getHashWrite(key, true)
+  if err != nil {
+    return false, err
+  }
+
+  if _, exists := hash[field]; exists {
+    return false, nil
+  }
+  hash[field] = value
+  return true, nil
+}
+
+// HGET: Lấy giá trị của một trường (field) trong Hash
+func (s *Store) HGET(key string, field string) (string, bool, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  hash, err := s.getHashRead(key)
+  if err != nil {
+    return "", false, err
+  }
+
+  val, fieldFound := hash[field]
+  return val, fieldFound, nil
+}
+
+// HMGET: Lấy giá trị của nhiều field, found[i] = false nếu field thứ i không tồn tại
+func (s *Store) HMGET(key string, fields ...string) ([]string, []bool, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  hash, err := s.getHashRead(key)
+  if err != nil {
+    return nil, nil, err
+  }
+
+  values := make([]string, len(fields))
+  found := make([]bool, len(fields))
+  for i, field := range fields {
+    values[i], found[i] = hash[field]
+  }
+  return values, found, nil
+}
+
+// HGETALL: Lấy tất cả field-value trong Hash
+func (s *Store) HGETALL(key string) (map[string]string, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  hash, err := s.getHashRead(key)
+  if err != nil {
+    return nil, err
+  }
+
+  // Tạo copy để tránh race condition
+  result := make(map[string]string, len(hash))
+  for k, v := range hash {
+    result[k] = v
+  }
+  return result, nil
+}
+
+// HDEL: Xóa các field, trả về số field đã xóa. Hash rỗng sẽ bị xóa luôn.
+func (s *Store) HDEL(key string, fields ...string) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  hash, err := s.getHashWrite(key, false)
+  if err != nil || hash == nil {
+    return 0, err
+  }
+
+  removed := 0
+  for _, field := range fields {
+    if _, exists := hash[field]; exists {
+      delete(hash, field)
+      removed++
+    }
+  }
+
+  if len(hash) == 0 {
+    delete(s.data, key)
+  }
+  return removed, nil
+}
+
+// HEXISTS: Kiểm tra field có tồn tại trong Hash hay không
+func (s *Store) HEXISTS(key string, field string) (bool, error) {
+  _, found, err := s.HGET(key, field)
+  return found, err
+}
+
+// HLEN: Số field trong Hash
+func (s *Store) HLEN(key string) (int, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  hash, err := s.getHashRead(key)
+  if err != nil {
+    return 0, err
+  }
+  return len(hash), nil
+}
+
+// HSTRLEN: Độ dài giá trị của field (0 nếu không tồn tại)
+func (s *Store) HSTRLEN(key string, field string) (int, error) {
+  value, _, err := s.HGET(key, field)
+  return len(value), err
+}
+
+// HKEYS: Lấy tất cả field của Hash (đã sắp xếp)
+func (s *Store) HKEYS(key string) ([]string, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  hash, err := s.getHashRead(key)
+  if err != nil {
+    return nil, err
+  }
+
+  fields := make([]string, 0, len(hash))
+  for field := range hash {
+    fields = append(fields, field)
+  }
+  sort.Strings(fields)
+  return fields, nil
+}
+
+// HVALS: Lấy tất cả giá trị của Hash (theo thứ tự field giống HKEYS)
+func (s *Store) HVALS(key string) ([]string, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  hash, err := s.getHashRead(key)
+  if err != nil {
+    return nil, err
+  }
+
+  fields := make([]string, 0, len(hash))
+  for field := range hash {
+    fields = append(fields, field)
+  }
+  sort.Strings(fields)
+
+  values := make([]string, len(fields))
+  for i, field := range fields {
+    values[i] = hash[field]
+  }
+  return values, nil
+}
+
+// HINCRBY: Cộng increment vào field (coi như 0 nếu chưa tồn tại) một cách nguyên tử
+func (s *Store) HINCRBY(key string, field string, increment int64) (int64, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  hash, err := s.getHashWrite(key, true)
+  if err != nil {
+    return 0, err
+  }
+
+  var current int64
+  if raw, exists := hash[field]; exists {
+    current, err = strconv.ParseInt(raw, 10, 64)
+    if err != nil {
+      return 0, ErrHashNotInteger
+    }
+  }
+
+  if (increment > 0 && current > math.MaxInt64-increment) ||
+    (increment < 0 && current < math.MinInt64-increment) {
+    return 0, ErrOverflow
+  }
+
+  current += increment
+  hash[field] = strconv.FormatInt(current, 10)
+  return current, nil
+}
+
+// HINCRBYFLOAT: Cộng increment (số thực) vào field một cách nguyên tử.
+// Trả về giá trị mới đã được định dạng (cũng là chuỗi được lưu trong Hash).
+// onApplied có ý nghĩa giống như trong INCRBYFLOAT.
+func (s *Store) HINCRBYFLOAT(key string, field string, increment float64, onApplied func(value string)) (string, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  hash, err := s.getHashWrite(key, true)
+  if err != nil {
+    return "", err
+  }
+
+  var current float64
+  if raw, exists := hash[field]; exists {
+    current, err = strconv.ParseFloat(raw, 64)
+    if err != nil || math.IsNaN(current) || math.IsInf(current, 0) {
+      return "", ErrHashNotFloat
+    }
+  }
+
+  current += increment
+  if math.IsNaN(current) || math.IsInf(current, 0) {
+    if len(hash) == 0 {
+      delete(s.data, key)
+    }
+    return "", ErrNaNOrInf
+  }
+
+  formatted := strconv.FormatFloat(current, 'f', -1, 64)
+  hash[field] = formatted
+  if onApplied != nil {
+    onApplied(formatted)
+  }
+  return formatted, nil
+}
+
+// fieldHash tính vị trí của field trong không gian cursor của HSCAN
+func fieldHash(field string) uint64 {
+  h := fnv.New64a()
+  h.Write([]byte(field))
+  return h.Sum64()
+}
+
+// HSCAN: Duyệt Hash theo từng đợt. Cursor là giá trị hash của field kế tiếp cần trả về
+// (0 để bắt đầu), nên các field tồn tại suốt quá trình duyệt luôn được trả về ít nhất
+// một lần dù Hash bị thay đổi giữa các lần gọi. Trả về cursor 0 khi đã duyệt xong.
+func (s *Store) HSCAN(key string, cursor uint64, match string, count int) (uint64, []string, []string, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  hash, err := s.getHashRead(key)
+  if err != nil || len(hash) == 0 {
+    return 0, nil, nil, err
+  }
+
+  type hashedField struct {
+    pos   uint64
+    field string
+  }
+  candidates := make([]hashedField, 0, len(hash))
+  for field := range hash {
+    if pos := fieldHash(field); pos >= cursor {
+      candidates = append(candidates, hashedField{pos, field})
+    }
+  }
+  sort.Slice(candidates, func(i, j int) bool {
+    if candidates[i].pos != candidates[j].pos {
+      return candidates[i].pos < candidates[j].pos
+    }
+    return candidates[i].field < candidates[j].field
+  })
+
+  // COUNT do client gửi có thể rất lớn: dung lượng không vượt quá số field có thể trả về
+  fields := make([]string, 0, min(count, len(candidates)))
+  values := make([]string, 0, min(count, len(candidates)))
+  for i, c := range candidates {
+    // Chỉ dừng ở ranh giới giữa hai giá trị hash khác nhau để không bỏ sót field trùng hash
+    if i >= count && c.pos != candidates[i-1].pos {
+      return c.pos, fields, values, nil
+    }
+    if match == "" || GlobMatch(match, c.field) {
+      fields = append(fields, c.field)
+      values = append(values, hash[c.field])
+    }
+  }
+  return 0, fields, values, nil
+}
@@ -0,0 +1,394 @@
+package store
+
+import (
+  "container/list"
+  "errors"
+)
+
+var (
+  // ErrNoSuchKey được trả về khi lệnh yêu cầu key phải tồn tại (ví dụ LSET)
+  ErrNoSuchKey = errors.New("ERR no such key")
+  // ErrIndexOutOfRange được trả về khi chỉ số nằm ngoài phạm vi của List
+  ErrIndexOutOfRange = errors.New("ERR index out of range")
+)
+
+// getListWrite trả về List của key (phải giữ khóa ghi).
+// Nếu create = true và key chưa tồn tại thì tạo List mới.
+func (s *Store) getListWrite(key string, create bool) (*list.List, error) {
+  entry, ok := s.lookupWrite(key)
+  if !ok {
+    if !create {
+      return nil, nil
+    }
+    l := list.New()
+    s.data[key] = Entry{Value: l}
+    return l, nil
+  }
+
+  l, isList := entry.Value.(*list.List)
+  if !isList {
+    return nil, ErrWrongType
+  }
+  return l, nil
+}
+
+// getListRead trả về List của key (chỉ cần khóa đọc), nil nếu key không tồn tại
+func (s *Store) getListRead(key string) (*list.List, error) {
+  entry, ok := s.lookupRead(key)
+  if !ok {
+    return nil, nil
+  }
+
+  l, isList := entry.Value.(*list.List)
+  if !isList {
+    return nil, ErrWrongType
+  }
+  return l, nil
+}
+
+// removeIfEmpty xóa key khi List không còn phần tử nào (giống Redis)
+func (s *Store) removeIfEmpty(key string, l *list.List) {
+  if l.Len() == 0 {
+    delete(s.data, key)
+  }
+}
+
+// pushList thêm các phần tử vào đầu (front = true) hoặc cuối List.
+// Nếu onlyIfExists = true thì không tạo key mới (LPUSHX/RPUSHX).
+func (s *Store) pushList(key string, values []string, front bool, onlyIfExists bool) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  l, err := s.getListWrite(key, !onlyIfExists)
+  if err != nil {
+    return 0, err
+  }
+  if l == nil {
+    return 0, nil
+  }
+
+  for _, v := range values {
+    if front {
+      l.PushFront(v)
+    } else {
+      l.PushBack(v)
+    }
+  }
+  return l.Len(), nil
+}
+
+// LPUSH: Thêm các phần tử vào đầu List, trả về độ dài mới
+func (s *Store) LPUSH(key string, values ...string) (int, error) {
+  return s.pushList(key, values, true, false)
+}
+
+// RPUSH: Thêm các phần tử vào cuối List, trả về độ dài mới
+func (s *Store) RPUSH(key string, values ...string) (int, error) {
+  return s.pushList(key, values, false, false)
+}
+
+// LPUSHX: Giống LPUSH nhưng chỉ thực hiện khi key đã tồn tại
+func (s *Store) LPUSHX(key string, values ...string) (int, error) {
+  return s.pushList(key, values, true, true)
+}
+
+// RPUSHX: Giống RPUSH nhưng chỉ thực hiện khi key đã tồn tại
+func (s *Store) RPUSHX(key string, values ...string) (int, error) {
+  return s.pushList(key, values, false, true)
+}
+
+// popList lấy ra tối đa count phần tử từ đầu (front = true) hoặc cuối List
+func (s *Store) popList(key string, count int, front bool) ([]string, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  return s.popListLocked(key, count, front)
+}
+
+// popListLocked là phần lõi của popList, người gọi phải giữ khóa ghi s.mu
+func (s *Store) popListLocked(key string, count int, front bool) ([]string, error) {
+  l, err := s.getListWrite(key, false)
+  if err != nil || l == nil {
+    return nil, err
+  }
+
+  result := make([]string, 0, min(count, l.Len()))
+  for len(result) < count && l.Len() > 0 {
+    var e *list.Element
+    if front {
+      e = l.Front()
+    } else {
+      e = l.Back()
+    }
+    result = append(result, l.Remove(e).(string))
+  }
+
+  s.removeIfEmpty(key, l)
+  return result, nil
+}
+
+// LPOP: Lấy ra tối đa count phần tử từ đầu List (nil nếu key không tồn tại)
+func (s *Store) LPOP(key string, count int) ([]string, error) {
+  return s.popList(key, count, true)
+}
+
+// RPOP: Lấy ra tối đa count phần tử từ cuối List (nil nếu key không tồn tại)
+func (s *Store) RPOP(key string, count int) ([]string, error) {
+  return s.popList(key, count, false)
+}
+
+// LMOVE: Lấy một phần tử từ đầu/cuối List src và đẩy vào đầu/cuối List dst một cách nguyên tử.
+// ok = false nếu src không tồn tại.
+func (s *Store) LMOVE(src, dst string, srcFront, dstFront bool) (string, bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  return s.lmoveLocked(src, dst, srcFront, dstFront)
+}
+
+// lmoveLocked là phần lõi của LMOVE, người gọi phải giữ khóa ghi s.mu
+func (s *Store) lmoveLocked(src, dst string, srcFront, dstFront bool) (string, bool, error) {
+  srcList, err := s.getListWrite(src, false)
+  if err != nil || srcList == nil {
+    return "", false, err
+  }
+
+  // Kiểm tra kiểu của dst trước khi lấy phần tử ra khỏi src
+  if entry, ok := s.lookupWrite(dst); ok {
+    if _, isList := entry.Value.(*list.List); !isList {
+      return "", false, ErrWrongType
+    }
+  }
+
+  var e *list.Element
+  if srcFront {
+    e = srcList.Front()
+  } else {
+    e = srcList.Back()
+  }
+  value := srcList.Remove(e).(string)
+  s.removeIfEmpty(src, srcList)
+
+  dstList, _ := s.getListWrite(dst, true)
+  if dstFront {
+    dstList.PushFront(value)
+  } else {
+    dstList.PushBack(value)
+  }
+  return value, true, nil
+}
+
+// LLEN: Trả về độ dài của List (0 nếu key không tồn tại)
+func (s *Store) LLEN(key string) (int, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  l, err := s.getListRead(key)
+  if err != nil || l == nil {
+    return 0, err
+  }
+  return l.Len(), nil
+}
+
+// normalizeRange chuyển start/stop (có thể âm) thành khoảng [start, stop] hợp lệ.
+// Trả về ok = false nếu khoảng rỗng.
+func normalizeRange(start, stop, length int) (int, int, bool) {
+  if start < 0 {
+    start += length
+  }
+  if stop < 0 {
+    stop += length
+  }
+  if start < 0 {
+    start = 0
+  }
+  if stop >= length {
+    stop = length - 1
+  }
+  if start > stop || start >= length {
+    return 0, 0, false
+  }
+  return start, stop, true
+}
+
+// LRANGE: Lấy các phần tử trong khoảng [start, stop] (hỗ trợ chỉ số âm)
+func (s *Store) LRANGE(key string, start, stop int) ([]string, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  l, err := s.getListRead(key)
+  if err != nil || l == nil {
+    return []string{}, err
+  }
+
+  start, stop, ok := normalizeRange(start, stop, l.Len())
+  if !ok {
+    return []string{}, nil
+  }
+
+  result := make([]string, 0, stop-start+1)
+  i := 0
+  for e := l.Front(); e != nil && i <= stop; e = e.Next() {
+    if i >= start {
+      result = append(result, e.Value.(string))
+    }
+    i++
+  }
+  return result, nil
+}
+
+// elementAt tìm phần tử tại vị trí index (hỗ trợ chỉ số âm), duyệt từ đầu gần nhất
+func elementAt(l *list.List, index int) *list.Element {
+  if index < 0 {
+    index += l.Len()
+  }
+  if index < 0 || index >= l.Len() {
+    return nil
+  }
+
+  if index < l.Len()/2 {
+    e := l.Front()
+    for i := 0; i < index; i++ {
+      e = e.Next()
+    }
+    return e
+  }
+
+  e := l.Back()
+  for i := l.Len() - 1; i > index; i-- {
+    e = e.Prev()
+  }
+  return e
+}
+
+// LINDEX: Lấy phần tử tại vị trí index
+func (s *Store) LINDEX(key string, index int) (string, bool, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  l, err := s.getListRead(key)
+  if err != nil || l == nil {
+    return "", false, err
+  }
+
+  e := elementAt(l, index)
+  if e == nil {
+    return "", false, nil
+  }
+  return e.Value.(string), true, nil
+}
+
+// LSET: Ghi đè phần tử tại vị trí index
+func (s *Store) LSET(key string, index int, value string) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  l, err := s.getListWrite(key, false)
+  if err != nil {
+    return err
+  }
+  if l == nil {
+    return ErrNoSuchKey
+  }
+
+  e := elementAt(l, index)
+  if e == nil {
+    return ErrIndexOutOfRange
+  }
+  e.Value = value
+  return nil
+}
+
+// LTRIM: Chỉ giữ lại các phần tử trong khoảng [start, stop]
+func (s *Store) LTRIM(key string, start, stop int) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  l, err := s.getListWrite(key, false)
+  if err != nil || l == nil {
+    return err
+  }
+
+  start, stop, ok := normalizeRange(start, stop, l.Len())
+  if !ok {
+    delete(s.data, key)
+    return nil
+  }
+
+  // Xóa phần tử ở cuối trước để giữ nguyên chỉ số start
+  for l.Len() > stop+1 {
+    l.Remove(l.Back())
+  }
+  for i := 0; i < start; i++ {
+    l.Remove(l.Front())
+  }
+
+  s.removeIfEmpty(key, l)
+  return nil
+}
+
+// LINSERT: Chèn value trước (before = true) hoặc sau phần tử pivot đầu tiên.
+// Trả về độ dài mới, -1 nếu không tìm thấy pivot, 0 nếu key không tồn tại.
+func (s *Store) LINSERT(key string, before bool, pivot, value string) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  l, err := s.getListWrite(key, false)
+  if err != nil || l == nil {
+    return 0, err
+  }
+
+  for e := l.Front(); e != nil; e = e.Next() {
+    if e.Value.(string) != pivot {
+      continue
+    }
+    if before {
+      l.InsertBefore(value, e)
+    } else {
+      l.InsertAfter(value, e)
+    }
+    return l.Len(), nil
+  }
+  return -1, nil
+}
+
+// LREM: Xóa các phần tử bằng value.
+// count > 0: xóa từ đầu, count < 0: xóa từ cuối, count = 0: xóa tất cả.
+func (s *Store) LREM(key string, count int, value string) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  l, err := s.getListWrite(key, false)
+  if err != nil || l == nil {
+    return 0, err
+  }
+
+  limit := count
+  if limit < 0 {
+    limit = -limit
+  }
+
+  removed := 0
+  if count >= 0 {
+    for e := l.Front(); e != nil && (limit == 0 || removed < limit); {
+      next := e.Next()
+      if e.Value.(string) == value {
+        l.Remove(e)
+        removed++
+      }
+      e = next
+    }
+  } else {
+    for e := l.Back(); e != nil && removed < limit; {
+      prev := e.Prev()
+      if e.Value.(string) == value {
+        l.Remove(e)
+        removed++
+      }
+      e = prev
+    }
+  }
+
+  s.removeIfEmpty(key, l)
+  return removed, nil
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+)
+
+// Loại file trong manifest của AOF nhiều phần
+const (
+  AOFBaseFile = "b" // File nền: snapshot tại lần ghi lại gần nhất (RDB hoặc RESP)
+  AOFIncrFile = "i" // File tăng dần: các lệnh RESP ghi sau file nền
+)
+
+// AOFFileInfo là một dòng của manifest: "file <name> seq <seq> type <b|i>"
+type AOFFileInfo struct {
+  Name string
+  Seq  int
+  Type string
+}
+
+// Manifest liệt kê các file tạo nên AOF theo thứ tự phát lại: file nền (nếu có) rồi các file tăng dần
+type Manifest struct {
+  Base  *AOFFileInfo
+  Incrs []AOFFileInfo
+
+  baseSeq int // Số thứ tự lớn nhất đã dùng cho file nền
+  incrSeq int // Số thứ tự lớn nhất đã dùng cho file tăng dần
+}
+
+// ManifestName trả về tên file manifest của AOF có tên name
+func ManifestName(name string) string {
+  return name + ".manifest"
+}
+
+// Files trả về các file theo thứ tự phát lại
+func (m *Manifest) Files() []AOFFileInfo {
+  files := make([]AOFFileInfo, 0, len(m.Incrs)+1)
+  if m.Base != nil {
+    files = append(files, *m.Base)
+  }
+  return append(files, m.Incrs...)
+}
+
+// clone tạo bản sao của manifest để có thể sửa đổi rồi mới thay thế bản đang dùng
+func (m *Manifest) clone() *Manifest {
+  c := *m
+  if m.Base != nil {
+    base := *m.Base
+    c.Base = &base
+  }
+  c.Incrs = append([]AOFFileInfo(nil), m.Incrs...)
+  return &c
+}
+
+// nextIncr tạo file tăng dần mới (chưa thêm vào manifest)
+func (m *Manifest) nextIncr(name string) AOFFileInfo {
+  seq := m.incrSeq + 1
+  return AOFFileInfo{Name: fmt.Sprintf("%s.%d.incr.aof", name, seq), Seq: seq, Type: AOFIncrFile}
+}
+
+// nextBase tạo file nền mới (chưa thêm vào manifest); ext là "rdb" hoặc "aof"
+func (m *Manifest) nextBase(name, ext string) AOFFileInfo {
+  seq := m.baseSeq + 1
+  return AOFFileInfo{Name: fmt.Sprintf("%s.%d.base.%s", name, seq, ext), Seq: seq, Type: AOFBaseFile}
+}
+
+// add thêm một file vào manifest
+func (m *Manifest) add(info AOFFileInfo) {
+  if info.Type == AOFBaseFile {
+    m.Base = &info
+    m.baseSeq = max(m.baseSeq, info.Seq)
+    return
+  }
+  m.Incrs = append(m.Incrs, info)
+  m.incrSeq = max(m.incrSeq, info.Seq)
+}
+
+// Marshal mã hóa manifest thành văn bản, mỗi file một dòng
+func (m *Manifest) Marshal() []byte {
+  var b strings.Builder
+  for _, info := range m.Files() {
+    fmt.Fprintf(&b, "file %s seq %d type %s\n", info.Name, info.Seq, info.Type)
+  }
+  return []byte(b.String())
+}
+
+// ParseManifest đọc manifest từ r. Dòng trống và dòng bắt đầu bằng '#' được bỏ qua.
+func ParseManifest(r io.Reader) (*Manifest, error) {
+  m := &Manifest{}
+  scanner := bufio.NewScanner(r)
+  line := 0
+  for scanner.Scan() {
+    line++
+    text := strings.TrimSpace(scanner.Text())
+    if text == "" || strings.HasPrefix(text, "#") {
+      continue
+    }
+
+    fields := strings.Fields(text)
+    if len(fields)%2 != 0 {
+      return nil, fmt.Errorf("manifest line %d: expected key/value pairs", line)
+    }
+    var info AOFFileInfo
+    for i := 0; i < len(fields); i += 2 {
+      switch fields[i] {
+      case "file":
+        info.Name = fields[i+1]
+      case "seq":
+        seq, err := strconv.Atoi(fields[i+1])
+        if err != nil || seq <= 0 {
+          return nil, fmt.Errorf("manifest line %d: invalid seq %q", line, fields[i+1])
+        }
+        info.Seq = seq
+      case "type":
+        info.Type = fields[i+1]
+      }
+    }
+
+    // Tên file không được chứa đường dẫn để manifest không trỏ ra ngoài thư mục AOF
+    if info.Name == "" || info.Name != filepath.Base(info.Name) || info.Seq == 0 {
+      return nil, fmt.Errorf("manifest line %d: invalid file entry", line)
+    }
+    switch info.Type {
+    case AOFBaseFile:
+      if m.Base != nil {
+        return nil, fmt.Errorf("manifest line %d: more than one base file", line)
+      }
+    case AOFIncrFile:
+      if len(m.Incrs) > 0 && info.Seq <= m.incrSeq {
+        return nil, fmt.Errorf("manifest line %d: incr files out of order", line)
+      }
+    default:
+      return nil, fmt.Errorf("manifest line %d: unknown file type %q", line, info.Type)
+    }
+    m.add(info)
+  }
+  return m, scanner.Err()
+}
+
+// ReadManifest đọc file manifest tại path
+func ReadManifest(path string) (*Manifest, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+  return ParseManifest(f)
+}
+
+// writeManifest ghi manifest vào dir một cách nguyên tử: file tạm, fsync, rename rồi fsync thư mục
+func writeManifest(dir, name string, m *Manifest) error {
+  path := filepath.Join(dir, ManifestName(name))
+  tmpPath := filepath.Join(dir, "temp-"+ManifestName(name))
+  f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+  if err != nil {
+    return err
+  }
+  defer os.Remove(tmpPath)
+
+  if _, err := f.Write(m.Marshal()); err != nil {
+    f.Close()
+    return err
+  }
+  if err := f.Sync(); err != nil {
+    f.Close()
+    return err
+  }
+  if err := f.Close(); err != nil {
+    return err
+  }
+  if err := os.Rename(tmpPath, path); err != nil {
+    return err
+  }
+  return syncDir(dir)
+}
+
+// syncDir fsync thư mục để việc tạo/đổi tên file bền vững qua sự cố mất điện
+func syncDir(dir string) error {
+  d, err := os.Open(dir)
+  if err != nil {
+    return err
+  }
+  defer d.Close()
+  return d.Sync()
+}
+
+// loadManifest đọc manifest của AOF trong dir. Nếu chưa có manifest, tạo manifest mới;
+// file AOF đơn cũ (legacyPath) nếu tồn tại được chuyển vào dir làm file nền.
+func loadManifest(dir, name, legacyPath string) (*Manifest, error) {
+  m, err := ReadManifest(filepath.Join(dir, ManifestName(name)))
+  if err == nil || !errors.Is(err, os.ErrNotExist) {
+    return m, err
+  }
+
+  m = &Manifest{}
+  base := m.nextBase(name, "aof")
+  basePath := filepath.Join(dir, base.Name)
+  if info, err := os.Stat(legacyPath); err == nil && info.Mode().IsRegular() {
+    if err := os.Rename(legacyPath, basePath); err != nil {
+      return nil, fmt.Errorf("moving legacy AOF %s into %s: %v", legacyPath, dir, err)
+    }
+    m.add(base)
+  } else if _, err := os.Stat(basePath); err == nil {
+    // Lần nâng cấp trước đã chuyển file nhưng dừng trước khi kịp ghi manifest
+    m.add(base)
+  }
+  m.add(m.nextIncr(name))
+  if err := writeManifest(dir, name, m); err != nil {
+    return nil, err
+  }
+  return m, nil
+}
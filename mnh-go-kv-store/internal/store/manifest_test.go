@@ -0,0 +1,149 @@
+package store
+
+import (
+  "os"
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestParseManifest(t *testing.T) {
+  tests := []struct {
+    name string
+    text string
+    want []AOFFileInfo // nil: manifest lỗi
+  }{
+    {"empty", "", []AOFFileInfo{}},
+    {"base and incrs", "file a.1.base.rdb seq 1 type b\nfile a.1.incr.aof seq 1 type i\nfile a.2.incr.aof seq 2 type i\n",
+      []AOFFileInfo{{"a.1.base.rdb", 1, "b"}, {"a.1.incr.aof", 1, "i"}, {"a.2.incr.aof", 2, "i"}}},
+    {"comments and any key order", "# manifest\n\n  type i seq 3 file a.3.incr.aof  \n",
+      []AOFFileInfo{{"a.3.incr.aof", 3, "i"}}},
+    {"base listed after incrs", "file a.2.incr.aof seq 2 type i\nfile a.1.base.aof seq 1 type b\n",
+      []AOFFileInfo{{"a.1.base.aof", 1, "b"}, {"a.2.incr.aof", 2, "i"}}},
+    {"odd number of fields", "file a.1.incr.aof seq 1 type\n", nil},
+    {"invalid seq", "file a.1.incr.aof seq one type i\n", nil},
+    {"zero seq", "file a.1.incr.aof seq 0 type i\n", nil},
+    {"missing seq", "file a.1.incr.aof type i\n", nil},
+    {"missing name", "seq 1 type i\n", nil},
+    {"path outside the directory", "file ../a.1.incr.aof seq 1 type i\n", nil},
+    {"unknown type", "file a.1.incr.aof seq 1 type x\n", nil},
+    {"two base files", "file a.1.base.aof seq 1 type b\nfile a.2.base.aof seq 2 type b\n", nil},
+    {"incrs out of order", "file a.2.incr.aof seq 2 type i\nfile a.1.incr.aof seq 1 type i\n", nil},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      m, err := ParseManifest(strings.NewReader(tt.text))
+      if tt.want == nil {
+        if err == nil {
+          t.Fatalf("ParseManifest() accepted %q", tt.text)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatal(err)
+      }
+      if got := m.Files(); !reflect.DeepEqual(got, tt.want) {
+        t.Fatalf("Files() = %v, want %v", got, tt.want)
+      }
+
+      // Marshal rồi đọc lại cho cùng danh sách file
+      again, err := ParseManifest(strings.NewReader(string(m.Marshal())))
+      if err != nil || !reflect.DeepEqual(again.Files(), tt.want) {
+        t.Fatalf("reparsing %q = %v, %v", m.Marshal(), again, err)
+      }
+    })
+  }
+}
+
+func TestLoadManifestMovesLegacyAOF(t *testing.T) {
+  root := t.TempDir()
+  legacy := filepath.Join(root, "appendonly.aof")
+  data := testCommands([]string{"SET", "a", "1"})
+  if err := os.WriteFile(legacy, data, 0644); err != nil {
+    t.Fatal(err)
+  }
+
+  dir := filepath.Join(root, "appendonlydir")
+  if err := os.Mkdir(dir, 0755); err != nil {
+    t.Fatal(err)
+  }
+  m, err := loadManifest(dir, "appendonly.aof", legacy)
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := []AOFFileInfo{{"appendonly.aof.1.base.aof", 1, AOFBaseFile}, {"appendonly.aof.1.incr.aof", 1, AOFIncrFile}}
+  if !reflect.DeepEqual(m.Files(), want) {
+    t.Fatalf("Files() = %v, want %v", m.Files(), want)
+  }
+  if moved, err := os.ReadFile(filepath.Join(dir, want[0].Name)); err != nil || string(moved) != string(data) {
+    t.Fatalf("the legacy AOF was not moved into the base file: %q, %v", moved, err)
+  }
+  if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+    t.Errorf("the legacy AOF still exists (stat: %v)", err)
+  }
+
+  // Lần mở sau đọc manifest đã ghi thay vì tạo lại
+  again, err := loadManifest(dir, "appendonly.aof", legacy)
+  if err != nil || !reflect.DeepEqual(again.Files(), want) {
+    t.Fatalf("reloading the manifest = %v, %v", again, err)
+  }
+}
+
+func TestAOFRewriteRotatesFiles(t *testing.T) {
+  for _, preamble := range []bool{true, false} {
+    t.Run(map[bool]string{true: "rdb preamble", false: "resp"}[preamble], func(t *testing.T) {
+      dir := t.TempDir()
+      aof, err := NewAOF(dir, "appendonly.aof")
+      if err != nil {
+        t.Fatal(err)
+      }
+      aof.UseRDBPreamble = preamble
+      s := NewStore()
+      s.SET("a", "1", 0)
+      s.SET("b", "2", 0)
+      if err := aof.AppendCommand("SET", "a", "1"); err != nil {
+        t.Fatal(err)
+      }
+      aof.AppendCommand("SET", "b", "2")
+
+      snapshot, err := aof.BeginRewrite(s)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if _, err := aof.BeginRewrite(s); err != ErrRewriteInProgress {
+        t.Fatalf("second BeginRewrite() = %v, want ErrRewriteInProgress", err)
+      }
+      // Lệnh ghi trong lúc ghi lại nằm trong file tăng dần mới
+      aof.AppendCommand("SET", "c", "3")
+      if err := aof.FinishRewrite(snapshot); err != nil {
+        t.Fatal(err)
+      }
+      aof.Close()
+
+      ext := map[bool]string{true: "rdb", false: "aof"}[preamble]
+      want := []AOFFileInfo{{"appendonly.aof.1.base." + ext, 1, AOFBaseFile}, {"appendonly.aof.2.incr.aof", 2, AOFIncrFile}}
+      m, err := ReadManifest(filepath.Join(dir, ManifestName("appendonly.aof")))
+      if err != nil || !reflect.DeepEqual(m.Files(), want) {
+        t.Fatalf("manifest after the rewrite = %v, %v, want %v", m, err, want)
+      }
+      if _, err := os.Stat(filepath.Join(dir, "appendonly.aof.1.incr.aof")); !os.IsNotExist(err) {
+        t.Errorf("the obsolete incr file was not removed (stat: %v)", err)
+      }
+
+      reopened, err := NewAOF(dir, "appendonly.aof")
+      if err != nil {
+        t.Fatal(err)
+      }
+      defer reopened.Close()
+      var executor countingExecutor
+      if err := reopened.ReadAndLoad(&executor); err != nil {
+        t.Fatal(err)
+      }
+      // Snapshot: 2 key (dạng RDB) hoặc 2 lệnh (dạng RESP), cộng lệnh ghi trong lúc ghi lại
+      if got := executor.keys + executor.commands; got != 3 {
+        t.Errorf("replayed %d keys and %d commands, want 3 in total", executor.keys, executor.commands)
+      }
+    })
+  }
+}
@@ -0,0 +1,629 @@
+package store
+
+import (
+  "bufio"
+  "container/list"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "hash"
+  "hash/crc64"
+  "io"
+  "math"
+  "os"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+)
+
+// Định dạng snapshot nhị phân (RDB):
+//
+//   "MNHRDB" + phiên bản (4 chữ số)
+//   các bản ghi: [0xFC + thời điểm hết hạn (int64 Unix ms)] + kiểu (1 byte) + key + giá trị
+//   0xFF + CRC-64/ECMA (8 byte, little endian) của toàn bộ dữ liệu phía trước
+//
+// Độ dài và số phần tử được mã hóa bằng uvarint, chuỗi gồm độ dài + dữ liệu,
+// score của Sorted Set là float64 (8 byte, little endian).
+const (
+  rdbMagic   = "MNHRDB"
+  rdbVersion = "0001"
+
+  rdbOpExpireMs = 0xFC
+  rdbOpEOF      = 0xFF
+
+  rdbTypeString = 0
+  rdbTypeList   = 1
+  rdbTypeSet    = 2
+  rdbTypeZSet   = 3
+  rdbTypeHash   = 4
+)
+
+// DefaultSaveRules là các quy tắc save mặc định (giống Redis: "3600 1 300 100 60 10000")
+const DefaultSaveRules = "3600 1 300 100 60 10000"
+
+// saveRetryDelay là thời gian chờ trước khi thử lại lưu tự động sau một lần lưu thất bại
+const saveRetryDelay = 5 * time.Second
+
+var (
+  // ErrBgsaveInProgress được trả về khi đã có một lần lưu snapshot đang chạy
+  ErrBgsaveInProgress = errors.New("ERR Background save already in progress")
+  // ErrRDBChecksum được trả về khi checksum của file snapshot không khớp
+  ErrRDBChecksum = errors.New("RDB checksum mismatch")
+
+  crcTable = crc64.MakeTable(crc64.ECMA)
+)
+
+// SaveRule: lưu snapshot khi có ít nhất Changes thay đổi trong Seconds giây
+type SaveRule struct {
+  Seconds int
+  Changes int64
+}
+
+// ParseSaveRules đọc chuỗi quy tắc dạng "900 1 300 10" (chuỗi rỗng: tắt lưu tự động)
+func ParseSaveRules(spec string) ([]SaveRule, error) {
+  fields := strings.Fields(spec)
+  if len(fields)%2 != 0 {
+    return nil, fmt.Errorf("invalid save rules %q: expected pairs of <seconds> <changes>", spec)
+  }
+
+  rules := make([]SaveRule, 0, len(fields)/2)
+  for i := 0; i < len(fields); i += 2 {
+    seconds, err1 := strconv.Atoi(fields[i])
+    changes, err2 := strconv.ParseInt(fields[i+1], 10, 64)
+    if err1 != nil || err2 != nil || seconds <= 0 || changes <= 0 {
+      return nil, fmt.Errorf("invalid save rule %q %q", fields[i], fields[i+1])
+    }
+    rules = append(rules, SaveRule{Seconds: seconds, Changes: changes})
+  }
+  return rules, nil
+}
+
+// FormatSaveRules chuyển các quy tắc về dạng chuỗi "900 1 300 10"
+func FormatSaveRules(rules []SaveRule) string {
+  parts := make([]string, 0, len(rules)*2)
+  for _, rule := range rules {
+    parts = append(parts, strconv.Itoa(rule.Seconds), strconv.FormatInt(rule.Changes, 10))
+  }
+  return strings.Join(parts, " ")
+}
+
+// MarkDirty ghi nhận một thay đổi trên Store (bỏ qua khi đang tải dữ liệu)
+func (s *Store) MarkDirty() {
+  if !s.loading.Load() {
+    s.dirty.Add(1)
+  }
+}
+
+// Dirty trả về số thay đổi kể từ lần lưu snapshot gần nhất
+func (s *Store) Dirty() int64 {
+  return s.dirty.Load()
+}
+
+// Snapshot trả về bản sao sâu của toàn bộ key còn hiệu lực tại một thời điểm nhất quán,
+// cùng số thay đổi (dirty) tại thời điểm đó.
+// Việc sao chép giữ khóa đọc từ đầu đến cuối nên mọi lệnh ghi bị chặn trong thời gian này
+// (tỉ lệ với số key và kích thước giá trị); chỉ phần mã hóa và ghi file mới chạy song song với lệnh ghi.
+func (s *Store) Snapshot() (map[string]Entry, int64) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+  return s.snapshotLocked(), s.dirty.Load()
+}
+
+// Restore nạp các entry vào Store (ghi đè key trùng tên), dùng khi tải snapshot lúc khởi động
+func (s *Store) Restore(entries map[string]Entry) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  for key, entry := range entries {
+    s.data[key] = entry
+    s.trackExpiryLocked(key, entry)
+  }
+}
+
+// rdbWriter ghi dữ liệu snapshot đồng thời cập nhật checksum
+type rdbWriter struct {
+  w   *bufio.Writer
+  crc hash.Hash64
+  buf [binary.MaxVarintLen64]byte
+}
+
+func (rw *rdbWriter) write(p []byte) error {
+  rw.crc.Write(p)
+  _, err := rw.w.Write(p)
+  return err
+}
+
+func (rw *rdbWriter) writeByte(b byte) error {
+  return rw.write([]byte{b})
+}
+
+func (rw *rdbWriter) writeLen(n int) error {
+  return rw.write(rw.buf[:binary.PutUvarint(rw.buf[:], uint64(n))])
+}
+
+func (rw *rdbWriter) writeString(str string) error {
+  if err := rw.writeLen(len(str)); err != nil {
+    return err
+  }
+  return rw.write([]byte(str))
+}
+
+func (rw *rdbWriter) writeUint64(v uint64) error {
+  binary.LittleEndian.PutUint64(rw.buf[:8], v)
+  return rw.write(rw.buf[:8])
+}
+
+// writeEntry ghi một key cùng TTL và giá trị của nó
+func (rw *rdbWriter) writeEntry(key string, e Entry) error {
+  if !e.ExpiresAt.IsZero() {
+    if err := rw.writeByte(rdbOpExpireMs); err != nil {
+      return err
+    }
+    if err := rw.writeUint64(uint64(e.ExpiresAt.UnixMilli())); err != nil {
+      return err
+    }
+  }
+
+  var items []string
+  var typ byte
+  switch v := e.Value.(type) {
+  case string:
+    typ, items = rdbTypeString, []string{v}
+  case *list.List:
+    typ = rdbTypeList
+    for el := v.Front(); el != nil; el = el.Next() {
+      items = append(items, el.Value.(string))
+    }
+  case Set:
+    typ, items = rdbTypeSet, v.members()
+  case map[string]string:
+    typ = rdbTypeHash
+    for field, value := range v {
+      items = append(items, field, value)
+    }
+  case *ZSet:
+    return rw.writeZSet(key, v)
+  default:
+    return fmt.Errorf("cannot encode key %q of type %T", key, e.Value)
+  }
+
+  if err := rw.writeByte(typ); err != nil {
+    return err
+  }
+  if err := rw.writeString(key); err != nil {
+    return err
+  }
+  if typ == rdbTypeString {
+    return rw.writeString(items[0])
+  }
+
+  count := len(items)
+  if typ == rdbTypeHash {
+    count /= 2
+  }
+  if err := rw.writeLen(count); err != nil {
+    return err
+  }
+  for _, item := range items {
+    if err := rw.writeString(item); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// writeZSet ghi Sorted Set theo thứ tự của skiplist (member rồi score)
+func (rw *rdbWriter) writeZSet(key string, z *ZSet) error {
+  if err := rw.writeByte(rdbTypeZSet); err != nil {
+    return err
+  }
+  if err := rw.writeString(key); err != nil {
+    return err
+  }
+  if err := rw.writeLen(z.zsl.length); err != nil {
+    return err
+  }
+  for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+    if err := rw.writeString(x.member); err != nil {
+      return err
+    }
+    if err := rw.writeUint64(math.Float64bits(x.score)); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// WriteRDB mã hóa snapshot theo định dạng RDB và ghi vào w
+func WriteRDB(w io.Writer, snapshot map[string]Entry) error {
+  rw := &rdbWriter{w: bufio.NewWriter(w), crc: crc64.New(crcTable)}
+
+  if err := rw.write([]byte(rdbMagic + rdbVersion)); err != nil {
+    return err
+  }
+  for key, entry := range snapshot {
+    if err := rw.writeEntry(key, entry); err != nil {
+      return err
+    }
+  }
+  if err := rw.writeByte(rdbOpEOF); err != nil {
+    return err
+  }
+
+  // Checksum không tự tính chính nó nên ghi thẳng ra writer
+  binary.LittleEndian.PutUint64(rw.buf[:8], rw.crc.Sum64())
+  if _, err := rw.w.Write(rw.buf[:8]); err != nil {
+    return err
+  }
+  return rw.w.Flush()
+}
+
+// rdbReader đọc dữ liệu snapshot đồng thời cập nhật checksum
+type rdbReader struct {
+  r         *bufio.Reader
+  crc       hash.Hash64
+  remaining int64 // Số byte còn lại có thể đọc: độ dài đọc từ dữ liệu không được vượt quá giá trị này
+}
+
+func (rr *rdbReader) ReadByte() (byte, error) {
+  if rr.remaining <= 0 {
+    return 0, io.ErrUnexpectedEOF
+  }
+  b, err := rr.r.ReadByte()
+  if err == nil {
+    rr.crc.Write([]byte{b})
+    rr.remaining--
+  }
+  return b, err
+}
+
+func (rr *rdbReader) readFull(n int) ([]byte, error) {
+  if int64(n) > rr.remaining {
+    return nil, io.ErrUnexpectedEOF
+  }
+  buf := make([]byte, n)
+  if _, err := io.ReadFull(rr.r, buf); err != nil {
+    return nil, err
+  }
+  rr.crc.Write(buf)
+  rr.remaining -= int64(n)
+  return buf, nil
+}
+
+func (rr *rdbReader) readLen() (int, error) {
+  n, err := binary.ReadUvarint(rr)
+  if err != nil {
+    return 0, err
+  }
+  // Mỗi byte (hoặc phần tử, tốn ít nhất một byte) phải còn nằm trong dữ liệu: độ dài hỏng
+  // không được dẫn tới việc cấp phát vượt quá kích thước snapshot
+  if n > math.MaxInt32 || n > uint64(rr.remaining) {
+    return 0, fmt.Errorf("invalid length %d (%d bytes left)", n, rr.remaining)
+  }
+  return int(n), nil
+}
+
+func (rr *rdbReader) readString() (string, error) {
+  n, err := rr.readLen()
+  if err != nil {
+    return "", err
+  }
+  buf, err := rr.readFull(n)
+  return string(buf), err
+}
+
+func (rr *rdbReader) readUint64() (uint64, error) {
+  buf, err := rr.readFull(8)
+  if err != nil {
+    return 0, err
+  }
+  return binary.LittleEndian.Uint64(buf), nil
+}
+
+// readValue đọc giá trị có kiểu typ
+func (rr *rdbReader) readValue(typ byte) (interface{}, error) {
+  if typ == rdbTypeString {
+    return rr.readString()
+  }
+
+  count, err := rr.readLen()
+  if err != nil {
+    return nil, err
+  }
+
+  switch typ {
+  case rdbTypeList:
+    l := list.New()
+    for i := 0; i < count; i++ {
+      item, err := rr.readString()
+      if err != nil {
+        return nil, err
+      }
+      l.PushBack(item)
+    }
+    return l, nil
+  case rdbTypeSet:
+    set := make(Set, count)
+    for i := 0; i < count; i++ {
+      member, err := rr.readString()
+      if err != nil {
+        return nil, err
+      }
+      set[member] = struct{}{}
+    }
+    return set, nil
+  case rdbTypeHash:
+    hash := make(map[string]string, count)
+    for i := 0; i < count; i++ {
+      field, err := rr.readString()
+      if err != nil {
+        return nil, err
+      }
+      value, err := rr.readString()
+      if err != nil {
+        return nil, err
+      }
+      hash[field] = value
+    }
+    return hash, nil
+  case rdbTypeZSet:
+    z := newZSet()
+    for i := 0; i < count; i++ {
+      member, err := rr.readString()
+      if err != nil {
+        return nil, err
+      }
+      bits, err := rr.readUint64()
+      if err != nil {
+        return nil, err
+      }
+      if _, dup := z.dict[member]; dup {
+        return nil, fmt.Errorf("duplicate sorted set member %q", member)
+      }
+      score := math.Float64frombits(bits)
+      z.dict[member] = score
+      z.zsl.insert(score, member)
+    }
+    return z, nil
+  }
+  return nil, fmt.Errorf("unknown value type %d", typ)
+}
+
+// hasRDBPreamble cho biết dữ liệu tiếp theo của r có bắt đầu bằng header RDB hay không (không tiêu thụ dữ liệu)
+func hasRDBPreamble(r *bufio.Reader) bool {
+  header, err := r.Peek(len(rdbMagic))
+  return err == nil && string(header) == rdbMagic
+}
+
+// ReadRDB giải mã một snapshot RDB từ r và kiểm tra checksum. size là số byte tối đa của snapshot
+// (ví dụ kích thước file): độ dài đọc từ dữ liệu lớn hơn phần còn lại là lỗi, không cấp phát.
+// Việc đọc dừng ngay sau checksum, nhờ đó có thể đọc tiếp phần dữ liệu phía sau snapshot
+// trên cùng r (ví dụ phần đuôi RESP của AOF).
+func ReadRDB(r *bufio.Reader, size int64) (map[string]Entry, error) {
+  rr := &rdbReader{r: r, crc: crc64.New(crcTable), remaining: size}
+
+  header, err := rr.readFull(len(rdbMagic) + len(rdbVersion))
+  if err != nil {
+    return nil, fmt.Errorf("reading RDB header: %w", err)
+  }
+  if string(header[:len(rdbMagic)]) != rdbMagic {
+    return nil, errors.New("not an RDB file (bad magic)")
+  }
+  if version := string(header[len(rdbMagic):]); version != rdbVersion {
+    return nil, fmt.Errorf("unsupported RDB version %s", version)
+  }
+
+  entries := make(map[string]Entry)
+  var expiresAt time.Time
+  for {
+    op, err := rr.ReadByte()
+    if err != nil {
+      return nil, fmt.Errorf("reading RDB record: %w", err)
+    }
+
+    switch op {
+    case rdbOpEOF:
+      expected := rr.crc.Sum64()
+      var sum [8]byte
+      if _, err := io.ReadFull(rr.r, sum[:]); err != nil {
+        return nil, fmt.Errorf("reading RDB checksum: %w", err)
+      }
+      if binary.LittleEndian.Uint64(sum[:]) != expected {
+        return nil, ErrRDBChecksum
+      }
+      return entries, nil
+    case rdbOpExpireMs:
+      ms, err := rr.readUint64()
+      if err != nil {
+        return nil, err
+      }
+      expiresAt = time.UnixMilli(int64(ms))
+    default:
+      key, err := rr.readString()
+      if err != nil {
+        return nil, err
+      }
+      value, err := rr.readValue(op)
+      if err != nil {
+        return nil, fmt.Errorf("reading key %q: %w", key, err)
+      }
+      entries[key] = Entry{Value: value, ExpiresAt: expiresAt}
+      expiresAt = time.Time{}
+    }
+  }
+}
+
+// RDB quản lý file snapshot, việc lưu nền (BGSAVE) và các quy tắc lưu tự động
+type RDB struct {
+  path string
+
+  mu       sync.Mutex
+  saving   bool      // Đang có một lần lưu snapshot
+  lastSave time.Time // Thời điểm lưu thành công gần nhất (hoặc lúc khởi động)
+  lastTry  time.Time // Thời điểm bắt đầu lần lưu gần nhất
+  lastErr  error     // Lỗi của lần lưu gần nhất
+
+  SaveRules []SaveRule
+}
+
+// RDBStats là trạng thái của RDB dùng cho giám sát (INFO)
+type RDBStats struct {
+  Changes          int64
+  BgsaveInProgress bool
+  LastSave         time.Time
+  LastErr          error
+}
+
+// NewRDB tạo bộ quản lý snapshot lưu tại path
+func NewRDB(path string) *RDB {
+  return &RDB{path: path, lastSave: time.Now()}
+}
+
+// Path trả về đường dẫn file snapshot
+func (r *RDB) Path() string {
+  return r.path
+}
+
+// writeFile ghi snapshot ra file tạm, fsync rồi đổi tên thành file snapshot một cách nguyên tử
+func (r *RDB) writeFile(snapshot map[string]Entry) error {
+  tmpPath := fmt.Sprintf("%s.tmp-%d", r.path, os.Getpid())
+  f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+  if err != nil {
+    return err
+  }
+  defer os.Remove(tmpPath)
+  defer f.Close()
+
+  if err := WriteRDB(f, snapshot); err != nil {
+    return err
+  }
+  if err := f.Sync(); err != nil {
+    return err
+  }
+  return os.Rename(tmpPath, r.path)
+}
+
+// begin đánh dấu bắt đầu một lần lưu, trả về lỗi nếu đã có lần lưu khác đang chạy
+func (r *RDB) begin() error {
+  r.mu.Lock()
+  defer r.mu.Unlock()
+
+  if r.saving {
+    return ErrBgsaveInProgress
+  }
+  r.saving = true
+  r.lastTry = time.Now()
+  return nil
+}
+
+// finish ghi nhận kết quả lưu; khi thành công, trừ đi số thay đổi đã nằm trong snapshot
+func (r *RDB) finish(s *Store, dirty int64, err error) {
+  r.mu.Lock()
+  defer r.mu.Unlock()
+
+  r.saving = false
+  r.lastErr = err
+  if err == nil {
+    r.lastSave = time.Now()
+    s.dirty.Add(-dirty)
+  }
+}
+
+// Save lưu snapshot của Store và chờ cho tới khi ghi xong (SAVE)
+func (r *RDB) Save(s *Store) error {
+  if err := r.begin(); err != nil {
+    return err
+  }
+  snapshot, dirty := s.Snapshot()
+  err := r.writeFile(snapshot)
+  r.finish(s, dirty, err)
+  return err
+}
+
+// BackgroundSave lấy snapshot của Store ngay lập tức rồi ghi ra file trong Goroutine riêng (BGSAVE).
+// done (nếu khác nil) được gọi với kết quả khi ghi xong.
+func (r *RDB) BackgroundSave(s *Store, done func(keys int, err error)) error {
+  if err := r.begin(); err != nil {
+    return err
+  }
+  snapshot, dirty := s.Snapshot()
+
+  go func() {
+    err := r.writeFile(snapshot)
+    r.finish(s, dirty, err)
+    if done != nil {
+      done(len(snapshot), err)
+    }
+  }()
+  return nil
+}
+
+// Load đọc file snapshot (nếu có) vào Store, trả về số key đã nạp
+func (r *RDB) Load(s *Store) (int, error) {
+  f, err := os.Open(r.path)
+  if err != nil {
+    if errors.Is(err, os.ErrNotExist) {
+      return 0, nil
+    }
+    return 0, err
+  }
+  defer f.Close()
+
+  info, err := f.Stat()
+  if err != nil {
+    return 0, err
+  }
+  entries, err := ReadRDB(bufio.NewReader(f), info.Size())
+  if err != nil {
+    return 0, err
+  }
+  s.Restore(entries)
+  return len(entries), nil
+}
+
+// SetSaveRules thay đổi các quy tắc lưu tự động khi đang chạy
+func (r *RDB) SetSaveRules(rules []SaveRule) {
+  r.mu.Lock()
+  defer r.mu.Unlock()
+  r.SaveRules = rules
+}
+
+// HasSaveRules cho biết có quy tắc lưu tự động nào đang được cấu hình hay không
+func (r *RDB) HasSaveRules() bool {
+  r.mu.Lock()
+  defer r.mu.Unlock()
+  return len(r.SaveRules) > 0
+}
+
+// ShouldSave kiểm tra các quy tắc save: có quy tắc nào đã đủ số thay đổi và đủ thời gian chưa.
+// Sau một lần lưu thất bại, chờ saveRetryDelay trước khi thử lại.
+func (r *RDB) ShouldSave(s *Store) bool {
+  r.mu.Lock()
+  defer r.mu.Unlock()
+
+  if r.saving || (r.lastErr != nil && time.Since(r.lastTry) < saveRetryDelay) {
+    return false
+  }
+  elapsed := time.Since(r.lastSave)
+  changes := s.Dirty()
+  for _, rule := range r.SaveRules {
+    if changes >= rule.Changes && elapsed >= time.Duration(rule.Seconds)*time.Second {
+      return true
+    }
+  }
+  return false
+}
+
+// Stats trả về trạng thái hiện tại của RDB
+func (r *RDB) Stats(s *Store) RDBStats {
+  r.mu.Lock()
+  defer r.mu.Unlock()
+
+  return RDBStats{
+    Changes:          s.Dirty(),
+    BgsaveInProgress: r.saving,
+    LastSave:         r.lastSave,
+    LastErr:          r.lastErr,
+  }
+}
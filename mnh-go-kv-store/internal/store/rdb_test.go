@@ -0,0 +1,102 @@
+package store
+
+import (
+  "bufio"
+  "bytes"
+  "encoding/binary"
+  "testing"
+  "time"
+)
+
+// testSnapshot mã hóa một snapshot có đủ các kiểu giá trị
+func testSnapshot(t testing.TB) []byte {
+  t.Helper()
+  s := NewStore()
+  s.SET("string", "value", 0)
+  s.SET("volatile", "value", time.Hour)
+  s.LPUSH("list", "a", "b", "c")
+  s.SADD("set", "x", "y")
+  s.HSET("hash", "field", "value")
+  s.ZADD("zset", ZAddOptions{}, []ScoredMember{{Member: "m1", Score: 1.5}, {Member: "m2", Score: -2}}, nil)
+
+  snapshot, _ := s.Snapshot()
+  var buf bytes.Buffer
+  if err := WriteRDB(&buf, snapshot); err != nil {
+    t.Fatal(err)
+  }
+  return buf.Bytes()
+}
+
+func readRDBBytes(data []byte) (map[string]Entry, error) {
+  return ReadRDB(bufio.NewReader(bytes.NewReader(data)), int64(len(data)))
+}
+
+func TestReadRDBRoundTrip(t *testing.T) {
+  entries, err := readRDBBytes(testSnapshot(t))
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(entries) != 6 {
+    t.Fatalf("read %d keys, want 6", len(entries))
+  }
+  if entries["volatile"].ExpiresAt.IsZero() || !entries["string"].ExpiresAt.IsZero() {
+    t.Error("expiry times were not restored")
+  }
+}
+
+func TestReadRDBStopsAfterChecksum(t *testing.T) {
+  data := testSnapshot(t)
+  r := bufio.NewReader(bytes.NewReader(append(data, "*1\r\n$4\r\nPING\r\n"...)))
+  if _, err := ReadRDB(r, int64(len(data))+14); err != nil {
+    t.Fatal(err)
+  }
+  rest, _ := r.ReadString('\n')
+  if rest != "*1\r\n" {
+    t.Fatalf("the data after the snapshot starts with %q", rest)
+  }
+}
+
+func TestReadRDBRejectsCorruptLengths(t *testing.T) {
+  header := []byte(rdbMagic + rdbVersion)
+  withLen := func(typ byte, n uint64) []byte {
+    data := append(bytes.Clone(header), typ)
+    data = binary.AppendUvarint(data, 3)
+    data = append(data, "key"...)
+    return binary.AppendUvarint(data, n)
+  }
+
+  tests := map[string][]byte{
+    "huge string":     withLen(rdbTypeString, 1<<40),
+    "string past end": withLen(rdbTypeString, 1000),
+    "huge list":       withLen(rdbTypeList, 1<<31-1),
+    "huge set":        withLen(rdbTypeSet, 1<<30),
+    "huge hash":       withLen(rdbTypeHash, 1<<30),
+    "huge zset":       withLen(rdbTypeZSet, 1<<30),
+    "huge key":        binary.AppendUvarint(append(bytes.Clone(header), rdbTypeString), 1<<62),
+  }
+  for name, data := range tests {
+    t.Run(name, func(t *testing.T) {
+      if _, err := readRDBBytes(data); err == nil {
+        t.Fatal("ReadRDB accepted a corrupt length")
+      }
+    })
+  }
+}
+
+func TestReadRDBTruncated(t *testing.T) {
+  data := testSnapshot(t)
+  for n := 0; n < len(data); n++ {
+    if _, err := readRDBBytes(data[:n]); err == nil {
+      t.Fatalf("ReadRDB accepted a snapshot truncated to %d of %d bytes", n, len(data))
+    }
+  }
+}
+
+func FuzzReadRDB(f *testing.F) {
+  f.Add(testSnapshot(f))
+  f.Add([]byte(rdbMagic + rdbVersion))
+  f.Fuzz(func(t *testing.T, data []byte) {
+    // Dữ liệu bất kỳ chỉ được trả về lỗi, không panic hay cấp phát quá kích thước dữ liệu
+    readRDBBytes(data)
+  })
+}
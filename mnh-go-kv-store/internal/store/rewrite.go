@@ -0,0 +1,139 @@
+package store
+
+import (
+  "bufio"
+  "container/list"
+  "io"
+  "sort"
+  "strconv"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// rewriteItemsPerCmd là số phần tử tối đa trong một lệnh khi ghi lại AOF
+// (giống AOF_REWRITE_ITEMS_PER_CMD của Redis), tránh tạo ra các lệnh quá lớn
+const rewriteItemsPerCmd = 64
+
+// clone tạo bản sao sâu của entry để có thể đọc mà không cần giữ khóa
+func (e Entry) clone() Entry {
+  switch v := e.Value.(type) {
+  case map[string]string:
+    hash := make(map[string]string, len(v))
+    for field, value := range v {
+      hash[field] = value
+    }
+    e.Value = hash
+  case *list.List:
+    l := list.New()
+    for el := v.Front(); el != nil; el = el.Next() {
+      l.PushBack(el.Value)
+    }
+    e.Value = l
+  case Set:
+    set := make(Set, len(v))
+    for m := range v {
+      set[m] = struct{}{}
+    }
+    e.Value = set
+  case *ZSet:
+    z := newZSet()
+    for x := v.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+      z.dict[x.member] = x.score
+      z.zsl.insert(x.score, x.member)
+    }
+    e.Value = z
+  }
+  return e
+}
+
+// snapshotLocked trả về bản sao sâu của toàn bộ key còn hiệu lực (phải giữ khóa đọc hoặc ghi)
+func (s *Store) snapshotLocked() map[string]Entry {
+  now := time.Now()
+  snapshot := make(map[string]Entry, len(s.data))
+  for key, entry := range s.data {
+    if entry.isExpired(now) {
+      continue
+    }
+    snapshot[key] = entry.clone()
+  }
+  return snapshot
+}
+
+// batchCommands chia items thành nhiều lệnh "cmd key items..." với tối đa rewriteItemsPerCmd phần tử mỗi lệnh.
+// width là số chuỗi của một phần tử (ví dụ 2 với cặp field/value).
+func batchCommands(cmd, key string, items []string, width int) [][]string {
+  var commands [][]string
+  step := rewriteItemsPerCmd * width
+  for start := 0; start < len(items); start += step {
+    end := min(start+step, len(items))
+    command := append([]string{cmd, key}, items[start:end]...)
+    commands = append(commands, command)
+  }
+  return commands
+}
+
+// rewriteCommands trả về các lệnh tối thiểu để tạo lại key với giá trị và TTL của entry
+func rewriteCommands(key string, e Entry) [][]string {
+  var commands [][]string
+  switch v := e.Value.(type) {
+  case string:
+    command := []string{"SET", key, v}
+    if !e.ExpiresAt.IsZero() {
+      command = append(command, "PXAT", strconv.FormatInt(e.ExpiresAt.UnixMilli(), 10))
+    }
+    // TTL của String đã nằm trong lệnh SET
+    return [][]string{command}
+  case map[string]string:
+    fields := make([]string, 0, len(v))
+    for field := range v {
+      fields = append(fields, field)
+    }
+    sort.Strings(fields)
+    items := make([]string, 0, len(v)*2)
+    for _, field := range fields {
+      items = append(items, field, v[field])
+    }
+    commands = batchCommands("HSET", key, items, 2)
+  case *list.List:
+    items := make([]string, 0, v.Len())
+    for el := v.Front(); el != nil; el = el.Next() {
+      items = append(items, el.Value.(string))
+    }
+    commands = batchCommands("RPUSH", key, items, 1)
+  case Set:
+    commands = batchCommands("SADD", key, v.members(), 1)
+  case *ZSet:
+    items := make([]string, 0, len(v.dict)*2)
+    for x := v.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+      items = append(items, strconv.FormatFloat(x.score, 'g', -1, 64), x.member)
+    }
+    commands = batchCommands("ZADD", key, items, 2)
+  }
+
+  if !e.ExpiresAt.IsZero() {
+    commands = append(commands, []string{"PEXPIREAT", key, strconv.FormatInt(e.ExpiresAt.UnixMilli(), 10)})
+  }
+  return commands
+}
+
+// WriteAOFSnapshot ghi snapshot dưới dạng chuỗi lệnh RESP tối thiểu (theo thứ tự key) vào w
+func WriteAOFSnapshot(w io.Writer, snapshot map[string]Entry) error {
+  keys := make([]string, 0, len(snapshot))
+  for key := range snapshot {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+
+  bw := bufio.NewWriter(w)
+  var buf []byte
+  for _, key := range keys {
+    for _, command := range rewriteCommands(key, snapshot[key]) {
+      buf = protocol.AppendCommand(buf[:0], command)
+      if _, err := bw.Write(buf); err != nil {
+        return err
+      }
+    }
+  }
+  return bw.Flush()
+}
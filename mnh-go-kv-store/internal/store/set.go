@@ -0,0 +1,326 @@
+package store
+
+import (
+  "errors"
+  "math/rand"
+  "sort"
+)
+
+// MaxRandomMembers là số phần tử lớn nhất SRANDMEMBER trả về với count âm (có lặp lại): mỗi phần tử
+// được cấp phát trước, nên count do client gửi phải có giới hạn
+const MaxRandomMembers = 1 << 20
+
+// ErrRandomCountRange được trả về khi count âm của SRANDMEMBER vượt quá MaxRandomMembers
+var ErrRandomCountRange = errors.New("ERR value is out of range")
+
+// Set lưu các phần tử duy nhất, không có thứ tự
+type Set map[string]struct{}
+
+// getSetWrite trả về Set của key (phải giữ khóa ghi).
+// Nếu create = true và key chưa tồn tại thì tạo Set mới.
+func (s *Store) getSetWrite(key string, create bool) (Set, error) {
+  entry, ok := s.lookupWrite(key)
+  if !ok {
+    if !create {
+      return nil, nil
+    }
+    set := make(Set)
+    s.data[key] = Entry{Value: set}
+    return set, nil
+  }
+
+  set, isSet := entry.Value.(Set)
+  if !isSet {
+    return nil, ErrWrongType
+  }
+  return set, nil
+}
+
+// getSetRead trả về Set của key (chỉ cần khóa đọc), nil nếu key không tồn tại
+func (s *Store) getSetRead(key string) (Set, error) {
+  entry, ok := s.lookupRead(key)
+  if !ok {
+    return nil, nil
+  }
+
+  set, isSet := entry.Value.(Set)
+  if !isSet {
+    return nil, ErrWrongType
+  }
+  return set, nil
+}
+
+// members trả về các phần tử của Set dưới dạng slice (đã sắp xếp để kết quả ổn định)
+func (set Set) members() []string {
+  result := make([]string, 0, len(set))
+  for m := range set {
+    result = append(result, m)
+  }
+  sort.Strings(result)
+  return result
+}
+
+// SADD: Thêm các phần tử vào Set, trả về số phần tử mới được thêm
+func (s *Store) SADD(key string, members ...string) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  set, err := s.getSetWrite(key, true)
+  if err != nil {
+    return 0, err
+  }
+
+  added := 0
+  for _, m := range members {
+    if _, exists := set[m]; !exists {
+      set[m] = struct{}{}
+      added++
+    }
+  }
+  return added, nil
+}
+
+// SREM: Xóa các phần tử khỏi Set, trả về số phần tử đã xóa
+func (s *Store) SREM(key string, members ...string) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  set, err := s.getSetWrite(key, false)
+  if err != nil || set == nil {
+    return 0, err
+  }
+
+  removed := 0
+  for _, m := range members {
+    if _, exists := set[m]; exists {
+      delete(set, m)
+      removed++
+    }
+  }
+
+  // Xóa key khi Set rỗng
+  if len(set) == 0 {
+    delete(s.data, key)
+  }
+  return removed, nil
+}
+
+// SMEMBERS: Lấy tất cả phần tử của Set
+func (s *Store) SMEMBERS(key string) ([]string, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  set, err := s.getSetRead(key)
+  if err != nil {
+    return nil, err
+  }
+  return set.members(), nil
+}
+
+// SISMEMBER: Kiểm tra phần tử có thuộc Set hay không
+func (s *Store) SISMEMBER(key string, member string) (bool, error) {
+  results, err := s.SMISMEMBER(key, member)
+  if err != nil {
+    return false, err
+  }
+  return results[0], nil
+}
+
+// SMISMEMBER: Kiểm tra nhiều phần tử cùng lúc
+func (s *Store) SMISMEMBER(key string, members ...string) ([]bool, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  set, err := s.getSetRead(key)
+  if err != nil {
+    return nil, err
+  }
+
+  results := make([]bool, len(members))
+  for i, m := range members {
+    _, results[i] = set[m]
+  }
+  return results, nil
+}
+
+// SCARD: Số phần tử của Set
+func (s *Store) SCARD(key string) (int, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  set, err := s.getSetRead(key)
+  if err != nil {
+    return 0, err
+  }
+  return len(set), nil
+}
+
+// SPOP: Lấy ngẫu nhiên và xóa tối đa count phần tử khỏi Set
+func (s *Store) SPOP(key string, count int) ([]string, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  set, err := s.getSetWrite(key, false)
+  if err != nil || set == nil {
+    return nil, err
+  }
+
+  // Thứ tự duyệt map của Go vốn đã ngẫu nhiên
+  result := make([]string, 0, min(count, len(set)))
+  for m := range set {
+    if len(result) >= count {
+      break
+    }
+    result = append(result, m)
+    delete(set, m)
+  }
+
+  if len(set) == 0 {
+    delete(s.data, key)
+  }
+  return result, nil
+}
+
+// SRANDMEMBER: Lấy ngẫu nhiên các phần tử mà không xóa.
+// count > 0: các phần tử khác nhau, count < 0: có thể lặp lại |count| phần tử (tối đa MaxRandomMembers).
+func (s *Store) SRANDMEMBER(key string, count int) ([]string, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  // So sánh count < -MaxRandomMembers (không đổi dấu count) để math.MinInt không bị tràn số
+  if count < -MaxRandomMembers {
+    return nil, ErrRandomCountRange
+  }
+  set, err := s.getSetRead(key)
+  if err != nil || len(set) == 0 {
+    return []string{}, err
+  }
+
+  members := set.members()
+  if count >= 0 {
+    rand.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+    return members[:min(count, len(members))], nil
+  }
+
+  result := make([]string, -count)
+  for i := range result {
+    result[i] = members[rand.Intn(len(members))]
+  }
+  return result, nil
+}
+
+// setOp là loại phép toán tập hợp
+type setOp int
+
+const (
+  setInter setOp = iota
+  setUnion
+  setDiff
+)
+
+// combineLocked tính giao/hợp/hiệu của các Set (người gọi phải giữ khóa).
+// Key không tồn tại được coi như Set rỗng.
+func (s *Store) combineLocked(op setOp, keys []string) (Set, error) {
+  sets := make([]Set, len(keys))
+  for i, key := range keys {
+    set, err := s.getSetRead(key)
+    if err != nil {
+      return nil, err
+    }
+    sets[i] = set
+  }
+
+  result := make(Set)
+  switch op {
+  case setInter:
+    for m := range sets[0] {
+      inAll := true
+      for _, other := range sets[1:] {
+        if _, ok := other[m]; !ok {
+          inAll = false
+          break
+        }
+      }
+      if inAll {
+        result[m] = struct{}{}
+      }
+    }
+  case setUnion:
+    for _, set := range sets {
+      for m := range set {
+        result[m] = struct{}{}
+      }
+    }
+  case setDiff:
+    for m := range sets[0] {
+      result[m] = struct{}{}
+    }
+    for _, other := range sets[1:] {
+      for m := range other {
+        delete(result, m)
+      }
+    }
+  }
+  return result, nil
+}
+
+// combine tính phép toán tập hợp và trả về các phần tử kết quả
+func (s *Store) combine(op setOp, keys []string) ([]string, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  result, err := s.combineLocked(op, keys)
+  if err != nil {
+    return nil, err
+  }
+  return result.members(), nil
+}
+
+// combineStore tính phép toán tập hợp và ghi kết quả vào dst (ghi đè bất kể kiểu cũ).
+// Trả về số phần tử của Set kết quả; dst bị xóa nếu kết quả rỗng.
+func (s *Store) combineStore(op setOp, dst string, keys []string) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  result, err := s.combineLocked(op, keys)
+  if err != nil {
+    return 0, err
+  }
+
+  if len(result) == 0 {
+    delete(s.data, dst)
+  } else {
+    s.data[dst] = Entry{Value: result}
+  }
+  return len(result), nil
+}
+
+// SINTER: Giao của các Set
+func (s *Store) SINTER(keys ...string) ([]string, error) {
+  return s.combine(setInter, keys)
+}
+
+// SUNION: Hợp của các Set
+func (s *Store) SUNION(keys ...string) ([]string, error) {
+  return s.combine(setUnion, keys)
+}
+
+// SDIFF: Hiệu của Set đầu tiên với các Set còn lại
+func (s *Store) SDIFF(keys ...string) ([]string, error) {
+  return s.combine(setDiff, keys)
+}
+
+// SINTERSTORE: Giống SINTER nhưng lưu kết quả vào dst
+func (s *Store) SINTERSTORE(dst string, keys ...string) (int, error) {
+  return s.combineStore(setInter, dst, keys)
+}
+
+// SUNIONSTORE: Giống SUNION nhưng lưu kết quả vào dst
+func (s *Store) SUNIONSTORE(dst string, keys ...string) (int, error) {
+  return s.combineStore(setUnion, dst, keys)
+}
+
+// SDIFFSTORE: Giống SDIFF nhưng lưu kết quả vào dst
+func (s *Store) SDIFFSTORE(dst string, keys ...string) (int, error) {
+  return s.combineStore(setDiff, dst, keys)
+}
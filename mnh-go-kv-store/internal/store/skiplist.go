@@ -0,0 +1,219 @@
+package store
+
+import "math/rand"
+
+const (
+  skiplistMaxLevel = 32   // Số tầng tối đa, đủ cho 2^64 phần tử với p = 0.25
+  skiplistP        = 0.25 // Xác suất một node được nâng lên tầng tiếp theo
+)
+
+// skiplistLevel là một tầng của node: con trỏ tới node kế tiếp và số node bị "nhảy qua".
+// span cho phép tính thứ hạng (rank) trong O(log n).
+type skiplistLevel struct {
+  forward *skiplistNode
+  span    int
+}
+
+// skiplistNode lưu một phần tử của Sorted Set, sắp xếp theo (score, member)
+type skiplistNode struct {
+  member   string
+  score    float64
+  backward *skiplistNode
+  level    []skiplistLevel
+}
+
+// skiplist là cấu trúc sắp xếp của Sorted Set (giống zskiplist của Redis)
+type skiplist struct {
+  header *skiplistNode
+  tail   *skiplistNode
+  length int
+  level  int
+}
+
+// rangeSpec mô tả một khoảng giá trị (theo score hoặc theo thứ tự từ điển)
+type rangeSpec interface {
+  gteMin(n *skiplistNode) bool // n nằm ở phía trên cận dưới
+  lteMax(n *skiplistNode) bool // n nằm ở phía dưới cận trên
+}
+
+func newSkiplist() *skiplist {
+  return &skiplist{
+    level:  1,
+    header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+  }
+}
+
+// randomLevel chọn số tầng cho node mới theo phân phối hình học
+func randomLevel() int {
+  level := 1
+  for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+    level++
+  }
+  return level
+}
+
+// before kiểm tra node n có đứng trước (score, member) trong thứ tự sắp xếp hay không
+func (n *skiplistNode) before(score float64, member string) bool {
+  return n.score < score || (n.score == score && n.member < member)
+}
+
+// insert thêm phần tử mới. Người gọi phải đảm bảo member chưa tồn tại.
+func (zsl *skiplist) insert(score float64, member string) *skiplistNode {
+  var update [skiplistMaxLevel]*skiplistNode
+  var rank [skiplistMaxLevel]int
+
+  x := zsl.header
+  for i := zsl.level - 1; i >= 0; i-- {
+    if i < zsl.level-1 {
+      rank[i] = rank[i+1]
+    }
+    for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+      rank[i] += x.level[i].span
+      x = x.level[i].forward
+    }
+    update[i] = x
+  }
+
+  level := randomLevel()
+  if level > zsl.level {
+    for i := zsl.level; i < level; i++ {
+      rank[i] = 0
+      update[i] = zsl.header
+      update[i].level[i].span = zsl.length
+    }
+    zsl.level = level
+  }
+
+  x = &skiplistNode{member: member, score: score, level: make([]skiplistLevel, level)}
+  for i := 0; i < level; i++ {
+    x.level[i].forward = update[i].level[i].forward
+    update[i].level[i].forward = x
+
+    x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+    update[i].level[i].span = rank[0] - rank[i] + 1
+  }
+
+  // Các tầng cao hơn node mới chỉ cần tăng span
+  for i := level; i < zsl.level; i++ {
+    update[i].level[i].span++
+  }
+
+  if update[0] != zsl.header {
+    x.backward = update[0]
+  }
+  if x.level[0].forward != nil {
+    x.level[0].forward.backward = x
+  } else {
+    zsl.tail = x
+  }
+  zsl.length++
+  return x
+}
+
+// deleteNode gỡ node x khỏi skiplist, update là các node đứng trước x ở từng tầng
+func (zsl *skiplist) deleteNode(x *skiplistNode, update []*skiplistNode) {
+  for i := 0; i < zsl.level; i++ {
+    if update[i].level[i].forward == x {
+      update[i].level[i].span += x.level[i].span - 1
+      update[i].level[i].forward = x.level[i].forward
+    } else {
+      update[i].level[i].span--
+    }
+  }
+
+  if x.level[0].forward != nil {
+    x.level[0].forward.backward = x.backward
+  } else {
+    zsl.tail = x.backward
+  }
+
+  for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+    zsl.level--
+  }
+  zsl.length--
+}
+
+// delete xóa phần tử (score, member), trả về false nếu không tìm thấy
+func (zsl *skiplist) delete(score float64, member string) bool {
+  update := make([]*skiplistNode, skiplistMaxLevel)
+
+  x := zsl.header
+  for i := zsl.level - 1; i >= 0; i-- {
+    for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+      x = x.level[i].forward
+    }
+    update[i] = x
+  }
+
+  x = x.level[0].forward
+  if x != nil && x.score == score && x.member == member {
+    zsl.deleteNode(x, update)
+    return true
+  }
+  return false
+}
+
+// rank trả về thứ hạng (bắt đầu từ 1) của phần tử, 0 nếu không tồn tại
+func (zsl *skiplist) rank(score float64, member string) int {
+  rank := 0
+  x := zsl.header
+  for i := zsl.level - 1; i >= 0; i-- {
+    for x.level[i].forward != nil &&
+      (x.level[i].forward.before(score, member) ||
+        (x.level[i].forward.score == score && x.level[i].forward.member == member)) {
+      rank += x.level[i].span
+      x = x.level[i].forward
+    }
+    if x != zsl.header && x.member == member {
+      return rank
+    }
+  }
+  return 0
+}
+
+// byRank trả về node có thứ hạng rank (bắt đầu từ 1), nil nếu ngoài phạm vi
+func (zsl *skiplist) byRank(rank int) *skiplistNode {
+  traversed := 0
+  x := zsl.header
+  for i := zsl.level - 1; i >= 0; i-- {
+    for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+      traversed += x.level[i].span
+      x = x.level[i].forward
+    }
+    if traversed == rank {
+      return x
+    }
+  }
+  return nil
+}
+
+// firstInRange trả về node đầu tiên nằm trong khoảng r, nil nếu khoảng rỗng
+func (zsl *skiplist) firstInRange(r rangeSpec) *skiplistNode {
+  x := zsl.header
+  for i := zsl.level - 1; i >= 0; i-- {
+    for x.level[i].forward != nil && !r.gteMin(x.level[i].forward) {
+      x = x.level[i].forward
+    }
+  }
+
+  x = x.level[0].forward
+  if x == nil || !r.lteMax(x) {
+    return nil
+  }
+  return x
+}
+
+// lastInRange trả về node cuối cùng nằm trong khoảng r, nil nếu khoảng rỗng
+func (zsl *skiplist) lastInRange(r rangeSpec) *skiplistNode {
+  x := zsl.header
+  for i := zsl.level - 1; i >= 0; i-- {
+    for x.level[i].forward != nil && r.lteMax(x.level[i].forward) {
+      x = x.level[i].forward
+    }
+  }
+
+  if x == zsl.header || !r.gteMin(x) {
+    return nil
+  }
+  return x
+}
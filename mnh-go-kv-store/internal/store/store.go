@@ -1,182 +1,184 @@
 package store
 
 import (
+  "errors"
   "sync"
+  "sync/atomic"
   "time"
 )
 
+// ErrWrongType được trả về khi key tồn tại nhưng chứa kiểu dữ liệu khác với lệnh yêu cầu
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
 // Entry lưu trữ giá trị và thời gian hết hạn của một key
 type Entry struct {
   Value     interface{} // Có thể là string, map[string]string, list, v.v.
   ExpiresAt time.Time   // Thời điểm hết hạn (Zero time.Time nếu không hết hạn)
 }
 
+// isExpired kiểm tra entry đã hết hạn tại thời điểm now hay chưa
+func (e Entry) isExpired(now time.Time) bool {
+  return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
+
 // Store chứa dữ liệu chính và Mutex để quản lý đồng thời
 type Store struct {
-  data map[string]Entry
-  mu   sync.RWMutex // RWMutex cho phép đọc đồng thời, nhưng khóa khi ghi
+  data     map[string]Entry
+  mu       sync.RWMutex            // RWMutex cho phép đọc đồng thời, nhưng khóa khi ghi
+  waiters  map[string][]*KeyWaiter // Hàng đợi FIFO các kết nối đang chờ trên từng key (BLPOP, ...)
+  volatile map[string]struct{}     // Các key (có thể) đang có TTL, dùng để lấy mẫu khi xóa chủ động
+
+  loading        atomic.Bool  // Đang tải dữ liệu khi khởi động (RDB/AOF), tạm ngưng việc hết hạn
+  dirty          atomic.Int64 // Số thay đổi kể từ lần lưu snapshot gần nhất
+  expiredKeys    atomic.Int64 // Tổng số key đã bị xóa do hết hạn (lazy + chủ động)
+  timeCapReached atomic.Int64 // Số vòng xóa chủ động bị dừng do hết ngân sách thời gian
+  expireCycleNs  atomic.Int64 // Tổng thời gian đã dùng cho các vòng xóa chủ động
 }
 
 func NewStore() *Store {
   return &Store{
-    data: make(map[string]Entry),
+    data:     make(map[string]Entry),
+    waiters:  make(map[string][]*KeyWaiter),
+    volatile: make(map[string]struct{}),
   }
 }
 
-// SET: Thiết lập giá trị cho một key với thời gian hết hạn tùy chọn
-func (s *Store) SET(key string, value string, ttl time.Duration) {
-  s.mu.Lock()
-  defer s.mu.Unlock()
-
-  entry := Entry{Value: value}
-  if ttl > 0 {
-    entry.ExpiresAt = time.Now().Add(ttl)
-  }
-
-  s.data[key] = entry
+// expiredLocked kiểm tra entry đã hết hạn hay chưa (phải giữ khóa đọc hoặc ghi).
+// Khi đang tải dữ liệu, không entry nào bị coi là hết hạn để các lệnh được phát lại thấy đúng
+// trạng thái tại thời điểm chúng được ghi; các key hết hạn sẽ bị xóa trong FinishLoading.
+func (s *Store) expiredLocked(entry Entry) bool {
+  return !s.loading.Load() && entry.isExpired(time.Now())
 }
 
-// GET: Lấy giá trị từ một key
-func (s *Store) GET(key string) (string, bool) {
-  s.mu.RLock()
+// lookupWrite trả về entry còn hiệu lực của key và xóa luôn entry đã hết hạn.
+// Người gọi phải giữ khóa ghi s.mu.
+func (s *Store) lookupWrite(key string) (Entry, bool) {
   entry, ok := s.data[key]
-  s.mu.RUnlock()
-
   if !ok {
-    return "", false
+    return Entry{}, false
   }
-
-  // Kiểm tra hết hạn (TTL)
-  if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
-    // Gọi DELETE để xóa entry hết hạn
-    s.DELETE(key)
-    return "", false
+  if s.expiredLocked(entry) {
+    delete(s.data, key)
+    s.expiredKeys.Add(1)
+    return Entry{}, false
   }
+  return entry, true
+}
 
-  // Ép kiểu giá trị (giả sử là string cho lệnh GET cơ bản)
-  strVal, isString := entry.Value.(string)
-  if !isString {
-    return "", false // Lỗi nếu key tồn tại nhưng không phải string
+// lookupRead trả về entry còn hiệu lực của key, coi entry hết hạn như không tồn tại.
+// Người gọi chỉ cần giữ khóa đọc s.mu.
+func (s *Store) lookupRead(key string) (Entry, bool) {
+  entry, ok := s.data[key]
+  if !ok || s.expiredLocked(entry) {
+    return Entry{}, false
   }
-  return strVal, true
+  return entry, true
 }
 
-// DELETE: Xóa một key khỏi Store
-func (s *Store) DELETE(key string) {
+// SET: Thiết lập giá trị cho một key với thời gian hết hạn tùy chọn
+func (s *Store) SET(key string, value string, ttl time.Duration) {
   s.mu.Lock()
   defer s.mu.Unlock()
 
-  delete(s.data, key)
+  entry := Entry{Value: value}
+  if ttl > 0 {
+    entry.ExpiresAt = time.Now().Add(ttl)
+  }
+
+  s.data[key] = entry
+  s.trackExpiryLocked(key, entry)
 }
 
-// HSET: Thiết lập giá trị cho một trường (field) trong Hash
-func (s *Store) HSET(key string, field string, value string) bool {
+// SetOptions là các tùy chọn của lệnh SET
+type SetOptions struct {
+  NX        bool      // Chỉ ghi khi key chưa tồn tại
+  XX        bool      // Chỉ ghi khi key đã tồn tại
+  Get       bool      // Trả về giá trị cũ (key cũ phải là String)
+  KeepTTL   bool      // Giữ nguyên thời điểm hết hạn hiện có
+  ExpiresAt time.Time // Thời điểm hết hạn mới (zero: không hết hạn)
+}
+
+// SETWithOptions: Thiết lập giá trị với đầy đủ tùy chọn của lệnh SET, thực hiện nguyên tử.
+// Trả về giá trị cũ (và cờ cho biết key cũ có tồn tại) cùng cờ applied cho biết lệnh
+// có thực sự ghi hay bị bỏ qua do NX/XX.
+func (s *Store) SETWithOptions(key string, value string, opts SetOptions) (string, bool, bool, error) {
   s.mu.Lock()
   defer s.mu.Unlock()
 
-  entry, ok := s.data[key]
-
-  if !ok {
-    // Key không tồn tại: tạo Entry mới với Hash Map
-    hash := make(map[string]string)
-    hash[field] = value
-    s.data[key] = Entry{Value: hash}
-    return true
+  old, exists := s.lookupWrite(key)
+  oldStr, isString := old.Value.(string)
+  if opts.Get && exists && !isString {
+    return "", false, false, ErrWrongType
   }
 
-  hash, isHash := entry.Value.(map[string]string)
-  if !isHash {
-    // Lỗi: Key tồn tại nhưng không phải là Hash (ví dụ: là String)
-    return false
+  if (opts.NX && exists) || (opts.XX && !exists) {
+    return oldStr, exists, false, nil
   }
 
-  hash[field] = value
-  return true
-}
-
-// HGET: Lấy giá trị của một trường (field) trong Hash
-func (s *Store) HGET(key string, field string) (string, bool) {
-  s.mu.RLock()
-  defer s.mu.RUnlock()
-
-  entry, ok := s.data[key]
-  if !ok {
-    return "", false
+  entry := Entry{Value: value, ExpiresAt: opts.ExpiresAt}
+  if opts.KeepTTL && exists {
+    entry.ExpiresAt = old.ExpiresAt
   }
 
-  // Kiểm tra và ép kiểu sang Hash Map
-  hash, isHash := entry.Value.(map[string]string)
-  if !isHash {
-    return "", false
+  // Thời điểm hết hạn đã qua (ví dụ EXAT trong quá khứ) thì key coi như bị xóa ngay
+  if s.expiredLocked(entry) {
+    delete(s.data, key)
+  } else {
+    s.data[key] = entry
+    s.trackExpiryLocked(key, entry)
   }
-
-  val, fieldFound := hash[field]
-  return val, fieldFound
+  return oldStr, exists, true, nil
 }
 
-// HGETALL: Lấy tất cả field-value trong Hash
-func (s *Store) HGETALL(key string) (map[string]string, bool) {
-  s.mu.RLock()
-  defer s.mu.RUnlock()
+// GET: Lấy giá trị từ một key. Giữ khóa ghi để key hết hạn được xóa cùng lúc với việc kiểm tra,
+// không xóa nhầm giá trị mới do một lệnh SET đồng thời ghi vào.
+func (s *Store) GET(key string) (string, bool) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
 
-  entry, ok := s.data[key]
+  entry, ok := s.lookupWrite(key)
   if !ok {
-    return nil, false
+    return "", false
   }
 
-  // Kiểm tra và ép kiểu sang Hash Map
-  hash, isHash := entry.Value.(map[string]string)
-  if !isHash {
-    return nil, false
+  // Ép kiểu giá trị (giả sử là string cho lệnh GET cơ bản)
+  strVal, isString := entry.Value.(string)
+  if !isString {
+    return "", false // Lỗi nếu key tồn tại nhưng không phải string
   }
+  return strVal, true
+}
 
-  // Tạo copy để tránh race condition
-  result := make(map[string]string)
-  for k, v := range hash {
-    result[k] = v
-  }
+// DELETE: Xóa một key khỏi Store
+func (s *Store) DELETE(key string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
 
-  return result, true
+  delete(s.data, key)
 }
 
-// EXISTS: Kiểm tra xem key có tồn tại không
+// EXISTS: Kiểm tra xem key có tồn tại không (key hết hạn bị xóa như GET)
 func (s *Store) EXISTS(key string) bool {
-  s.mu.RLock()
-  entry, ok := s.data[key]
-  s.mu.RUnlock()
-
-  if !ok {
-    return false
-  }
-
-  // Kiểm tra hết hạn
-  if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
-    s.DELETE(key) // Delete expired entry
-    return false
-  }
+  s.mu.Lock()
+  defer s.mu.Unlock()
 
-  return true
+  _, ok := s.lookupWrite(key)
+  return ok
 }
 
 // TTL: Lấy thời gian còn lại (Time To Live) của key, trả về giây
 func (s *Store) TTL(key string) int {
-  s.mu.RLock()
-  entry, ok := s.data[key]
-  s.mu.RUnlock()
+  s.mu.Lock()
+  defer s.mu.Unlock()
 
+  entry, ok := s.lookupWrite(key)
   if !ok {
-    return -2 // Key không tồn tại
+    return -2 // Key không tồn tại hoặc đã hết hạn
   }
 
   if entry.ExpiresAt.IsZero() {
     return -1 // Key tồn tại nhưng không có TTL
   }
 
-  ttl := entry.ExpiresAt.Sub(time.Now())
-  if ttl <= 0 {
-    s.DELETE(key) // Key đã hết hạn
-    return -2
-  }
-
-  return int(ttl.Seconds())
+  return int(max(time.Until(entry.ExpiresAt), 0).Seconds())
 }
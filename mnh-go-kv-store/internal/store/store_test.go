@@ -0,0 +1,48 @@
+package store
+
+import (
+  "testing"
+  "time"
+)
+
+func TestLazyExpireCountsExpiredKeys(t *testing.T) {
+  s := NewStore()
+  for _, key := range []string{"get", "exists", "ttl"} {
+    s.SET(key, "value", time.Millisecond)
+  }
+  time.Sleep(5 * time.Millisecond)
+
+  if _, ok := s.GET("get"); ok {
+    t.Error("GET returned an expired key")
+  }
+  if s.EXISTS("exists") {
+    t.Error("EXISTS reported an expired key")
+  }
+  if ttl := s.TTL("ttl"); ttl != -2 {
+    t.Errorf("TTL of an expired key = %d, want -2", ttl)
+  }
+  if got := s.ExpireStats().ExpiredKeys; got != 3 {
+    t.Errorf("ExpiredKeys = %d, want 3", got)
+  }
+  if len(s.data) != 0 {
+    t.Errorf("%d expired keys are still stored", len(s.data))
+  }
+}
+
+func TestLazyExpireKeepsNewValue(t *testing.T) {
+  s := NewStore()
+  s.SET("key", "old", time.Millisecond)
+  time.Sleep(5 * time.Millisecond)
+
+  // Key hết hạn được ghi đè trước khi có lệnh đọc: các lệnh đọc phải thấy giá trị mới
+  s.SET("key", "new", 0)
+  if !s.EXISTS("key") || s.TTL("key") != -1 {
+    t.Fatal("the new value was treated as expired")
+  }
+  if value, ok := s.GET("key"); !ok || value != "new" {
+    t.Fatalf("GET = %q, %v, want the new value", value, ok)
+  }
+  if got := s.ExpireStats().ExpiredKeys; got != 0 {
+    t.Errorf("ExpiredKeys = %d, want 0", got)
+  }
+}
@@ -0,0 +1,228 @@
+package store
+
+import (
+  "errors"
+  "math"
+  "strconv"
+  "time"
+)
+
+// MaxStringSize là kích thước tối đa của một giá trị String (512MB giống Redis)
+const MaxStringSize = 512 * 1024 * 1024
+
+var (
+  // ErrNotInteger được trả về khi giá trị hiện tại không phải số nguyên 64-bit
+  ErrNotInteger = errors.New("ERR value is not an integer or out of range")
+  // ErrStringTooLong được trả về khi SETRANGE/APPEND làm giá trị vượt quá MaxStringSize
+  ErrStringTooLong = errors.New("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+)
+
+// getStringWrite trả về entry String của key (phải giữ khóa ghi)
+func (s *Store) getStringWrite(key string) (Entry, string, bool, error) {
+  entry, ok := s.lookupWrite(key)
+  if !ok {
+    return Entry{}, "", false, nil
+  }
+
+  str, isString := entry.Value.(string)
+  if !isString {
+    return Entry{}, "", false, ErrWrongType
+  }
+  return entry, str, true, nil
+}
+
+// getStringRead trả về giá trị String của key (chỉ cần khóa đọc)
+func (s *Store) getStringRead(key string) (string, bool, error) {
+  entry, ok := s.lookupRead(key)
+  if !ok {
+    return "", false, nil
+  }
+
+  str, isString := entry.Value.(string)
+  if !isString {
+    return "", false, ErrWrongType
+  }
+  return str, true, nil
+}
+
+// INCRBY: Cộng delta vào giá trị số nguyên của key (coi như 0 nếu chưa tồn tại).
+// Thời gian hết hạn hiện có của key được giữ nguyên.
+func (s *Store) INCRBY(key string, delta int64) (int64, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  entry, str, exists, err := s.getStringWrite(key)
+  if err != nil {
+    return 0, err
+  }
+
+  var current int64
+  if exists {
+    current, err = strconv.ParseInt(str, 10, 64)
+    if err != nil {
+      return 0, ErrNotInteger
+    }
+  }
+
+  if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+    return 0, ErrOverflow
+  }
+
+  current += delta
+  entry.Value = strconv.FormatInt(current, 10)
+  s.data[key] = entry
+  return current, nil
+}
+
+// INCRBYFLOAT: Cộng delta (số thực) vào giá trị của key, trả về giá trị mới đã định dạng.
+// onApplied (có thể nil) được gọi với giá trị mới khi vẫn đang giữ khóa ghi, để lệnh ghi AOF
+// theo đúng thứ tự các lần cộng đồng thời; onApplied không được chờ fsync.
+func (s *Store) INCRBYFLOAT(key string, delta float64, onApplied func(value string)) (string, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  entry, str, exists, err := s.getStringWrite(key)
+  if err != nil {
+    return "", err
+  }
+
+  var current float64
+  if exists {
+    current, err = strconv.ParseFloat(str, 64)
+    if err != nil || math.IsNaN(current) || math.IsInf(current, 0) {
+      return "", ErrNotFloat
+    }
+  }
+
+  current += delta
+  if math.IsNaN(current) || math.IsInf(current, 0) {
+    return "", ErrNaNOrInf
+  }
+
+  formatted := strconv.FormatFloat(current, 'f', -1, 64)
+  entry.Value = formatted
+  s.data[key] = entry
+  if onApplied != nil {
+    onApplied(formatted)
+  }
+  return formatted, nil
+}
+
+// APPEND: Nối value vào cuối giá trị hiện tại (tạo mới nếu chưa có), trả về độ dài mới
+func (s *Store) APPEND(key string, value string) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  entry, str, _, err := s.getStringWrite(key)
+  if err != nil {
+    return 0, err
+  }
+  if len(str)+len(value) > MaxStringSize {
+    return 0, ErrStringTooLong
+  }
+
+  entry.Value = str + value
+  s.data[key] = entry
+  return len(str) + len(value), nil
+}
+
+// STRLEN: Độ dài giá trị String của key (0 nếu không tồn tại)
+func (s *Store) STRLEN(key string) (int, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  str, _, err := s.getStringRead(key)
+  return len(str), err
+}
+
+// GETRANGE: Lấy chuỗi con trong khoảng [start, end] (hỗ trợ chỉ số âm)
+func (s *Store) GETRANGE(key string, start, end int) (string, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  str, _, err := s.getStringRead(key)
+  if err != nil {
+    return "", err
+  }
+
+  start, end, ok := normalizeRange(start, end, len(str))
+  if !ok {
+    return "", nil
+  }
+  return str[start : end+1], nil
+}
+
+// SETRANGE: Ghi đè value bắt đầu từ offset, chèn byte 0 nếu chuỗi hiện tại ngắn hơn.
+// Trả về độ dài mới của chuỗi.
+func (s *Store) SETRANGE(key string, offset int, value string) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  entry, str, exists, err := s.getStringWrite(key)
+  if err != nil {
+    return 0, err
+  }
+
+  // Giá trị rỗng không tạo key mới và không thay đổi chuỗi hiện có
+  if len(value) == 0 {
+    return len(str), nil
+  }
+  if offset+len(value) > MaxStringSize {
+    return 0, ErrStringTooLong
+  }
+
+  buf := []byte(str)
+  if need := offset + len(value); need > len(buf) {
+    buf = append(buf, make([]byte, need-len(buf))...)
+  }
+  copy(buf[offset:], value)
+
+  if !exists {
+    entry = Entry{}
+  }
+  entry.Value = string(buf)
+  s.data[key] = entry
+  return len(buf), nil
+}
+
+// GETDEL: Lấy giá trị String của key rồi xóa key
+func (s *Store) GETDEL(key string) (string, bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  _, str, exists, err := s.getStringWrite(key)
+  if err != nil || !exists {
+    return "", false, err
+  }
+
+  delete(s.data, key)
+  return str, true, nil
+}
+
+// GETEX: Lấy giá trị String của key và đồng thời cập nhật thời gian hết hạn.
+// expiresAt khác zero: đặt thời điểm hết hạn mới; persist = true: xóa thời gian hết hạn.
+func (s *Store) GETEX(key string, expiresAt time.Time, persist bool) (string, bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  entry, str, exists, err := s.getStringWrite(key)
+  if err != nil || !exists {
+    return "", false, err
+  }
+
+  switch {
+  case persist:
+    entry.ExpiresAt = time.Time{}
+  case !expiresAt.IsZero():
+    entry.ExpiresAt = expiresAt
+  }
+
+  // Thời điểm hết hạn đã qua thì xóa key luôn (giống Redis)
+  if s.expiredLocked(entry) {
+    delete(s.data, key)
+  } else {
+    s.data[key] = entry
+    s.trackExpiryLocked(key, entry)
+  }
+  return str, true, nil
+}
@@ -0,0 +1,549 @@
+package store
+
+import (
+  "errors"
+  "math"
+  "strconv"
+  "strings"
+)
+
+var (
+  // ErrNotFloat được trả về khi score không phải số thực hợp lệ
+  ErrNotFloat = errors.New("ERR value is not a valid float")
+  // ErrScoreNaN được trả về khi phép cộng score cho kết quả NaN (ví dụ +inf + -inf)
+  ErrScoreNaN = errors.New("ERR resulting score is not a number (NaN)")
+  // ErrInvalidScoreRange được trả về khi cận của ZRANGEBYSCORE/ZCOUNT không hợp lệ
+  ErrInvalidScoreRange = errors.New("ERR min or max is not a float")
+  // ErrInvalidLexRange được trả về khi cận của ZRANGEBYLEX không hợp lệ
+  ErrInvalidLexRange = errors.New("ERR min or max not valid string range item")
+)
+
+// ZSet là Sorted Set: hash member -> score để tra cứu O(1) kết hợp skiplist để
+// truy vấn theo thứ tự và theo thứ hạng trong O(log n)
+type ZSet struct {
+  dict map[string]float64
+  zsl  *skiplist
+}
+
+// ScoredMember là một phần tử của Sorted Set cùng score của nó
+type ScoredMember struct {
+  Member string
+  Score  float64
+}
+
+// ZAddOptions là các cờ của lệnh ZADD
+type ZAddOptions struct {
+  NX   bool // Chỉ thêm phần tử mới
+  XX   bool // Chỉ cập nhật phần tử đã tồn tại
+  GT   bool // Chỉ cập nhật khi score mới lớn hơn
+  LT   bool // Chỉ cập nhật khi score mới nhỏ hơn
+  CH   bool // Đếm cả phần tử bị thay đổi score (không chỉ phần tử mới)
+  INCR bool // Cộng dồn score giống ZINCRBY
+}
+
+func newZSet() *ZSet {
+  return &ZSet{
+    dict: make(map[string]float64),
+    zsl:  newSkiplist(),
+  }
+}
+
+// Len trả về số phần tử của Sorted Set
+func (z *ZSet) Len() int {
+  return len(z.dict)
+}
+
+// set gán score cho member, cập nhật cả dict và skiplist.
+// Trả về true nếu member là phần tử mới.
+func (z *ZSet) set(member string, score float64) bool {
+  old, exists := z.dict[member]
+  if exists {
+    if old == score {
+      return false
+    }
+    z.zsl.delete(old, member)
+  }
+  z.zsl.insert(score, member)
+  z.dict[member] = score
+  return !exists
+}
+
+// remove xóa member, trả về false nếu không tồn tại
+func (z *ZSet) remove(member string) bool {
+  score, exists := z.dict[member]
+  if !exists {
+    return false
+  }
+  z.zsl.delete(score, member)
+  delete(z.dict, member)
+  return true
+}
+
+// ScoreRange là khoảng score của ZRANGEBYSCORE/ZCOUNT, hỗ trợ cận mở "(" và ±inf
+type ScoreRange struct {
+  Min, Max     float64
+  MinExclusive bool
+  MaxExclusive bool
+}
+
+func (r ScoreRange) gteMin(n *skiplistNode) bool {
+  if r.MinExclusive {
+    return n.score > r.Min
+  }
+  return n.score >= r.Min
+}
+
+func (r ScoreRange) lteMax(n *skiplistNode) bool {
+  if r.MaxExclusive {
+    return n.score < r.Max
+  }
+  return n.score <= r.Max
+}
+
+// parseScoreBound đọc một cận score dạng "1.5", "(1.5", "-inf", "+inf"
+func parseScoreBound(bound string) (float64, bool, error) {
+  exclusive := strings.HasPrefix(bound, "(")
+  if exclusive {
+    bound = bound[1:]
+  }
+  value, err := strconv.ParseFloat(bound, 64)
+  if err != nil || math.IsNaN(value) {
+    return 0, false, ErrInvalidScoreRange
+  }
+  return value, exclusive, nil
+}
+
+// ParseScoreRange đọc cặp cận min/max của khoảng score
+func ParseScoreRange(min, max string) (ScoreRange, error) {
+  var r ScoreRange
+  var err error
+  if r.Min, r.MinExclusive, err = parseScoreBound(min); err != nil {
+    return r, err
+  }
+  if r.Max, r.MaxExclusive, err = parseScoreBound(max); err != nil {
+    return r, err
+  }
+  return r, nil
+}
+
+// LexRange là khoảng member theo thứ tự từ điển của ZRANGEBYLEX.
+// Cận dạng "[a" (đóng), "(a" (mở), "-" (âm vô cùng) hoặc "+" (dương vô cùng).
+type LexRange struct {
+  Min, Max     string
+  MinExclusive bool
+  MaxExclusive bool
+  minInf       int // -1: "-", 1: "+", 0: giá trị cụ thể
+  maxInf       int
+}
+
+func (r LexRange) gteMin(n *skiplistNode) bool {
+  switch r.minInf {
+  case -1:
+    return true
+  case 1:
+    return false
+  }
+  if r.MinExclusive {
+    return n.member > r.Min
+  }
+  return n.member >= r.Min
+}
+
+func (r LexRange) lteMax(n *skiplistNode) bool {
+  switch r.maxInf {
+  case 1:
+    return true
+  case -1:
+    return false
+  }
+  if r.MaxExclusive {
+    return n.member < r.Max
+  }
+  return n.member <= r.Max
+}
+
+// parseLexBound đọc một cận dạng "[a", "(a", "-" hoặc "+"
+func parseLexBound(bound string) (string, bool, int, error) {
+  switch {
+  case bound == "-":
+    return "", false, -1, nil
+  case bound == "+":
+    return "", false, 1, nil
+  case strings.HasPrefix(bound, "("):
+    return bound[1:], true, 0, nil
+  case strings.HasPrefix(bound, "["):
+    return bound[1:], false, 0, nil
+  }
+  return "", false, 0, ErrInvalidLexRange
+}
+
+// ParseLexRange đọc cặp cận min/max của khoảng theo thứ tự từ điển
+func ParseLexRange(min, max string) (LexRange, error) {
+  var r LexRange
+  var err error
+  if r.Min, r.MinExclusive, r.minInf, err = parseLexBound(min); err != nil {
+    return r, err
+  }
+  if r.Max, r.MaxExclusive, r.maxInf, err = parseLexBound(max); err != nil {
+    return r, err
+  }
+  return r, nil
+}
+
+// getZSetWrite trả về ZSet của key (phải giữ khóa ghi).
+// Nếu create = true và key chưa tồn tại thì tạo ZSet mới.
+func (s *Store) getZSetWrite(key string, create bool) (*ZSet, error) {
+  entry, ok := s.lookupWrite(key)
+  if !ok {
+    if !create {
+      return nil, nil
+    }
+    z := newZSet()
+    s.data[key] = Entry{Value: z}
+    return z, nil
+  }
+
+  z, isZSet := entry.Value.(*ZSet)
+  if !isZSet {
+    return nil, ErrWrongType
+  }
+  return z, nil
+}
+
+// getZSetRead trả về ZSet của key (chỉ cần khóa đọc), nil nếu key không tồn tại
+func (s *Store) getZSetRead(key string) (*ZSet, error) {
+  entry, ok := s.lookupRead(key)
+  if !ok {
+    return nil, nil
+  }
+
+  z, isZSet := entry.Value.(*ZSet)
+  if !isZSet {
+    return nil, ErrWrongType
+  }
+  return z, nil
+}
+
+// removeZSetIfEmpty xóa key khi Sorted Set không còn phần tử
+func (s *Store) removeZSetIfEmpty(key string, z *ZSet) {
+  if z.Len() == 0 {
+    delete(s.data, key)
+  }
+}
+
+// ZADD: Thêm/cập nhật các phần tử theo opts.
+// Trả về số phần tử được đếm (mới thêm, hoặc cả phần tử bị đổi score nếu CH) và danh sách
+// các phần tử đã được áp dụng cùng score cuối cùng (dùng để ghi AOF và trả kết quả INCR).
+// onApplied (có thể nil) được gọi với các phần tử đã áp dụng khi vẫn đang giữ khóa ghi, để score
+// tuyệt đối được ghi AOF theo đúng thứ tự các lệnh đồng thời; onApplied không được chờ fsync.
+func (s *Store) ZADD(key string, opts ZAddOptions, members []ScoredMember, onApplied func(applied []ScoredMember)) (int, []ScoredMember, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  // Không tạo key mới khi XX
+  z, err := s.getZSetWrite(key, !opts.XX)
+  if err != nil || z == nil {
+    return 0, nil, err
+  }
+
+  count := 0
+  applied := make([]ScoredMember, 0, len(members))
+  for _, m := range members {
+    old, exists := z.dict[m.Member]
+    if (opts.NX && exists) || (opts.XX && !exists) {
+      continue
+    }
+
+    score := m.Score
+    if opts.INCR && exists {
+      score = old + m.Score
+      if math.IsNaN(score) {
+        s.removeZSetIfEmpty(key, z)
+        return 0, nil, ErrScoreNaN
+      }
+    }
+
+    if exists && ((opts.GT && score <= old) || (opts.LT && score >= old)) {
+      continue
+    }
+
+    if z.set(m.Member, score) {
+      count++
+    } else if opts.CH && old != score {
+      count++
+    }
+    applied = append(applied, ScoredMember{Member: m.Member, Score: score})
+  }
+
+  s.removeZSetIfEmpty(key, z)
+  if onApplied != nil && len(applied) > 0 {
+    onApplied(applied)
+  }
+  return count, applied, nil
+}
+
+// ZINCRBY: Cộng increment vào score của member (tạo mới nếu chưa có), trả về score mới.
+// onApplied có ý nghĩa giống như trong ZADD.
+func (s *Store) ZINCRBY(key string, increment float64, member string, onApplied func(applied []ScoredMember)) (float64, error) {
+  _, applied, err := s.ZADD(key, ZAddOptions{INCR: true}, []ScoredMember{{Member: member, Score: increment}}, onApplied)
+  if err != nil {
+    return 0, err
+  }
+  return applied[0].Score, nil
+}
+
+// ZREM: Xóa các phần tử khỏi Sorted Set, trả về số phần tử đã xóa
+func (s *Store) ZREM(key string, members ...string) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  z, err := s.getZSetWrite(key, false)
+  if err != nil || z == nil {
+    return 0, err
+  }
+
+  removed := 0
+  for _, m := range members {
+    if z.remove(m) {
+      removed++
+    }
+  }
+
+  s.removeZSetIfEmpty(key, z)
+  return removed, nil
+}
+
+// ZSCORE: Lấy score của member
+func (s *Store) ZSCORE(key string, member string) (float64, bool, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  z, err := s.getZSetRead(key)
+  if err != nil || z == nil {
+    return 0, false, err
+  }
+
+  score, ok := z.dict[member]
+  return score, ok, nil
+}
+
+// ZCARD: Số phần tử của Sorted Set
+func (s *Store) ZCARD(key string) (int, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  z, err := s.getZSetRead(key)
+  if err != nil || z == nil {
+    return 0, err
+  }
+  return z.Len(), nil
+}
+
+// ZRANK: Thứ hạng (bắt đầu từ 0) của member, theo thứ tự giảm dần nếu rev = true
+func (s *Store) ZRANK(key string, member string, rev bool) (int, bool, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  z, err := s.getZSetRead(key)
+  if err != nil || z == nil {
+    return 0, false, err
+  }
+
+  score, ok := z.dict[member]
+  if !ok {
+    return 0, false, nil
+  }
+
+  rank := z.zsl.rank(score, member)
+  if rev {
+    return z.Len() - rank, true, nil
+  }
+  return rank - 1, true, nil
+}
+
+// collect duyệt từ node start theo chiều xuôi (hoặc ngược nếu rev) và lấy tối đa limit
+// phần tử (limit < 0: không giới hạn) còn thỏa inRange
+func collect(start *skiplistNode, rev bool, limit int, inRange func(*skiplistNode) bool) []ScoredMember {
+  result := make([]ScoredMember, 0)
+  for x := start; x != nil && limit != 0 && inRange(x); limit-- {
+    result = append(result, ScoredMember{Member: x.member, Score: x.score})
+    if rev {
+      x = x.backward
+    } else {
+      x = x.level[0].forward
+    }
+  }
+  return result
+}
+
+// ZRangeByRank: Lấy các phần tử theo thứ hạng trong khoảng [start, stop] (hỗ trợ chỉ số âm)
+func (s *Store) ZRangeByRank(key string, start, stop int, rev bool) ([]ScoredMember, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  z, err := s.getZSetRead(key)
+  if err != nil || z == nil {
+    return []ScoredMember{}, err
+  }
+
+  start, stop, ok := normalizeRange(start, stop, z.Len())
+  if !ok {
+    return []ScoredMember{}, nil
+  }
+
+  // Với rev, thứ hạng i tính từ cuối tương ứng với thứ hạng Len()-i tính từ đầu
+  var first *skiplistNode
+  if rev {
+    first = z.zsl.byRank(z.Len() - start)
+  } else {
+    first = z.zsl.byRank(start + 1)
+  }
+  always := func(*skiplistNode) bool { return true }
+  return collect(first, rev, stop-start+1, always), nil
+}
+
+// rangeGeneric lấy các phần tử trong khoảng r, bỏ qua offset phần tử đầu và lấy tối đa
+// count phần tử (count < 0: không giới hạn)
+func (s *Store) rangeGeneric(key string, r rangeSpec, rev bool, offset, count int) ([]ScoredMember, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  z, err := s.getZSetRead(key)
+  if err != nil || z == nil || offset < 0 {
+    return []ScoredMember{}, err
+  }
+
+  var x *skiplistNode
+  var inRange func(*skiplistNode) bool
+  if rev {
+    x = z.zsl.lastInRange(r)
+    inRange = r.gteMin
+  } else {
+    x = z.zsl.firstInRange(r)
+    inRange = r.lteMax
+  }
+
+  for ; x != nil && offset > 0; offset-- {
+    if rev {
+      x = x.backward
+    } else {
+      x = x.level[0].forward
+    }
+  }
+  return collect(x, rev, count, inRange), nil
+}
+
+// ZRangeByScore: Lấy các phần tử có score trong khoảng r
+func (s *Store) ZRangeByScore(key string, r ScoreRange, rev bool, offset, count int) ([]ScoredMember, error) {
+  return s.rangeGeneric(key, r, rev, offset, count)
+}
+
+// ZRangeByLex: Lấy các phần tử có member trong khoảng r (khi mọi score bằng nhau)
+func (s *Store) ZRangeByLex(key string, r LexRange, rev bool, offset, count int) ([]ScoredMember, error) {
+  return s.rangeGeneric(key, r, rev, offset, count)
+}
+
+// countInRange đếm số phần tử trong khoảng r bằng hiệu thứ hạng, O(log n)
+func (s *Store) countInRange(key string, r rangeSpec) (int, error) {
+  s.mu.RLock()
+  defer s.mu.RUnlock()
+
+  z, err := s.getZSetRead(key)
+  if err != nil || z == nil {
+    return 0, err
+  }
+
+  first := z.zsl.firstInRange(r)
+  if first == nil {
+    return 0, nil
+  }
+  last := z.zsl.lastInRange(r)
+  return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1, nil
+}
+
+// ZCOUNT: Số phần tử có score trong khoảng r
+func (s *Store) ZCOUNT(key string, r ScoreRange) (int, error) {
+  return s.countInRange(key, r)
+}
+
+// ZLEXCOUNT: Số phần tử có member trong khoảng r
+func (s *Store) ZLEXCOUNT(key string, r LexRange) (int, error) {
+  return s.countInRange(key, r)
+}
+
+// ZPOP: Lấy ra và xóa tối đa count phần tử có score nhỏ nhất (hoặc lớn nhất nếu max = true)
+func (s *Store) ZPOP(key string, count int, max bool) ([]ScoredMember, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  z, err := s.getZSetWrite(key, false)
+  if err != nil || z == nil {
+    return []ScoredMember{}, err
+  }
+
+  result := make([]ScoredMember, 0, min(count, z.Len()))
+  for len(result) < count && z.Len() > 0 {
+    x := z.zsl.header.level[0].forward
+    if max {
+      x = z.zsl.tail
+    }
+    result = append(result, ScoredMember{Member: x.member, Score: x.score})
+    z.remove(x.member)
+  }
+
+  s.removeZSetIfEmpty(key, z)
+  return result, nil
+}
+
+// removeMembers xóa các phần tử đã thu thập, trả về số phần tử đã xóa
+func (s *Store) removeMembers(key string, z *ZSet, members []ScoredMember) int {
+  for _, m := range members {
+    z.remove(m.Member)
+  }
+  s.removeZSetIfEmpty(key, z)
+  return len(members)
+}
+
+// ZREMRANGEBYRANK: Xóa các phần tử có thứ hạng trong khoảng [start, stop]
+func (s *Store) ZREMRANGEBYRANK(key string, start, stop int) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  z, err := s.getZSetWrite(key, false)
+  if err != nil || z == nil {
+    return 0, err
+  }
+
+  start, stop, ok := normalizeRange(start, stop, z.Len())
+  if !ok {
+    return 0, nil
+  }
+
+  always := func(*skiplistNode) bool { return true }
+  members := collect(z.zsl.byRank(start+1), false, stop-start+1, always)
+  return s.removeMembers(key, z, members), nil
+}
+
+// removeRangeGeneric xóa các phần tử nằm trong khoảng r
+func (s *Store) removeRangeGeneric(key string, r rangeSpec) (int, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  z, err := s.getZSetWrite(key, false)
+  if err != nil || z == nil {
+    return 0, err
+  }
+
+  members := collect(z.zsl.firstInRange(r), false, -1, r.lteMax)
+  return s.removeMembers(key, z, members), nil
+}
+
+// ZREMRANGEBYSCORE: Xóa các phần tử có score trong khoảng r
+func (s *Store) ZREMRANGEBYSCORE(key string, r ScoreRange) (int, error) {
+  return s.removeRangeGeneric(key, r)
+}
+
+// ZREMRANGEBYLEX: Xóa các phần tử có member trong khoảng r
+func (s *Store) ZREMRANGEBYLEX(key string, r LexRange) (int, error) {
+  return s.removeRangeGeneric(key, r)
+}
@@ -1,22 +1,74 @@
 package client
 
 import (
+  "crypto/tls"
+  "errors"
   "fmt"
   "net"
+  "strconv"
+  "strings"
   "time"
 
   "mnhgo/mnh-go-kv-store/internal/protocol"
 )
 
+// ErrEmptyCommand được trả về khi gửi một lệnh không có thành phần nào (Do hoặc Pipeline.Queue)
+var ErrEmptyCommand = errors.New("empty command")
+
 // Client struct quản lý kết nối TCP và I/O với Server
 type Client struct {
   conn net.Conn
   resp *protocol.Resp
+  buf  []byte // Buffer dùng lại để mã hóa lệnh
+}
+
+// Option là tùy chọn khi tạo kết nối bằng NewClient
+type Option func(*options)
+
+type options struct {
+  username string
+  password string
+  auth     bool
+  tls      *tls.Config // nil: kết nối TCP không mã hóa
+}
+
+// WithAuth xác thực kết nối bằng AUTH ngay sau khi kết nối. username rỗng dùng user default
+// (mật khẩu là requirepass của server).
+func WithAuth(username, password string) Option {
+  return func(o *options) {
+    o.username, o.password, o.auth = username, password, true
+  }
+}
+
+// WithTLS kết nối tới cổng TLS của server. config chứa các CA dùng để xác minh server (RootCAs,
+// nil: CA của hệ thống) và chứng chỉ của client khi server yêu cầu mutual TLS (Certificates).
+// ServerName mặc định là tên host trong địa chỉ.
+func WithTLS(config *tls.Config) Option {
+  return func(o *options) {
+    o.tls = config
+  }
 }
 
-// NewClient thiết lập kết nối TCP đến địa chỉ server (ví dụ: "localhost:6379")
-func NewClient(addr string) (*Client, error) {
-  conn, err := net.Dial("tcp", addr)
+// NewClient thiết lập kết nối TCP (hoặc TLS với WithTLS) đến địa chỉ server (ví dụ: "localhost:6379"),
+// hoặc kết nối tới Unix socket với địa chỉ dạng "unix:///run/mnh-kv.sock"
+func NewClient(addr string, opts ...Option) (*Client, error) {
+  var o options
+  for _, opt := range opts {
+    opt(&o)
+  }
+
+  network, address := "tcp", addr
+  if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+    network, address = "unix", path
+  }
+
+  var conn net.Conn
+  var err error
+  if o.tls != nil {
+    conn, err = tls.Dial(network, address, o.tls)
+  } else {
+    conn, err = net.Dial(network, address)
+  }
   if err != nil {
     return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
   }
@@ -26,6 +78,12 @@ func NewClient(addr string) (*Client, error) {
     // Sử dụng protocol.NewResp để đọc phản hồi từ kết nối
     resp: protocol.NewResp(conn),
   }
+  if o.auth {
+    if err := client.AUTH(o.username, o.password); err != nil {
+      conn.Close()
+      return nil, err
+    }
+  }
   return client, nil
 }
 
@@ -34,19 +92,46 @@ func (c *Client) Close() error {
   return c.conn.Close()
 }
 
-// executeCommand gửi lệnh RESP và chờ phản hồi từ server
-// cmds là các thành phần của lệnh (ví dụ: "SET", "key1", "value1")
-func (c *Client) executeCommand(cmds ...string) (protocol.Value, error) {
-  // 1. Mã hóa lệnh thành định dạng RESP Array
-  array := make([]protocol.Value, len(cmds))
-  for i, cmd := range cmds {
-    // Tạo từng phần tử lệnh dưới dạng Bulk String
-    array[i] = protocol.Value{Typ: "bulk", Bulk: cmd}
+// AUTH xác thực kết nối với user username (rỗng: user default, chỉ gửi mật khẩu như AUTH password)
+func (c *Client) AUTH(username, password string) error {
+  cmds := []string{"AUTH", password}
+  if username != "" {
+    cmds = []string{"AUTH", username, password}
   }
-  command := protocol.Value{Typ: "array", Array: array}
+  return c.okReply(cmds...)
+}
+
+// HELLO chọn phiên bản giao thức (2 hoặc 3) cho kết nối và trả về thông tin server (server, version,
+// proto, id, ...). Các hàm API khác hoạt động như nhau với cả hai phiên bản; Do trả về các kiểu RESP3
+// (map, set, double, ...) khi đã chọn phiên bản 3.
+func (c *Client) HELLO(protover int) (map[string]protocol.Value, error) {
+  response, err := c.executeCommand("HELLO", strconv.Itoa(protover))
+  if err != nil {
+    return nil, err
+  }
+  if (response.Typ != "map" && response.Typ != "array") || len(response.Array)%2 != 0 {
+    return nil, fmt.Errorf("unexpected response type for HELLO: %s", response.Typ)
+  }
+
+  info := make(map[string]protocol.Value, len(response.Array)/2)
+  for i := 0; i < len(response.Array); i += 2 {
+    info[response.Array[i].Bulk] = response.Array[i+1]
+  }
+  return info, nil
+}
+
+// Do gửi một lệnh bất kỳ (ví dụ: c.Do("OBJECT", "ENCODING", "key")) và trả về nguyên phản hồi của server.
+// Lỗi server trả về nằm trong phản hồi (Typ "error"); lỗi trả về của Do là lỗi kết nối.
+func (c *Client) Do(cmds ...string) (protocol.Value, error) {
+  if len(cmds) == 0 {
+    return protocol.Value{}, ErrEmptyCommand
+  }
+
+  // 1. Mã hóa lệnh thành RESP Array các Bulk String vào buffer dùng lại
+  c.buf = protocol.AppendCommand(c.buf[:0], cmds)
 
   // 2. Gửi byte stream RESP qua kết nối
-  _, err := c.conn.Write(command.Marshal()) // Giả định protocol.Value có Marshal()
+  _, err := c.conn.Write(c.buf)
   if err != nil {
     return protocol.Value{}, fmt.Errorf("failed to write command: %w", err)
   }
@@ -56,8 +141,18 @@ func (c *Client) executeCommand(cmds ...string) (protocol.Value, error) {
   if err != nil {
     return protocol.Value{}, fmt.Errorf("failed to read response: %w", err)
   }
+  return response, nil
+}
 
-  // 4. Xử lý lỗi từ server (RESP Error)
+// executeCommand gửi lệnh RESP và chờ phản hồi từ server
+// cmds là các thành phần của lệnh (ví dụ: "SET", "key1", "value1")
+func (c *Client) executeCommand(cmds ...string) (protocol.Value, error) {
+  response, err := c.Do(cmds...)
+  if err != nil {
+    return protocol.Value{}, err
+  }
+
+  // Xử lý lỗi từ server (RESP Error)
   if response.Typ == "error" {
     return protocol.Value{}, fmt.Errorf("server error: %s", response.Str)
   }
@@ -142,3 +237,92 @@ func (c *Client) HGET(key string, field string) (string, error) {
 
   return response.Bulk, nil
 }
+
+// --- Các hàm hỗ trợ giải mã phản hồi ---
+
+// integerReply thực thi lệnh và trả về kết quả dạng Integer
+func (c *Client) integerReply(cmds ...string) (int, error) {
+  response, err := c.executeCommand(cmds...)
+  if err != nil {
+    return 0, err
+  }
+  return toInteger(cmds[0], response)
+}
+
+// bulkReply thực thi lệnh và trả về Bulk String ("" và found = false nếu Null)
+func (c *Client) bulkReply(cmds ...string) (string, bool, error) {
+  response, err := c.executeCommand(cmds...)
+  if err != nil {
+    return "", false, err
+  }
+  return toBulk(cmds[0], response)
+}
+
+// okReply thực thi lệnh và kiểm tra phản hồi là Simple String "OK"
+func (c *Client) okReply(cmds ...string) error {
+  response, err := c.executeCommand(cmds...)
+  if err != nil {
+    return err
+  }
+  return toOK(cmds[0], response)
+}
+
+// stringSliceReply thực thi lệnh và trả về Array các Bulk String (nil nếu Null)
+func (c *Client) stringSliceReply(cmds ...string) ([]string, error) {
+  response, err := c.executeCommand(cmds...)
+  if err != nil {
+    return nil, err
+  }
+  return toStringSlice(cmds[0], response)
+}
+
+// toStringSlice chuyển một RESP Array (hoặc RESP3 Set, Map) thành []string, phần tử Null thành "".
+// Map được trả về dưới dạng key và value xen kẽ như với RESP2.
+func toStringSlice(cmd string, response protocol.Value) ([]string, error) {
+  switch response.Typ {
+  case "null":
+    return nil, nil
+  case "array", "set", "map", "push":
+  default:
+    return nil, fmt.Errorf("unexpected response type for %s: %s", cmd, response.Typ)
+  }
+
+  result := make([]string, len(response.Array))
+  for i, item := range response.Array {
+    result[i], _, _ = toBulk(cmd, item)
+  }
+  return result, nil
+}
+
+// toInteger kiểm tra phản hồi là RESP Integer
+func toInteger(cmd string, response protocol.Value) (int, error) {
+  if response.Typ != "integer" {
+    return 0, fmt.Errorf("unexpected response type for %s: %s", cmd, response.Typ)
+  }
+  return response.Num, nil
+}
+
+// toBulk kiểm tra phản hồi là Bulk String ("" và found = false nếu Null). Với RESP3, Double,
+// Big Number và Verbatim String được chuyển thành chuỗi giống như server trả về cho RESP2.
+func toBulk(cmd string, response protocol.Value) (string, bool, error) {
+  switch response.Typ {
+  case "null":
+    return "", false, nil
+  case "bulk", "verbatim":
+    return response.Bulk, true, nil
+  case "double":
+    return string(protocol.AppendDouble(nil, response.Double)), true, nil
+  case "bignum":
+    return response.Str, true, nil
+  default:
+    return "", false, fmt.Errorf("unexpected response type for %s: %s", cmd, response.Typ)
+  }
+}
+
+// toOK kiểm tra phản hồi là Simple String "OK"
+func toOK(cmd string, response protocol.Value) error {
+  if response.Typ != "string" || response.Str != "OK" {
+    return fmt.Errorf("unexpected response type for %s: %s", cmd, response.Typ)
+  }
+  return nil
+}
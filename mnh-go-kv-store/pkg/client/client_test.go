@@ -0,0 +1,60 @@
+package client
+
+import (
+  "net"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+func TestNewClientAddresses(t *testing.T) {
+  dir := t.TempDir()
+  socket := filepath.Join(dir, "kv.sock")
+  unixListener, err := net.Listen("unix", socket)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer unixListener.Close()
+  tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer tcpListener.Close()
+
+  tests := []struct {
+    name     string
+    addr     string
+    listener net.Listener // nil: kết nối phải thất bại
+  }{
+    {"tcp", tcpListener.Addr().String(), tcpListener},
+    {"unix socket", "unix://" + socket, unixListener},
+    {"missing unix socket", "unix://" + filepath.Join(dir, "missing.sock"), nil},
+    {"unix path without scheme", socket, nil},
+    {"empty unix path", "unix://", nil},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      c, err := NewClient(tt.addr)
+      if tt.listener == nil {
+        if err == nil {
+          c.Close()
+          t.Fatalf("NewClient(%q) connected", tt.addr)
+        }
+        if !strings.Contains(err.Error(), tt.addr) {
+          t.Errorf("NewClient() error %q does not name the address", err)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatal(err)
+      }
+      defer c.Close()
+      // Kết nối tới đúng listener của địa chỉ
+      conn, err := tt.listener.Accept()
+      if err != nil {
+        t.Fatal(err)
+      }
+      conn.Close()
+    })
+  }
+}
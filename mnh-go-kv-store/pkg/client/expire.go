@@ -0,0 +1,34 @@
+package client
+
+import (
+  "strconv"
+  "time"
+)
+
+// EXPIRE: Đặt TTL cho key (gửi dạng PEXPIRE), trả về false nếu key không tồn tại
+func (c *Client) EXPIRE(key string, ttl time.Duration) (bool, error) {
+  n, err := c.integerReply("PEXPIRE", key, strconv.FormatInt(ttl.Milliseconds(), 10))
+  return n == 1, err
+}
+
+// EXPIREAT: Đặt thời điểm hết hạn tuyệt đối cho key (gửi dạng PEXPIREAT)
+func (c *Client) EXPIREAT(key string, at time.Time) (bool, error) {
+  n, err := c.integerReply("PEXPIREAT", key, strconv.FormatInt(at.UnixMilli(), 10))
+  return n == 1, err
+}
+
+// PERSIST: Xóa TTL của key, trả về true nếu key có TTL trước đó
+func (c *Client) PERSIST(key string) (bool, error) {
+  n, err := c.integerReply("PERSIST", key)
+  return n == 1, err
+}
+
+// TTL: Thời gian sống còn lại tính bằng giây (-2: key không tồn tại, -1: không có TTL)
+func (c *Client) TTL(key string) (int, error) {
+  return c.integerReply("TTL", key)
+}
+
+// PTTL: Thời gian sống còn lại tính bằng mili giây (-2: key không tồn tại, -1: không có TTL)
+func (c *Client) PTTL(key string) (int, error) {
+  return c.integerReply("PTTL", key)
+}
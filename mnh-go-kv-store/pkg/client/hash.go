@@ -0,0 +1,106 @@
+package client
+
+import (
+  "fmt"
+  "strconv"
+)
+
+// HSETNX: Chỉ thiết lập field khi field chưa tồn tại
+func (c *Client) HSETNX(key string, field string, value string) (bool, error) {
+  n, err := c.integerReply("HSETNX", key, field, value)
+  return n == 1, err
+}
+
+// HMGET: Lấy giá trị của nhiều field ("" nếu field không tồn tại)
+func (c *Client) HMGET(key string, fields ...string) ([]string, error) {
+  return c.stringSliceReply(append([]string{"HMGET", key}, fields...)...)
+}
+
+// HGETALL: Lấy tất cả field-value trong Hash
+func (c *Client) HGETALL(key string) (map[string]string, error) {
+  values, err := c.stringSliceReply("HGETALL", key)
+  if err != nil {
+    return nil, err
+  }
+  if len(values)%2 != 0 {
+    return nil, fmt.Errorf("unexpected response length for HGETALL: %d", len(values))
+  }
+
+  result := make(map[string]string, len(values)/2)
+  for i := 0; i < len(values); i += 2 {
+    result[values[i]] = values[i+1]
+  }
+  return result, nil
+}
+
+// HDEL: Xóa các field, trả về số field đã xóa
+func (c *Client) HDEL(key string, fields ...string) (int, error) {
+  return c.integerReply(append([]string{"HDEL", key}, fields...)...)
+}
+
+// HEXISTS: Kiểm tra field có tồn tại hay không
+func (c *Client) HEXISTS(key string, field string) (bool, error) {
+  n, err := c.integerReply("HEXISTS", key, field)
+  return n == 1, err
+}
+
+// HLEN: Số field trong Hash
+func (c *Client) HLEN(key string) (int, error) {
+  return c.integerReply("HLEN", key)
+}
+
+// HKEYS: Lấy tất cả field của Hash
+func (c *Client) HKEYS(key string) ([]string, error) {
+  return c.stringSliceReply("HKEYS", key)
+}
+
+// HVALS: Lấy tất cả giá trị của Hash
+func (c *Client) HVALS(key string) ([]string, error) {
+  return c.stringSliceReply("HVALS", key)
+}
+
+// HINCRBY: Cộng increment vào field một cách nguyên tử, trả về giá trị mới
+func (c *Client) HINCRBY(key string, field string, increment int64) (int64, error) {
+  n, err := c.integerReply("HINCRBY", key, field, strconv.FormatInt(increment, 10))
+  return int64(n), err
+}
+
+// HINCRBYFLOAT: Cộng increment (số thực) vào field một cách nguyên tử, trả về giá trị mới
+func (c *Client) HINCRBYFLOAT(key string, field string, increment float64) (float64, error) {
+  value, _, err := c.bulkReply("HINCRBYFLOAT", key, field, formatFloat(increment))
+  if err != nil {
+    return 0, err
+  }
+  return strconv.ParseFloat(value, 64)
+}
+
+// HSCAN: Duyệt Hash theo từng đợt, trả về cursor kế tiếp ("0" khi đã xong) và các cặp field-value.
+// match rỗng nghĩa là không lọc, count <= 0 dùng giá trị mặc định của server.
+func (c *Client) HSCAN(key string, cursor string, match string, count int) (string, map[string]string, error) {
+  cmds := []string{"HSCAN", key, cursor}
+  if match != "" {
+    cmds = append(cmds, "MATCH", match)
+  }
+  if count > 0 {
+    cmds = append(cmds, "COUNT", strconv.Itoa(count))
+  }
+
+  response, err := c.executeCommand(cmds...)
+  if err != nil {
+    return "", nil, err
+  }
+  if response.Typ != "array" || len(response.Array) != 2 {
+    return "", nil, fmt.Errorf("unexpected response type for HSCAN: %s", response.Typ)
+  }
+
+  values, err := toStringSlice("HSCAN", response.Array[1])
+  if err != nil {
+    return "", nil, err
+  }
+
+  result := make(map[string]string, len(values)/2)
+  for i := 0; i+1 < len(values); i += 2 {
+    result[values[i]] = values[i+1]
+  }
+  return response.Array[0].Bulk, result, nil
+}
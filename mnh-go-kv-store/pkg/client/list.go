@@ -0,0 +1,127 @@
+package client
+
+import (
+  "fmt"
+  "strconv"
+  "time"
+)
+
+// LPUSH: Thêm các phần tử vào đầu List, trả về độ dài mới
+func (c *Client) LPUSH(key string, values ...string) (int, error) {
+  return c.integerReply(append([]string{"LPUSH", key}, values...)...)
+}
+
+// RPUSH: Thêm các phần tử vào cuối List, trả về độ dài mới
+func (c *Client) RPUSH(key string, values ...string) (int, error) {
+  return c.integerReply(append([]string{"RPUSH", key}, values...)...)
+}
+
+// LPUSHX: Giống LPUSH nhưng chỉ thực hiện khi List đã tồn tại
+func (c *Client) LPUSHX(key string, values ...string) (int, error) {
+  return c.integerReply(append([]string{"LPUSHX", key}, values...)...)
+}
+
+// RPUSHX: Giống RPUSH nhưng chỉ thực hiện khi List đã tồn tại
+func (c *Client) RPUSHX(key string, values ...string) (int, error) {
+  return c.integerReply(append([]string{"RPUSHX", key}, values...)...)
+}
+
+// LPOP: Lấy ra phần tử đầu List ("" nếu List rỗng hoặc không tồn tại)
+func (c *Client) LPOP(key string) (string, error) {
+  value, _, err := c.bulkReply("LPOP", key)
+  return value, err
+}
+
+// RPOP: Lấy ra phần tử cuối List ("" nếu List rỗng hoặc không tồn tại)
+func (c *Client) RPOP(key string) (string, error) {
+  value, _, err := c.bulkReply("RPOP", key)
+  return value, err
+}
+
+// LPOPCount: Lấy ra tối đa count phần tử từ đầu List
+func (c *Client) LPOPCount(key string, count int) ([]string, error) {
+  return c.stringSliceReply("LPOP", key, strconv.Itoa(count))
+}
+
+// RPOPCount: Lấy ra tối đa count phần tử từ cuối List
+func (c *Client) RPOPCount(key string, count int) ([]string, error) {
+  return c.stringSliceReply("RPOP", key, strconv.Itoa(count))
+}
+
+// LLEN: Độ dài của List
+func (c *Client) LLEN(key string) (int, error) {
+  return c.integerReply("LLEN", key)
+}
+
+// LRANGE: Lấy các phần tử trong khoảng [start, stop] (hỗ trợ chỉ số âm)
+func (c *Client) LRANGE(key string, start, stop int) ([]string, error) {
+  return c.stringSliceReply("LRANGE", key, strconv.Itoa(start), strconv.Itoa(stop))
+}
+
+// LINDEX: Lấy phần tử tại vị trí index, found = false nếu ngoài phạm vi
+func (c *Client) LINDEX(key string, index int) (string, bool, error) {
+  return c.bulkReply("LINDEX", key, strconv.Itoa(index))
+}
+
+// LSET: Ghi đè phần tử tại vị trí index
+func (c *Client) LSET(key string, index int, value string) error {
+  return c.okReply("LSET", key, strconv.Itoa(index), value)
+}
+
+// LTRIM: Chỉ giữ lại các phần tử trong khoảng [start, stop]
+func (c *Client) LTRIM(key string, start, stop int) error {
+  return c.okReply("LTRIM", key, strconv.Itoa(start), strconv.Itoa(stop))
+}
+
+// LINSERT: Chèn value trước (before = true) hoặc sau phần tử pivot
+func (c *Client) LINSERT(key string, before bool, pivot, value string) (int, error) {
+  where := "AFTER"
+  if before {
+    where = "BEFORE"
+  }
+  return c.integerReply("LINSERT", key, where, pivot, value)
+}
+
+// LREM: Xóa tối đa |count| phần tử bằng value (count = 0: xóa tất cả)
+func (c *Client) LREM(key string, count int, value string) (int, error) {
+  return c.integerReply("LREM", key, strconv.Itoa(count), value)
+}
+
+// LMOVE: Chuyển một phần tử từ src sang dst (from/to là "LEFT" hoặc "RIGHT")
+func (c *Client) LMOVE(src, dst, from, to string) (string, bool, error) {
+  return c.bulkReply("LMOVE", src, dst, from, to)
+}
+
+// formatTimeout chuyển Duration thành số giây (dạng số thực) cho các lệnh blocking
+func formatTimeout(timeout time.Duration) string {
+  return strconv.FormatFloat(timeout.Seconds(), 'f', -1, 64)
+}
+
+// blockingPop gửi BLPOP/BRPOP và giải mã phản hồi [key, value]
+func (c *Client) blockingPop(cmd string, timeout time.Duration, keys []string) (string, string, bool, error) {
+  cmds := append([]string{cmd}, keys...)
+  values, err := c.stringSliceReply(append(cmds, formatTimeout(timeout))...)
+  if err != nil || values == nil {
+    return "", "", false, err
+  }
+  if len(values) != 2 {
+    return "", "", false, fmt.Errorf("unexpected response length for %s: %d", cmd, len(values))
+  }
+  return values[0], values[1], true, nil
+}
+
+// BLPOP: Chờ tối đa timeout (0 = vô hạn) để lấy phần tử đầu của List đầu tiên có dữ liệu.
+// Trả về key và phần tử đã lấy, ok = false nếu hết thời gian chờ.
+func (c *Client) BLPOP(timeout time.Duration, keys ...string) (string, string, bool, error) {
+  return c.blockingPop("BLPOP", timeout, keys)
+}
+
+// BRPOP: Giống BLPOP nhưng lấy phần tử ở cuối List
+func (c *Client) BRPOP(timeout time.Duration, keys ...string) (string, string, bool, error) {
+  return c.blockingPop("BRPOP", timeout, keys)
+}
+
+// BLMOVE: Giống LMOVE nhưng chờ tối đa timeout nếu src đang rỗng
+func (c *Client) BLMOVE(src, dst, from, to string, timeout time.Duration) (string, bool, error) {
+  return c.bulkReply("BLMOVE", src, dst, from, to, formatTimeout(timeout))
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+  "fmt"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// Pipeline gom nhiều lệnh rồi gửi tất cả trong một lần ghi và đọc mọi phản hồi sau đó,
+// nên N lệnh chỉ tốn một vòng gửi/nhận thay vì N vòng.
+// Pipeline không an toàn khi dùng đồng thời, và không được gọi lệnh khác trên Client trong lúc Exec.
+type Pipeline struct {
+  c    *Client
+  cmds [][]string
+  err  error // Lỗi của lệnh không hợp lệ đã thêm bằng Queue, trả về bởi Exec
+}
+
+// Reply là phản hồi của một lệnh trong pipeline
+type Reply struct {
+  Cmd   string         // Tên lệnh
+  Value protocol.Value // Phản hồi của server
+  Err   error          // Lỗi server trả về cho riêng lệnh này (RESP Error)
+}
+
+// Pipeline tạo một pipeline mới trên kết nối của client
+func (c *Client) Pipeline() *Pipeline {
+  return &Pipeline{c: c}
+}
+
+// Queue thêm một lệnh vào pipeline, ví dụ p.Queue("SET", "key", "value").
+// Lệnh rỗng không được thêm; Exec khi đó trả về ErrEmptyCommand mà không gửi lệnh nào.
+func (p *Pipeline) Queue(cmds ...string) *Pipeline {
+  if len(cmds) == 0 {
+    if p.err == nil {
+      p.err = fmt.Errorf("command %d of the pipeline: %w", len(p.cmds)+1, ErrEmptyCommand)
+    }
+    return p
+  }
+  p.cmds = append(p.cmds, cmds)
+  return p
+}
+
+// Len trả về số lệnh đang chờ gửi
+func (p *Pipeline) Len() int {
+  return len(p.cmds)
+}
+
+// Exec gửi mọi lệnh đã thêm trong một lần ghi rồi đọc phản hồi theo đúng thứ tự.
+// Lỗi của từng lệnh nằm trong Reply.Err; lỗi trả về của Exec là lỗi kết nối, khi đó
+// các phản hồi đã đọc được vẫn được trả về và kết nối bị đóng. Nếu đã thêm một lệnh rỗng, Exec trả về
+// ErrEmptyCommand và không gửi lệnh nào. Sau Exec pipeline trống và có thể dùng lại.
+func (p *Pipeline) Exec() ([]Reply, error) {
+  cmds, err := p.cmds, p.err
+  p.cmds, p.err = nil, nil
+  if err != nil {
+    return nil, err
+  }
+  if len(cmds) == 0 {
+    return nil, nil
+  }
+
+  // Mã hóa mọi lệnh vào buffer dùng lại của client để gửi trong một lần ghi
+  buf := p.c.buf[:0]
+  for _, cmd := range cmds {
+    buf = protocol.AppendCommand(buf, cmd)
+  }
+  p.c.buf = buf
+
+  // Ghi trong Goroutine riêng và đọc phản hồi song song: với pipeline lớn, server gửi phản hồi
+  // trong lúc vẫn đang nhận lệnh, nên nếu chỉ đọc sau khi ghi xong thì cả hai bên cùng chờ nhau
+  writeErr := make(chan error, 1)
+  go func() {
+    _, err := p.c.conn.Write(buf)
+    writeErr <- err
+  }()
+
+  replies := make([]Reply, 0, len(cmds))
+  for _, cmd := range cmds {
+    response, _, err := p.c.resp.Read()
+    if err != nil {
+      // Kết nối không còn dùng được: đóng để Goroutine ghi kết thúc trước khi trả về
+      p.c.conn.Close()
+      if werr := <-writeErr; werr != nil {
+        return replies, fmt.Errorf("failed to write pipeline: %w", werr)
+      }
+      return replies, fmt.Errorf("failed to read response: %w", err)
+    }
+
+    reply := Reply{Cmd: cmd[0], Value: response}
+    if response.Typ == "error" {
+      reply.Err = fmt.Errorf("server error: %s", response.Str)
+    }
+    replies = append(replies, reply)
+  }
+  if err := <-writeErr; err != nil {
+    return replies, fmt.Errorf("failed to write pipeline: %w", err)
+  }
+  return replies, nil
+}
+
+// Int trả về phản hồi dạng Integer
+func (r Reply) Int() (int, error) {
+  if r.Err != nil {
+    return 0, r.Err
+  }
+  return toInteger(r.Cmd, r.Value)
+}
+
+// Bulk trả về phản hồi dạng Bulk String ("" và found = false nếu Null)
+func (r Reply) Bulk() (string, bool, error) {
+  if r.Err != nil {
+    return "", false, r.Err
+  }
+  return toBulk(r.Cmd, r.Value)
+}
+
+// OK kiểm tra phản hồi là Simple String "OK"
+func (r Reply) OK() error {
+  if r.Err != nil {
+    return r.Err
+  }
+  return toOK(r.Cmd, r.Value)
+}
+
+// Strings trả về phản hồi dạng Array các Bulk String (nil nếu Null)
+func (r Reply) Strings() ([]string, error) {
+  if r.Err != nil {
+    return nil, r.Err
+  }
+  return toStringSlice(r.Cmd, r.Value)
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+  "errors"
+  "net"
+  "testing"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// pipeClient tạo Client nối với server giả qua net.Pipe; server trả lời "+OK" cho mỗi lệnh nhận được
+func pipeClient(t *testing.T) *Client {
+  t.Helper()
+  conn, server := net.Pipe()
+  t.Cleanup(func() { conn.Close(); server.Close() })
+  go func() {
+    resp := protocol.NewResp(server)
+    for {
+      if _, _, err := resp.Read(); err != nil {
+        return
+      }
+      if _, err := server.Write([]byte("+OK\r\n")); err != nil {
+        return
+      }
+    }
+  }()
+  return &Client{conn: conn, resp: protocol.NewResp(conn)}
+}
+
+func TestPipelineRejectsEmptyCommand(t *testing.T) {
+  c := pipeClient(t)
+  p := c.Pipeline().Queue("SET", "a", "1").Queue().Queue("GET", "a")
+  replies, err := p.Exec()
+  if !errors.Is(err, ErrEmptyCommand) {
+    t.Fatalf("Exec() error = %v, want ErrEmptyCommand", err)
+  }
+  if replies != nil || p.Len() != 0 {
+    t.Fatalf("Exec() sent %d commands, left %d queued", len(replies), p.Len())
+  }
+
+  // Pipeline vẫn dùng được sau lỗi
+  replies, err = p.Queue("PING").Exec()
+  if err != nil || len(replies) != 1 || replies[0].OK() != nil {
+    t.Fatalf("Exec() after the error = %+v, %v", replies, err)
+  }
+}
+
+func TestDoRejectsEmptyCommand(t *testing.T) {
+  c := pipeClient(t)
+  if _, err := c.Do(); !errors.Is(err, ErrEmptyCommand) {
+    t.Fatalf("Do() error = %v, want ErrEmptyCommand", err)
+  }
+  if reply, err := c.Do("PING"); err != nil || reply.Str != "OK" {
+    t.Fatalf("Do(PING) = %+v, %v", reply, err)
+  }
+}
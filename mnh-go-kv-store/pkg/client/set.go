@@ -0,0 +1,91 @@
+package client
+
+import (
+  "fmt"
+  "strconv"
+)
+
+// SADD: Thêm các phần tử vào Set, trả về số phần tử mới được thêm
+func (c *Client) SADD(key string, members ...string) (int, error) {
+  return c.integerReply(append([]string{"SADD", key}, members...)...)
+}
+
+// SREM: Xóa các phần tử khỏi Set, trả về số phần tử đã xóa
+func (c *Client) SREM(key string, members ...string) (int, error) {
+  return c.integerReply(append([]string{"SREM", key}, members...)...)
+}
+
+// SMEMBERS: Lấy tất cả phần tử của Set
+func (c *Client) SMEMBERS(key string) ([]string, error) {
+  return c.stringSliceReply("SMEMBERS", key)
+}
+
+// SISMEMBER: Kiểm tra phần tử có thuộc Set hay không
+func (c *Client) SISMEMBER(key string, member string) (bool, error) {
+  n, err := c.integerReply("SISMEMBER", key, member)
+  return n == 1, err
+}
+
+// SMISMEMBER: Kiểm tra nhiều phần tử cùng lúc
+func (c *Client) SMISMEMBER(key string, members ...string) ([]bool, error) {
+  cmds := append([]string{"SMISMEMBER", key}, members...)
+  response, err := c.executeCommand(cmds...)
+  if err != nil {
+    return nil, err
+  }
+
+  if response.Typ != "array" {
+    return nil, fmt.Errorf("unexpected response type for SMISMEMBER: %s", response.Typ)
+  }
+
+  results := make([]bool, len(response.Array))
+  for i, item := range response.Array {
+    results[i] = item.Num == 1
+  }
+  return results, nil
+}
+
+// SCARD: Số phần tử của Set
+func (c *Client) SCARD(key string) (int, error) {
+  return c.integerReply("SCARD", key)
+}
+
+// SPOP: Lấy ngẫu nhiên và xóa tối đa count phần tử khỏi Set
+func (c *Client) SPOP(key string, count int) ([]string, error) {
+  return c.stringSliceReply("SPOP", key, strconv.Itoa(count))
+}
+
+// SRANDMEMBER: Lấy ngẫu nhiên các phần tử mà không xóa (count < 0 cho phép lặp lại)
+func (c *Client) SRANDMEMBER(key string, count int) ([]string, error) {
+  return c.stringSliceReply("SRANDMEMBER", key, strconv.Itoa(count))
+}
+
+// SINTER: Giao của các Set
+func (c *Client) SINTER(keys ...string) ([]string, error) {
+  return c.stringSliceReply(append([]string{"SINTER"}, keys...)...)
+}
+
+// SUNION: Hợp của các Set
+func (c *Client) SUNION(keys ...string) ([]string, error) {
+  return c.stringSliceReply(append([]string{"SUNION"}, keys...)...)
+}
+
+// SDIFF: Hiệu của Set đầu tiên với các Set còn lại
+func (c *Client) SDIFF(keys ...string) ([]string, error) {
+  return c.stringSliceReply(append([]string{"SDIFF"}, keys...)...)
+}
+
+// SINTERSTORE: Lưu giao của các Set vào dst, trả về số phần tử
+func (c *Client) SINTERSTORE(dst string, keys ...string) (int, error) {
+  return c.integerReply(append([]string{"SINTERSTORE", dst}, keys...)...)
+}
+
+// SUNIONSTORE: Lưu hợp của các Set vào dst, trả về số phần tử
+func (c *Client) SUNIONSTORE(dst string, keys ...string) (int, error) {
+  return c.integerReply(append([]string{"SUNIONSTORE", dst}, keys...)...)
+}
+
+// SDIFFSTORE: Lưu hiệu của các Set vào dst, trả về số phần tử
+func (c *Client) SDIFFSTORE(dst string, keys ...string) (int, error) {
+  return c.integerReply(append([]string{"SDIFFSTORE", dst}, keys...)...)
+}
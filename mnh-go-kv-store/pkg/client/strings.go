@@ -0,0 +1,134 @@
+package client
+
+import (
+  "fmt"
+  "strconv"
+  "time"
+)
+
+// INCR: Tăng giá trị số nguyên của key lên 1, trả về giá trị mới
+func (c *Client) INCR(key string) (int64, error) {
+  n, err := c.integerReply("INCR", key)
+  return int64(n), err
+}
+
+// INCRBY: Cộng delta vào giá trị số nguyên của key
+func (c *Client) INCRBY(key string, delta int64) (int64, error) {
+  n, err := c.integerReply("INCRBY", key, strconv.FormatInt(delta, 10))
+  return int64(n), err
+}
+
+// DECR: Giảm giá trị số nguyên của key đi 1
+func (c *Client) DECR(key string) (int64, error) {
+  n, err := c.integerReply("DECR", key)
+  return int64(n), err
+}
+
+// DECRBY: Trừ delta khỏi giá trị số nguyên của key
+func (c *Client) DECRBY(key string, delta int64) (int64, error) {
+  n, err := c.integerReply("DECRBY", key, strconv.FormatInt(delta, 10))
+  return int64(n), err
+}
+
+// INCRBYFLOAT: Cộng delta (số thực) vào giá trị của key
+func (c *Client) INCRBYFLOAT(key string, delta float64) (float64, error) {
+  value, _, err := c.bulkReply("INCRBYFLOAT", key, formatFloat(delta))
+  if err != nil {
+    return 0, err
+  }
+  return strconv.ParseFloat(value, 64)
+}
+
+// APPEND: Nối value vào cuối giá trị của key, trả về độ dài mới
+func (c *Client) APPEND(key string, value string) (int, error) {
+  return c.integerReply("APPEND", key, value)
+}
+
+// STRLEN: Độ dài giá trị của key
+func (c *Client) STRLEN(key string) (int, error) {
+  return c.integerReply("STRLEN", key)
+}
+
+// GETRANGE: Lấy chuỗi con trong khoảng [start, end] (hỗ trợ chỉ số âm)
+func (c *Client) GETRANGE(key string, start, end int) (string, error) {
+  value, _, err := c.bulkReply("GETRANGE", key, strconv.Itoa(start), strconv.Itoa(end))
+  return value, err
+}
+
+// SETRANGE: Ghi đè value bắt đầu từ offset, trả về độ dài mới
+func (c *Client) SETRANGE(key string, offset int, value string) (int, error) {
+  return c.integerReply("SETRANGE", key, strconv.Itoa(offset), value)
+}
+
+// GETDEL: Lấy giá trị của key rồi xóa key, found = false nếu key không tồn tại
+func (c *Client) GETDEL(key string) (string, bool, error) {
+  return c.bulkReply("GETDEL", key)
+}
+
+// GETEX: Lấy giá trị của key và đặt lại TTL. ttl > 0: hết hạn sau ttl; ttl < 0: xóa TTL (PERSIST);
+// ttl = 0: giữ nguyên TTL hiện tại.
+func (c *Client) GETEX(key string, ttl time.Duration) (string, bool, error) {
+  cmds := []string{"GETEX", key}
+  switch {
+  case ttl > 0:
+    cmds = append(cmds, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+  case ttl < 0:
+    cmds = append(cmds, "PERSIST")
+  }
+  return c.bulkReply(cmds...)
+}
+
+// SetArgs là các tùy chọn của lệnh SET (dùng cho SETArgs)
+type SetArgs struct {
+  NX       bool          // Chỉ ghi khi key chưa tồn tại
+  XX       bool          // Chỉ ghi khi key đã tồn tại
+  Get      bool          // Trả về giá trị cũ
+  KeepTTL  bool          // Giữ nguyên TTL hiện tại
+  TTL      time.Duration // Hết hạn sau TTL (gửi dạng PX)
+  ExpireAt time.Time     // Hết hạn tại thời điểm tuyệt đối (gửi dạng PXAT)
+}
+
+// SETArgs: SET với đầy đủ tùy chọn.
+// Khi Get = true, trả về giá trị cũ (ok = false nếu key chưa tồn tại);
+// ngược lại ok cho biết lệnh có được áp dụng hay bị bỏ qua do NX/XX.
+func (c *Client) SETArgs(key string, value string, args SetArgs) (string, bool, error) {
+  cmds := []string{"SET", key, value}
+  if args.NX {
+    cmds = append(cmds, "NX")
+  }
+  if args.XX {
+    cmds = append(cmds, "XX")
+  }
+  if args.Get {
+    cmds = append(cmds, "GET")
+  }
+  switch {
+  case args.KeepTTL:
+    cmds = append(cmds, "KEEPTTL")
+  case args.TTL > 0:
+    cmds = append(cmds, "PX", strconv.FormatInt(args.TTL.Milliseconds(), 10))
+  case !args.ExpireAt.IsZero():
+    cmds = append(cmds, "PXAT", strconv.FormatInt(args.ExpireAt.UnixMilli(), 10))
+  }
+
+  response, err := c.executeCommand(cmds...)
+  if err != nil {
+    return "", false, err
+  }
+
+  switch response.Typ {
+  case "null":
+    return "", false, nil
+  case "bulk":
+    return response.Bulk, true, nil
+  case "string":
+    return response.Str, true, nil
+  }
+  return "", false, fmt.Errorf("unexpected response type for SET: %s", response.Typ)
+}
+
+// SETNX: Chỉ ghi khi key chưa tồn tại, trả về true nếu đã ghi
+func (c *Client) SETNX(key string, value string) (bool, error) {
+  n, err := c.integerReply("SETNX", key, value)
+  return n == 1, err
+}
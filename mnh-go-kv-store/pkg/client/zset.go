@@ -0,0 +1,164 @@
+package client
+
+import (
+  "fmt"
+  "strconv"
+)
+
+// Z là một phần tử của Sorted Set cùng score của nó
+type Z struct {
+  Score  float64
+  Member string
+}
+
+// formatFloat định dạng số thực để gửi lên server
+func formatFloat(f float64) string {
+  return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// parseScoredMembers giải mã Array dạng [member, score, member, score, ...]
+func parseScoredMembers(cmd string, values []string) ([]Z, error) {
+  if len(values)%2 != 0 {
+    return nil, fmt.Errorf("unexpected response length for %s: %d", cmd, len(values))
+  }
+
+  result := make([]Z, 0, len(values)/2)
+  for i := 0; i < len(values); i += 2 {
+    score, err := strconv.ParseFloat(values[i+1], 64)
+    if err != nil {
+      return nil, fmt.Errorf("invalid score in %s response: %w", cmd, err)
+    }
+    result = append(result, Z{Score: score, Member: values[i]})
+  }
+  return result, nil
+}
+
+// scoredMembersReply thực thi lệnh có WITHSCORES và giải mã kết quả thành []Z
+func (c *Client) scoredMembersReply(cmds ...string) ([]Z, error) {
+  values, err := c.stringSliceReply(cmds...)
+  if err != nil {
+    return nil, err
+  }
+  return parseScoredMembers(cmds[0], values)
+}
+
+// ZADD: Thêm/cập nhật các phần tử, trả về số phần tử mới được thêm
+func (c *Client) ZADD(key string, members ...Z) (int, error) {
+  cmds := []string{"ZADD", key}
+  for _, m := range members {
+    cmds = append(cmds, formatFloat(m.Score), m.Member)
+  }
+  return c.integerReply(cmds...)
+}
+
+// ZINCRBY: Cộng increment vào score của member, trả về score mới
+func (c *Client) ZINCRBY(key string, increment float64, member string) (float64, error) {
+  value, _, err := c.bulkReply("ZINCRBY", key, formatFloat(increment), member)
+  if err != nil {
+    return 0, err
+  }
+  return strconv.ParseFloat(value, 64)
+}
+
+// ZREM: Xóa các phần tử, trả về số phần tử đã xóa
+func (c *Client) ZREM(key string, members ...string) (int, error) {
+  return c.integerReply(append([]string{"ZREM", key}, members...)...)
+}
+
+// ZSCORE: Lấy score của member, found = false nếu không tồn tại
+func (c *Client) ZSCORE(key string, member string) (float64, bool, error) {
+  value, found, err := c.bulkReply("ZSCORE", key, member)
+  if err != nil || !found {
+    return 0, found, err
+  }
+  score, err := strconv.ParseFloat(value, 64)
+  return score, true, err
+}
+
+// ZCARD: Số phần tử của Sorted Set
+func (c *Client) ZCARD(key string) (int, error) {
+  return c.integerReply("ZCARD", key)
+}
+
+// rankReply giải mã phản hồi của ZRANK/ZREVRANK (Null nếu member không tồn tại)
+func (c *Client) rankReply(cmds ...string) (int, bool, error) {
+  response, err := c.executeCommand(cmds...)
+  if err != nil {
+    return 0, false, err
+  }
+
+  if response.Typ == "null" {
+    return 0, false, nil
+  }
+  if response.Typ != "integer" {
+    return 0, false, fmt.Errorf("unexpected response type for %s: %s", cmds[0], response.Typ)
+  }
+  return response.Num, true, nil
+}
+
+// ZRANK: Thứ hạng (từ 0, score tăng dần) của member
+func (c *Client) ZRANK(key string, member string) (int, bool, error) {
+  return c.rankReply("ZRANK", key, member)
+}
+
+// ZREVRANK: Thứ hạng (từ 0, score giảm dần) của member
+func (c *Client) ZREVRANK(key string, member string) (int, bool, error) {
+  return c.rankReply("ZREVRANK", key, member)
+}
+
+// ZRANGE: Lấy các member theo thứ hạng trong khoảng [start, stop]
+func (c *Client) ZRANGE(key string, start, stop int) ([]string, error) {
+  return c.stringSliceReply("ZRANGE", key, strconv.Itoa(start), strconv.Itoa(stop))
+}
+
+// ZRANGEWithScores: Giống ZRANGE nhưng trả về kèm score
+func (c *Client) ZRANGEWithScores(key string, start, stop int) ([]Z, error) {
+  return c.scoredMembersReply("ZRANGE", key, strconv.Itoa(start), strconv.Itoa(stop), "WITHSCORES")
+}
+
+// ZREVRANGE: Lấy các member theo thứ hạng giảm dần trong khoảng [start, stop]
+func (c *Client) ZREVRANGE(key string, start, stop int) ([]string, error) {
+  return c.stringSliceReply("ZRANGE", key, strconv.Itoa(start), strconv.Itoa(stop), "REV")
+}
+
+// ZRANGEBYSCORE: Lấy các member có score trong khoảng [min, max].
+// min/max hỗ trợ cú pháp của Redis: "(1", "-inf", "+inf".
+func (c *Client) ZRANGEBYSCORE(key string, min, max string) ([]string, error) {
+  return c.stringSliceReply("ZRANGE", key, min, max, "BYSCORE")
+}
+
+// ZRANGEBYSCOREWithScores: Giống ZRANGEBYSCORE, kèm score và giới hạn offset/count (count < 0: không giới hạn)
+func (c *Client) ZRANGEBYSCOREWithScores(key string, min, max string, offset, count int) ([]Z, error) {
+  return c.scoredMembersReply("ZRANGE", key, min, max, "BYSCORE",
+    "LIMIT", strconv.Itoa(offset), strconv.Itoa(count), "WITHSCORES")
+}
+
+// ZRANGEBYLEX: Lấy các member trong khoảng từ điển [min, max] ("[a", "(a", "-", "+")
+func (c *Client) ZRANGEBYLEX(key string, min, max string) ([]string, error) {
+  return c.stringSliceReply("ZRANGE", key, min, max, "BYLEX")
+}
+
+// ZCOUNT: Số phần tử có score trong khoảng [min, max]
+func (c *Client) ZCOUNT(key string, min, max string) (int, error) {
+  return c.integerReply("ZCOUNT", key, min, max)
+}
+
+// ZPOPMIN: Lấy ra và xóa tối đa count phần tử có score nhỏ nhất
+func (c *Client) ZPOPMIN(key string, count int) ([]Z, error) {
+  return c.scoredMembersReply("ZPOPMIN", key, strconv.Itoa(count))
+}
+
+// ZPOPMAX: Lấy ra và xóa tối đa count phần tử có score lớn nhất
+func (c *Client) ZPOPMAX(key string, count int) ([]Z, error) {
+  return c.scoredMembersReply("ZPOPMAX", key, strconv.Itoa(count))
+}
+
+// ZREMRANGEBYRANK: Xóa các phần tử có thứ hạng trong khoảng [start, stop]
+func (c *Client) ZREMRANGEBYRANK(key string, start, stop int) (int, error) {
+  return c.integerReply("ZREMRANGEBYRANK", key, strconv.Itoa(start), strconv.Itoa(stop))
+}
+
+// ZREMRANGEBYSCORE: Xóa các phần tử có score trong khoảng [min, max]
+func (c *Client) ZREMRANGEBYSCORE(key string, min, max string) (int, error) {
+  return c.integerReply("ZREMRANGEBYSCORE", key, min, max)
+}
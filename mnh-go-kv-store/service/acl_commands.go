@@ -0,0 +1,173 @@
+package service
+
+import (
+  "crypto/rand"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "strconv"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/acl"
+  "mnhgo/mnh-go-kv-store/internal/logging"
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// Lỗi xác thực (giống Redis)
+var (
+  errNoAuth    = errors.New("NOAUTH Authentication required.")
+  errNoPassSet = errors.New("ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+  errNoACLFile = errors.New("ERR This server is not configured to use an ACL file. Set the aclfile configuration parameter to load and save users.")
+)
+
+// EnableACLFile đặt file ACL cho ACL LOAD/SAVE và nạp các user trong file
+func (h *CommandsHandler) EnableACLFile(path string) error {
+  h.acl.SetFile(path)
+  return h.acl.Load()
+}
+
+// initialUser trả về user của kết nối mới: user default nếu nó không cần mật khẩu,
+// "" nếu kết nối phải xác thực bằng AUTH hoặc HELLO trước
+func (h *CommandsHandler) initialUser() string {
+  if h.acl.NoPass(acl.DefaultUser) {
+    return acl.DefaultUser
+  }
+  return ""
+}
+
+// authenticate kiểm tra username/password của AUTH hoặc HELLO AUTH. AUTH password (username rỗng)
+// xác thực user default và là lỗi nếu user default không cần mật khẩu (giống Redis).
+func (h *CommandsHandler) authenticate(username, password string) (string, error) {
+  if username == "" {
+    if h.acl.NoPass(acl.DefaultUser) {
+      return "", errNoPassSet
+    }
+    username = acl.DefaultUser
+  }
+  if err := h.acl.Authenticate(username, password); err != nil {
+    return "", err
+  }
+  return username, nil
+}
+
+// handleACL: ACL SETUSER|GETUSER|DELUSER|LIST|USERS|WHOAMI|CAT|LOAD|SAVE|GENPASS ...,
+// user là user của kết nối gửi lệnh (cho ACL WHOAMI)
+func (h *CommandsHandler) handleACL(args []protocol.Value, user string) []byte {
+  if len(args) == 0 {
+    return wrongArgsReply("acl")
+  }
+
+  sub := strings.ToUpper(args[0].Bulk)
+  params := argStrings(args[1:])
+  switch sub {
+  case "SETUSER":
+    if len(params) == 0 {
+      return wrongArgsReply("acl|setuser")
+    }
+    if err := h.acl.SetUser(params[0], params[1:]...); err != nil {
+      return errorReply(err.Error())
+    }
+    return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+
+  case "GETUSER":
+    if len(params) != 1 {
+      return wrongArgsReply("acl|getuser")
+    }
+    info, ok := h.acl.GetUser(params[0])
+    if !ok {
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    return protocol.Value{Typ: "map", Array: []protocol.Value{
+      {Typ: "bulk", Bulk: "flags"}, {Typ: "array", Array: bulkArray(info.Flags)},
+      {Typ: "bulk", Bulk: "passwords"}, {Typ: "array", Array: bulkArray(info.Passwords)},
+      {Typ: "bulk", Bulk: "commands"}, {Typ: "bulk", Bulk: info.Commands},
+      {Typ: "bulk", Bulk: "keys"}, {Typ: "bulk", Bulk: info.Keys},
+    }}.Marshal()
+
+  case "DELUSER":
+    if len(params) == 0 {
+      return wrongArgsReply("acl|deluser")
+    }
+    deleted, err := h.acl.DelUser(params...)
+    if err != nil {
+      return errorReply(err.Error())
+    }
+    return protocol.Value{Typ: "integer", Num: deleted}.Marshal()
+
+  case "LIST", "USERS", "WHOAMI", "SAVE", "LOAD":
+    if len(params) != 0 {
+      return wrongArgsReply("acl|" + strings.ToLower(sub))
+    }
+    switch sub {
+    case "LIST":
+      return bulkArrayReply(h.acl.List())
+    case "USERS":
+      return bulkArrayReply(h.acl.Users())
+    case "WHOAMI":
+      return protocol.Value{Typ: "bulk", Bulk: user}.Marshal()
+    case "SAVE":
+      return h.saveACL()
+    default:
+      return h.loadACL()
+    }
+
+  case "CAT":
+    if len(params) > 1 {
+      return wrongArgsReply("acl|cat")
+    }
+    if len(params) == 0 {
+      return bulkArrayReply(h.acl.Categories())
+    }
+    names, ok := h.acl.CommandsInCategory(params[0])
+    if !ok {
+      return errorReply(fmt.Sprintf("ERR Unknown category '%s'", params[0]))
+    }
+    return bulkArrayReply(names)
+
+  case "GENPASS":
+    if len(params) > 1 {
+      return wrongArgsReply("acl|genpass")
+    }
+    bits := 256
+    if len(params) == 1 {
+      n, err := strconv.Atoi(params[0])
+      if err != nil || n <= 0 || n > 4096 {
+        return errorReply("ERR ACL GENPASS argument must be the number of bits for the output password, a positive number up to 4096")
+      }
+      bits = n
+    }
+    // Mỗi ký tự hex mang 4 bit: sinh đủ byte rồi cắt theo số ký tự cần
+    chars := (bits + 3) / 4
+    buf := make([]byte, (chars+1)/2)
+    if _, err := rand.Read(buf); err != nil {
+      return errorReply("ERR " + err.Error())
+    }
+    return protocol.Value{Typ: "bulk", Bulk: hex.EncodeToString(buf)[:chars]}.Marshal()
+  }
+  return errorReply("ERR unknown subcommand '" + args[0].Bulk + "'. Try ACL HELP.")
+}
+
+// saveACL: ACL SAVE, ghi các user ra file ACL
+func (h *CommandsHandler) saveACL() []byte {
+  if h.acl.File() == "" {
+    return errorReply(errNoACLFile.Error())
+  }
+  if err := h.acl.Save(); err != nil {
+    logging.Warningf("Saving the ACL file %s failed: %v", h.acl.File(), err)
+    return errorReply("ERR There was an error trying to save the ACLs. Please check the server logs for more information")
+  }
+  logging.Noticef("ACL file %s saved", h.acl.File())
+  return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+}
+
+// loadACL: ACL LOAD, nạp lại các user từ file ACL (giữ nguyên các user hiện tại nếu file có lỗi)
+func (h *CommandsHandler) loadACL() []byte {
+  if h.acl.File() == "" {
+    return errorReply(errNoACLFile.Error())
+  }
+  if err := h.acl.Load(); err != nil {
+    return errorReply("ERR Error loading ACLs: " + err.Error())
+  }
+  logging.Noticef("ACL file %s loaded", h.acl.File())
+  return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+  "mnhgo/mnh-go-kv-store/pkg/client"
+)
+
+// startACLServer chạy server TCP với handler, trả về địa chỉ của server
+func startACLServer(t *testing.T, handler *CommandsHandler) string {
+  t.Helper()
+  _, addr := startServer(t, handler, func(s *Server) error {
+    return s.Listen("127.0.0.1:0")
+  })
+  return addr
+}
+
+// replyText trả về nội dung của phản hồi dạng chuỗi (simple string, error hoặc bulk string)
+func replyText(v protocol.Value) string {
+  if v.Typ == "bulk" {
+    return v.Bulk
+  }
+  return v.Str
+}
+
+// expectReply gửi lệnh và kiểm tra phản hồi bắt đầu bằng want
+func expectReply(t *testing.T, c *client.Client, want string, cmds ...string) {
+  t.Helper()
+  reply, err := c.Do(cmds...)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := replyText(reply); !strings.HasPrefix(got, want) {
+    t.Errorf("%v = %s %q, want %q", cmds, reply.Typ, got, want)
+  }
+}
+
+func TestAUTH(t *testing.T) {
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  handler.acl.SetDefaultPassword("secret")
+  if err := handler.acl.SetUser("alice", "on", ">pw", "~*", "+@all"); err != nil {
+    t.Fatal(err)
+  }
+  addr := startACLServer(t, handler)
+
+  tests := []struct {
+    name   string
+    auth   []string // nil: không xác thực
+    reply  string   // Phản hồi của lệnh xác thực
+    whoami string   // Phản hồi của ACL WHOAMI sau đó
+  }{
+    {"not authenticated", nil, "", "NOAUTH"},
+    {"default user", []string{"AUTH", "secret"}, "OK", "default"},
+    {"named user", []string{"AUTH", "alice", "pw"}, "OK", "alice"},
+    {"default user by name", []string{"AUTH", "default", "secret"}, "OK", "default"},
+    {"wrong password", []string{"AUTH", "wrong"}, "WRONGPASS", "NOAUTH"},
+    {"wrong user password", []string{"AUTH", "alice", "secret"}, "WRONGPASS", "NOAUTH"},
+    {"unknown user", []string{"AUTH", "bob", "pw"}, "WRONGPASS", "NOAUTH"},
+    {"hello auth", []string{"HELLO", "2", "AUTH", "alice", "pw"}, "", "alice"},
+    {"hello wrong password", []string{"HELLO", "3", "AUTH", "alice", "wrong"}, "WRONGPASS", "NOAUTH"},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      c := dial(t, addr)
+      if tt.auth != nil {
+        expectReply(t, c, tt.reply, tt.auth...)
+      }
+      expectReply(t, c, tt.whoami, "ACL", "WHOAMI")
+    })
+  }
+}
+
+func TestAUTHWithoutPassword(t *testing.T) {
+  c := dial(t, startACLServer(t, NewCommandsHandler(store.NewStore(), nil)))
+  // User default không cần mật khẩu: AUTH password là lỗi, kết nối vẫn được xác thực sẵn
+  expectReply(t, c, errNoPassSet.Error(), "AUTH", "secret")
+  expectReply(t, c, "OK", "AUTH", "default", "anything")
+  expectReply(t, c, "default", "ACL", "WHOAMI")
+}
+
+func TestACLPermissions(t *testing.T) {
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  if err := handler.acl.SetUser("alice", "on", ">pw", "~app:*", "+@all", "-@list", "-append"); err != nil {
+    t.Fatal(err)
+  }
+  addr := startACLServer(t, handler)
+  c, err := client.NewClient(addr, client.WithAuth("alice", "pw"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer c.Close()
+
+  tests := []struct {
+    cmd  []string
+    want string
+  }{
+    {[]string{"SET", "app:1", "v"}, "OK"},
+    {[]string{"GET", "app:1"}, "v"},
+    {[]string{"APPEND", "app:1", "x"}, "NOPERM User alice has no permissions to run the 'append' command"},
+    {[]string{"LPUSH", "app:list", "x"}, "NOPERM User alice has no permissions to run the 'lpush' command"},
+    {[]string{"GET", "other"}, "NOPERM No permissions to access a key"},
+    {[]string{"SET", "other", "v"}, "NOPERM No permissions to access a key"},
+    // Mọi key của lệnh nhiều key đều được kiểm tra theo vị trí key trong commandSpecs
+    {[]string{"DEL", "app:1", "other"}, "NOPERM No permissions to access a key"},
+    {[]string{"SUNIONSTORE", "app:dst", "app:a", "other"}, "NOPERM No permissions to access a key"},
+    {[]string{"EXISTS", "app:1", "app:2", "other"}, "NOPERM No permissions to access a key"},
+    {[]string{"ACL", "WHOAMI"}, "alice"},
+  }
+  for _, tt := range tests {
+    expectReply(t, c, tt.want, tt.cmd...)
+  }
+  // Lệnh bị từ chối không được thực thi
+  if value, ok := handler.store.GET("app:1"); !ok || value != "v" {
+    t.Errorf("app:1 = %q, %v after the denied commands", value, ok)
+  }
+}
+
+func TestACLSetUserDelUser(t *testing.T) {
+  addr := startACLServer(t, NewCommandsHandler(store.NewStore(), nil))
+  admin := dial(t, addr)
+  expectReply(t, admin, "OK", "ACL", "SETUSER", "bob", "on", ">pw", "~*", "+@read")
+  expectReply(t, admin, "ERR", "ACL", "SETUSER", "bob", "+nosuchcommand")
+
+  bob := dial(t, addr)
+  expectReply(t, bob, "WRONGPASS", "AUTH", "bob", "wrong")
+  expectReply(t, bob, "OK", "AUTH", "bob", "pw")
+  expectReply(t, bob, "NOPERM", "SET", "key", "value")
+
+  // Quyền mới có hiệu lực ngay với kết nối đã xác thực
+  expectReply(t, admin, "OK", "ACL", "SETUSER", "bob", "+set")
+  expectReply(t, bob, "OK", "SET", "key", "value")
+
+  reply, err := admin.Do("ACL", "DELUSER", "bob", "nobody")
+  if err != nil || reply.Typ != "integer" || reply.Num != 1 {
+    t.Fatalf("ACL DELUSER = %+v, %v, want 1", reply, err)
+  }
+  expectReply(t, admin, "ERR The 'default' user cannot be removed", "ACL", "DELUSER", "default")
+  // Kết nối của user đã bị xóa phải xác thực lại
+  expectReply(t, bob, "NOAUTH", "GET", "key")
+  expectReply(t, bob, "WRONGPASS", "AUTH", "bob", "pw")
+}
+
+func TestACLSaveLoad(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "users.acl")
+  if err := os.WriteFile(path, []byte("# users\nuser default on nopass ~* +@all\n"), 0600); err != nil {
+    t.Fatal(err)
+  }
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  if err := handler.EnableACLFile(path); err != nil {
+    t.Fatal(err)
+  }
+  c := dial(t, startACLServer(t, handler))
+
+  expectReply(t, c, "OK", "ACL", "SETUSER", "carol", "on", ">pw", "~cache:*", "+get")
+  expectReply(t, c, "OK", "ACL", "SAVE")
+  saved := handler.acl.List()
+
+  // ACL LOAD thay các user hiện tại bằng các user đã lưu
+  expectReply(t, c, "OK", "ACL", "SETUSER", "dave", "on", "nopass")
+  expectReply(t, c, "OK", "ACL", "SETUSER", "carol", "off")
+  expectReply(t, c, "OK", "ACL", "LOAD")
+  if got := handler.acl.List(); strings.Join(got, "\n") != strings.Join(saved, "\n") {
+    t.Fatalf("ACL LIST after ACL LOAD = %q, want %q", got, saved)
+  }
+
+  carol := dial(t, startACLServer(t, handler))
+  expectReply(t, carol, "OK", "AUTH", "carol", "pw")
+  expectReply(t, carol, "NOPERM", "GET", "other")
+
+  // File có lỗi không làm mất các user hiện tại
+  if err := os.WriteFile(path, []byte("user broken +nosuchcommand\n"), 0600); err != nil {
+    t.Fatal(err)
+  }
+  expectReply(t, c, "ERR Error loading ACLs", "ACL", "LOAD")
+  if got := handler.acl.Users(); strings.Join(got, " ") != "carol default" {
+    t.Errorf("ACL USERS after a failed ACL LOAD = %q", got)
+  }
+}
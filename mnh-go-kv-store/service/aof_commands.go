@@ -0,0 +1,94 @@
+package service
+
+import (
+  "errors"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/logging"
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// autoRewriteInterval là chu kỳ kiểm tra kích thước AOF để tự động ghi lại
+const autoRewriteInterval = time.Second
+
+// rewriteAOF bắt đầu ghi lại AOF trong nền từ snapshot hiện tại của Store.
+// Gate được giữ ở chế độ ghi khi lấy snapshot, nên không có lệnh nào đã thay đổi Store
+// mà chưa kịp ghi AOF (lệnh đó sẽ bị mất hoặc bị phát lại hai lần trong file mới).
+func (h *CommandsHandler) rewriteAOF() error {
+  if h.aof == nil {
+    return errors.New("ERR AOF is not enabled")
+  }
+
+  if err := h.beginJob(); err != nil {
+    return err
+  }
+  h.gate.Lock()
+  snapshot, err := h.aof.BeginRewrite(h.store)
+  h.gate.Unlock()
+  if err != nil {
+    h.endJob()
+    return err
+  }
+
+  go func() {
+    defer h.endJob()
+    start := time.Now()
+    if err := h.aof.FinishRewrite(snapshot); err != nil {
+      logging.Warningf("Background AOF rewrite failed: %v", err)
+      return
+    }
+    logging.Noticef("Background AOF rewrite finished successfully (%d keys, %v)", len(snapshot), time.Since(start))
+  }()
+  return nil
+}
+
+// handleBGREWRITEAOF: BGREWRITEAOF, ghi lại file AOF tối giản trong nền
+func (h *CommandsHandler) handleBGREWRITEAOF(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 0 {
+    return wrongArgsReply("bgrewriteaof")
+  }
+  // Không bao giờ ghi lại khi đang phát lại AOF
+  if s.Loading() {
+    return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+  }
+
+  if err := h.rewriteAOF(); err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "string", Str: "Background append only file rewriting started"}.Marshal()
+}
+
+// StartAutoRewrite định kỳ kiểm tra kích thước AOF và tự động ghi lại khi file tăng quá
+// RewritePercentage so với lần ghi lại trước (và lớn hơn RewriteMinSize).
+// Trả về hàm dừng việc kiểm tra.
+func (h *CommandsHandler) StartAutoRewrite() func() {
+  done := make(chan struct{})
+  stopped := make(chan struct{})
+
+  go func() {
+    defer close(stopped)
+    ticker := time.NewTicker(autoRewriteInterval)
+    defer ticker.Stop()
+
+    for {
+      select {
+      case <-done:
+        return
+      case <-ticker.C:
+        if h.aof != nil && h.aof.ShouldRewrite() {
+          stats := h.aof.Stats()
+          logging.Noticef("Starting automatic AOF rewrite (size %d, base %d)", stats.CurrentSize, stats.BaseSize)
+          if err := h.rewriteAOF(); err != nil {
+            logging.Warningf("Automatic AOF rewrite failed to start: %v", err)
+          }
+        }
+      }
+    }
+  }()
+
+  return func() {
+    close(done)
+    <-stopped
+  }
+}
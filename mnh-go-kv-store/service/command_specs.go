@@ -0,0 +1,215 @@
+package service
+
+import (
+  "mnhgo/mnh-go-kv-store/internal/acl"
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// commandSpec mô tả một lệnh cho ACL: các category (giống Redis) và vị trí các key trong lệnh.
+// Vị trí tính cả tên lệnh (args[0]); lastKey âm tính từ cuối (-1 là đối số cuối); firstKey 0: không có key.
+type commandSpec struct {
+  categories []string
+  firstKey   int
+  lastKey    int
+  step       int
+}
+
+// Các tổ hợp category hay dùng
+var (
+  readFast  = []string{"read", "fast"}
+  readSlow  = []string{"read", "slow"}
+  writeFast = []string{"write", "fast"}
+  writeSlow = []string{"write", "slow"}
+  adminSlow = []string{"admin", "slow", "dangerous"}
+  noKeys    = commandSpec{}
+  oneKey    = commandSpec{firstKey: 1, lastKey: 1, step: 1}
+  allKeys   = commandSpec{firstKey: 1, lastKey: -1, step: 1}
+  twoKeys   = commandSpec{firstKey: 1, lastKey: 2, step: 1}
+  blockKeys = commandSpec{firstKey: 1, lastKey: -2, step: 1}
+)
+
+// spec tạo commandSpec từ vị trí key và các category
+func spec(keys commandSpec, categories ...[]string) commandSpec {
+  for _, c := range categories {
+    keys.categories = append(keys.categories, c...)
+  }
+  return keys
+}
+
+// commandSpecs liệt kê mọi lệnh của server (kể cả lệnh được xử lý ở Server như AUTH, HELLO, SHUTDOWN, ACL).
+// Lệnh có lệnh con được phân quyền theo từng lệnh con dạng "LỆNH|CON".
+var commandSpecs = map[string]commandSpec{
+  // Kết nối
+  "PING":  spec(noKeys, []string{"fast", "connection"}),
+  "AUTH":  spec(noKeys, []string{"fast", "connection"}),
+  "HELLO": spec(noKeys, []string{"fast", "connection"}),
+  // Key
+  "DEL":         spec(allKeys, []string{"keyspace"}, writeSlow),
+  "EXISTS":      spec(allKeys, []string{"keyspace"}, readFast),
+  "TTL":         spec(oneKey, []string{"keyspace"}, readFast),
+  "PTTL":        spec(oneKey, []string{"keyspace"}, readFast),
+  "EXPIRETIME":  spec(oneKey, []string{"keyspace"}, readFast),
+  "PEXPIRETIME": spec(oneKey, []string{"keyspace"}, readFast),
+  "EXPIRE":      spec(oneKey, []string{"keyspace"}, writeFast),
+  "PEXPIRE":     spec(oneKey, []string{"keyspace"}, writeFast),
+  "EXPIREAT":    spec(oneKey, []string{"keyspace"}, writeFast),
+  "PEXPIREAT":   spec(oneKey, []string{"keyspace"}, writeFast),
+  "PERSIST":     spec(oneKey, []string{"keyspace"}, writeFast),
+  // String
+  "SET":         spec(oneKey, []string{"string"}, writeSlow),
+  "SETNX":       spec(oneKey, []string{"string"}, writeFast),
+  "SETEX":       spec(oneKey, []string{"string"}, writeSlow),
+  "PSETEX":      spec(oneKey, []string{"string"}, writeSlow),
+  "GET":         spec(oneKey, []string{"string"}, readFast),
+  "INCR":        spec(oneKey, []string{"string"}, writeFast),
+  "INCRBY":      spec(oneKey, []string{"string"}, writeFast),
+  "DECR":        spec(oneKey, []string{"string"}, writeFast),
+  "DECRBY":      spec(oneKey, []string{"string"}, writeFast),
+  "INCRBYFLOAT": spec(oneKey, []string{"string"}, writeFast),
+  "APPEND":      spec(oneKey, []string{"string"}, writeFast),
+  "STRLEN":      spec(oneKey, []string{"string"}, readFast),
+  "GETRANGE":    spec(oneKey, []string{"string"}, readSlow),
+  "SETRANGE":    spec(oneKey, []string{"string"}, writeSlow),
+  "GETDEL":      spec(oneKey, []string{"string"}, writeFast),
+  "GETEX":       spec(oneKey, []string{"string"}, writeFast),
+  // Hash
+  "HSET":         spec(oneKey, []string{"hash"}, writeFast),
+  "HMSET":        spec(oneKey, []string{"hash"}, writeFast),
+  "HSETNX":       spec(oneKey, []string{"hash"}, writeFast),
+  "HGET":         spec(oneKey, []string{"hash"}, readFast),
+  "HMGET":        spec(oneKey, []string{"hash"}, readFast),
+  "HGETALL":      spec(oneKey, []string{"hash"}, readSlow),
+  "HDEL":         spec(oneKey, []string{"hash"}, writeFast),
+  "HEXISTS":      spec(oneKey, []string{"hash"}, readFast),
+  "HLEN":         spec(oneKey, []string{"hash"}, readFast),
+  "HSTRLEN":      spec(oneKey, []string{"hash"}, readFast),
+  "HKEYS":        spec(oneKey, []string{"hash"}, readSlow),
+  "HVALS":        spec(oneKey, []string{"hash"}, readSlow),
+  "HINCRBY":      spec(oneKey, []string{"hash"}, writeFast),
+  "HINCRBYFLOAT": spec(oneKey, []string{"hash"}, writeFast),
+  "HSCAN":        spec(oneKey, []string{"hash"}, readSlow),
+  // List
+  "LPUSH":     spec(oneKey, []string{"list"}, writeFast),
+  "RPUSH":     spec(oneKey, []string{"list"}, writeFast),
+  "LPUSHX":    spec(oneKey, []string{"list"}, writeFast),
+  "RPUSHX":    spec(oneKey, []string{"list"}, writeFast),
+  "LPOP":      spec(oneKey, []string{"list"}, writeFast),
+  "RPOP":      spec(oneKey, []string{"list"}, writeFast),
+  "LLEN":      spec(oneKey, []string{"list"}, readFast),
+  "LRANGE":    spec(oneKey, []string{"list"}, readSlow),
+  "LINDEX":    spec(oneKey, []string{"list"}, readSlow),
+  "LSET":      spec(oneKey, []string{"list"}, writeSlow),
+  "LTRIM":     spec(oneKey, []string{"list"}, writeSlow),
+  "LINSERT":   spec(oneKey, []string{"list"}, writeSlow),
+  "LREM":      spec(oneKey, []string{"list"}, writeSlow),
+  "LMOVE":     spec(twoKeys, []string{"list"}, writeSlow),
+  "RPOPLPUSH": spec(twoKeys, []string{"list"}, writeSlow),
+  "BLPOP":     spec(blockKeys, []string{"list", "blocking"}, writeSlow),
+  "BRPOP":     spec(blockKeys, []string{"list", "blocking"}, writeSlow),
+  "BLMOVE":    spec(twoKeys, []string{"list", "blocking"}, writeSlow),
+  // Set
+  "SADD":        spec(oneKey, []string{"set"}, writeFast),
+  "SREM":        spec(oneKey, []string{"set"}, writeFast),
+  "SMEMBERS":    spec(oneKey, []string{"set"}, readSlow),
+  "SISMEMBER":   spec(oneKey, []string{"set"}, readFast),
+  "SMISMEMBER":  spec(oneKey, []string{"set"}, readFast),
+  "SCARD":       spec(oneKey, []string{"set"}, readFast),
+  "SPOP":        spec(oneKey, []string{"set"}, writeFast),
+  "SRANDMEMBER": spec(oneKey, []string{"set"}, readSlow),
+  "SINTER":      spec(allKeys, []string{"set"}, readSlow),
+  "SUNION":      spec(allKeys, []string{"set"}, readSlow),
+  "SDIFF":       spec(allKeys, []string{"set"}, readSlow),
+  "SINTERSTORE": spec(allKeys, []string{"set"}, writeSlow),
+  "SUNIONSTORE": spec(allKeys, []string{"set"}, writeSlow),
+  "SDIFFSTORE":  spec(allKeys, []string{"set"}, writeSlow),
+  // Sorted Set
+  "ZADD":             spec(oneKey, []string{"sortedset"}, writeFast),
+  "ZINCRBY":          spec(oneKey, []string{"sortedset"}, writeFast),
+  "ZREM":             spec(oneKey, []string{"sortedset"}, writeFast),
+  "ZSCORE":           spec(oneKey, []string{"sortedset"}, readFast),
+  "ZCARD":            spec(oneKey, []string{"sortedset"}, readFast),
+  "ZRANK":            spec(oneKey, []string{"sortedset"}, readFast),
+  "ZREVRANK":         spec(oneKey, []string{"sortedset"}, readFast),
+  "ZRANGE":           spec(oneKey, []string{"sortedset"}, readSlow),
+  "ZREVRANGE":        spec(oneKey, []string{"sortedset"}, readSlow),
+  "ZRANGEBYSCORE":    spec(oneKey, []string{"sortedset"}, readSlow),
+  "ZREVRANGEBYSCORE": spec(oneKey, []string{"sortedset"}, readSlow),
+  "ZRANGEBYLEX":      spec(oneKey, []string{"sortedset"}, readSlow),
+  "ZREVRANGEBYLEX":   spec(oneKey, []string{"sortedset"}, readSlow),
+  "ZCOUNT":           spec(oneKey, []string{"sortedset"}, readFast),
+  "ZLEXCOUNT":        spec(oneKey, []string{"sortedset"}, readFast),
+  "ZPOPMIN":          spec(oneKey, []string{"sortedset"}, writeFast),
+  "ZPOPMAX":          spec(oneKey, []string{"sortedset"}, writeFast),
+  "ZREMRANGEBYRANK":  spec(oneKey, []string{"sortedset"}, writeSlow),
+  "ZREMRANGEBYSCORE": spec(oneKey, []string{"sortedset"}, writeSlow),
+  "ZREMRANGEBYLEX":   spec(oneKey, []string{"sortedset"}, writeSlow),
+  // Server
+  "INFO":           spec(noKeys, []string{"slow", "dangerous"}),
+  "BGREWRITEAOF":   spec(noKeys, adminSlow),
+  "SAVE":           spec(noKeys, adminSlow),
+  "BGSAVE":         spec(noKeys, adminSlow),
+  "LASTSAVE":       spec(noKeys, []string{"fast", "dangerous"}),
+  "SHUTDOWN":       spec(noKeys, adminSlow),
+  "CONFIG|GET":     spec(noKeys, adminSlow),
+  "CONFIG|SET":     spec(noKeys, adminSlow),
+  "CONFIG|REWRITE": spec(noKeys, adminSlow),
+  "ACL|CAT":        spec(noKeys, []string{"slow"}),
+  "ACL|WHOAMI":     spec(noKeys, []string{"slow"}),
+  "ACL|GENPASS":    spec(noKeys, []string{"slow"}),
+  "ACL|SETUSER":    spec(noKeys, adminSlow),
+  "ACL|GETUSER":    spec(noKeys, adminSlow),
+  "ACL|DELUSER":    spec(noKeys, adminSlow),
+  "ACL|LIST":       spec(noKeys, adminSlow),
+  "ACL|USERS":      spec(noKeys, adminSlow),
+  "ACL|LOAD":       spec(noKeys, adminSlow),
+  "ACL|SAVE":       spec(noKeys, adminSlow),
+}
+
+// aclCommands chuyển commandSpecs thành danh sách lệnh cho ACL
+func aclCommands() []acl.Command {
+  commands := make([]acl.Command, 0, len(commandSpecs))
+  for name, cs := range commandSpecs {
+    commands = append(commands, acl.Command{Name: name, Categories: cs.categories})
+  }
+  return commands
+}
+
+// commandKeys trả về các key mà lệnh args (gồm cả tên lệnh) truy cập theo commandSpecs
+func commandKeys(name string, args []protocol.Value) []string {
+  cs, ok := commandSpecs[name]
+  if !ok || cs.firstKey == 0 {
+    return nil
+  }
+  last := cs.lastKey
+  if last < 0 {
+    last += len(args)
+  }
+  if last >= len(args) {
+    last = len(args) - 1
+  }
+
+  var keys []string
+  for i := cs.firstKey; i <= last; i += cs.step {
+    keys = append(keys, args[i].Bulk)
+  }
+  return keys
+}
+
+// checkACL kiểm tra user được chạy lệnh cmdValue; trả về phản hồi lỗi NOPERM (hoặc NOAUTH nếu user
+// đã bị xóa) hoặc nil nếu được phép
+func (h *CommandsHandler) checkACL(user string, cmdValue protocol.Value) []byte {
+  name := commandName(cmdValue)
+  var sub string
+  if len(cmdValue.Array) > 1 {
+    sub = cmdValue.Array[1].Bulk
+  }
+
+  err := h.acl.Check(user, name, sub, commandKeys(name, cmdValue.Array))
+  if err == acl.ErrNoUser {
+    return errorReply(errNoAuth.Error())
+  }
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return nil
+}
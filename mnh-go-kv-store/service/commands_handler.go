@@ -4,8 +4,10 @@ import (
   "fmt"
   "strconv"
   "strings"
-  "time"
+  "sync"
 
+  "mnhgo/mnh-go-kv-store/internal/acl"
+  "mnhgo/mnh-go-kv-store/internal/config"
   "mnhgo/mnh-go-kv-store/internal/protocol"
   "mnhgo/mnh-go-kv-store/internal/store"
 )
@@ -17,24 +19,160 @@ type HandlerFunc func(s *store.Store, aof *store.AOF, args []protocol.Value) []b
 type CommandsHandler struct {
   store    *store.Store
   aof      *store.AOF
+  rdb      *store.RDB     // nil nếu không dùng snapshot RDB
+  config   *config.Config // nil nếu server chạy không có cấu hình (CONFIG, maxmemory, requirepass bị tắt)
   commands map[string]HandlerFunc
+
+  acl         *acl.ACL // Các user và quyền của họ (user default có mọi quyền khi chưa cấu hình)
+  requirePass string   // Giá trị requirepass đã áp dụng cho user default
+
+  closing   chan struct{} // Đóng khi server bắt đầu dừng, đánh thức các lệnh chặn
+  closeOnce sync.Once
+
+  // Các công việc nền (BGSAVE, ghi lại AOF) mà việc dừng server phải chờ
+  jobsMu       sync.Mutex
+  jobs         sync.WaitGroup
+  shuttingDown bool
+
+  // gate đảm bảo mỗi lệnh thay đổi Store và ghi AOF như một khối đối với việc ghi lại AOF:
+  // các lệnh giữ RLock, còn BGREWRITEAOF giữ Lock khi lấy snapshot.
+  gate sync.RWMutex
+  // frozen: đã lưu snapshot cuối cùng khi dừng server, mọi lệnh bị từ chối (chỉ đổi khi giữ gate.Lock)
+  frozen bool
+}
+
+// ungatedCommands là các lệnh không được giữ gate trong suốt quá trình thực thi: lệnh chặn
+// (tự giữ gate khi thay đổi Store, không giữ trong lúc chờ) và lệnh cần giữ gate ở chế độ ghi
+var ungatedCommands = map[string]bool{
+  "BLPOP":        true,
+  "BRPOP":        true,
+  "BLMOVE":       true,
+  "BGREWRITEAOF": true,
+}
+
+// blockingCommands là các lệnh có thể chặn kết nối để chờ dữ liệu
+var blockingCommands = map[string]bool{
+  "BLPOP":  true,
+  "BRPOP":  true,
+  "BLMOVE": true,
 }
 
 func NewCommandsHandler(s *store.Store, aof *store.AOF) *CommandsHandler {
   h := &CommandsHandler{
-    store: s,
-    aof:   aof,
+    store:   s,
+    aof:     aof,
+    closing: make(chan struct{}),
+    acl:     acl.New(aclCommands()),
   }
   h.commands = map[string]HandlerFunc{
-    "PING":    h.handlePING,
-    "SET":     h.handleSET,
-    "GET":     h.handleGET,
-    "DEL":     h.handleDEL,
-    "EXISTS":  h.handleEXISTS,
-    "TTL":     h.handleTTL,
-    "HSET":    h.handleHSET,
-    "HGET":    h.handleHGET,
-    "HGETALL": h.handleHGETALL,
+    "PING":   h.handlePING,
+    "SET":    h.handleSET,
+    "SETNX":  h.handleSETNX,
+    "SETEX":  h.handleSETEX,
+    "PSETEX": h.handlePSETEX,
+    "GET":    h.handleGET,
+    "DEL":    h.handleDEL,
+    "EXISTS": h.handleEXISTS,
+    "TTL":    h.handleTTL,
+    // Expire
+    "EXPIRE":      h.handleEXPIRE,
+    "PEXPIRE":     h.handlePEXPIRE,
+    "EXPIREAT":    h.handleEXPIREAT,
+    "PEXPIREAT":   h.handlePEXPIREAT,
+    "PERSIST":     h.handlePERSIST,
+    "PTTL":        h.handlePTTL,
+    "EXPIRETIME":  h.handleEXPIRETIME,
+    "PEXPIRETIME": h.handlePEXPIRETIME,
+    // String
+    "INCR":        h.handleINCR,
+    "INCRBY":      h.handleINCRBY,
+    "DECR":        h.handleDECR,
+    "DECRBY":      h.handleDECRBY,
+    "INCRBYFLOAT": h.handleINCRBYFLOAT,
+    "APPEND":      h.handleAPPEND,
+    "STRLEN":      h.handleSTRLEN,
+    "GETRANGE":    h.handleGETRANGE,
+    "SETRANGE":    h.handleSETRANGE,
+    "GETDEL":      h.handleGETDEL,
+    "GETEX":       h.handleGETEX,
+    // Hash
+    "HSET":         h.handleHSET,
+    "HMSET":        h.handleHMSET,
+    "HSETNX":       h.handleHSETNX,
+    "HGET":         h.handleHGET,
+    "HMGET":        h.handleHMGET,
+    "HGETALL":      h.handleHGETALL,
+    "HDEL":         h.handleHDEL,
+    "HEXISTS":      h.handleHEXISTS,
+    "HLEN":         h.handleHLEN,
+    "HSTRLEN":      h.handleHSTRLEN,
+    "HKEYS":        h.handleHKEYS,
+    "HVALS":        h.handleHVALS,
+    "HINCRBY":      h.handleHINCRBY,
+    "HINCRBYFLOAT": h.handleHINCRBYFLOAT,
+    "HSCAN":        h.handleHSCAN,
+    // List
+    "LPUSH":     h.handleLPUSH,
+    "RPUSH":     h.handleRPUSH,
+    "LPUSHX":    h.handleLPUSHX,
+    "RPUSHX":    h.handleRPUSHX,
+    "LPOP":      h.handleLPOP,
+    "RPOP":      h.handleRPOP,
+    "LLEN":      h.handleLLEN,
+    "LRANGE":    h.handleLRANGE,
+    "LINDEX":    h.handleLINDEX,
+    "LSET":      h.handleLSET,
+    "LTRIM":     h.handleLTRIM,
+    "LINSERT":   h.handleLINSERT,
+    "LREM":      h.handleLREM,
+    "LMOVE":     h.handleLMOVE,
+    "RPOPLPUSH": h.handleRPOPLPUSH,
+    "BLPOP":     h.handleBLPOP,
+    "BRPOP":     h.handleBRPOP,
+    "BLMOVE":    h.handleBLMOVE,
+    // Set
+    "SADD":        h.handleSADD,
+    "SREM":        h.handleSREM,
+    "SMEMBERS":    h.handleSMEMBERS,
+    "SISMEMBER":   h.handleSISMEMBER,
+    "SMISMEMBER":  h.handleSMISMEMBER,
+    "SCARD":       h.handleSCARD,
+    "SPOP":        h.handleSPOP,
+    "SRANDMEMBER": h.handleSRANDMEMBER,
+    "SINTER":      h.handleSINTER,
+    "SUNION":      h.handleSUNION,
+    "SDIFF":       h.handleSDIFF,
+    "SINTERSTORE": h.handleSINTERSTORE,
+    "SUNIONSTORE": h.handleSUNIONSTORE,
+    "SDIFFSTORE":  h.handleSDIFFSTORE,
+    // Sorted Set
+    "ZADD":             h.handleZADD,
+    "ZINCRBY":          h.handleZINCRBY,
+    "ZREM":             h.handleZREM,
+    "ZSCORE":           h.handleZSCORE,
+    "ZCARD":            h.handleZCARD,
+    "ZRANK":            h.handleZRANK,
+    "ZREVRANK":         h.handleZREVRANK,
+    "ZRANGE":           h.handleZRANGE,
+    "ZREVRANGE":        h.handleZREVRANGE,
+    "ZRANGEBYSCORE":    h.handleZRANGEBYSCORE,
+    "ZREVRANGEBYSCORE": h.handleZREVRANGEBYSCORE,
+    "ZRANGEBYLEX":      h.handleZRANGEBYLEX,
+    "ZREVRANGEBYLEX":   h.handleZREVRANGEBYLEX,
+    "ZCOUNT":           h.handleZCOUNT,
+    "ZLEXCOUNT":        h.handleZLEXCOUNT,
+    "ZPOPMIN":          h.handleZPOPMIN,
+    "ZPOPMAX":          h.handleZPOPMAX,
+    "ZREMRANGEBYRANK":  h.handleZREMRANGEBYRANK,
+    "ZREMRANGEBYSCORE": h.handleZREMRANGEBYSCORE,
+    "ZREMRANGEBYLEX":   h.handleZREMRANGEBYLEX,
+    // Server
+    "INFO":         h.handleINFO,
+    "BGREWRITEAOF": h.handleBGREWRITEAOF,
+    "SAVE":         h.handleSAVE,
+    "BGSAVE":       h.handleBGSAVE,
+    "LASTSAVE":     h.handleLASTSAVE,
+    "CONFIG":       h.handleCONFIG,
     // Thêm các lệnh khác vào đây
   }
   return h
@@ -53,6 +191,11 @@ func (h *CommandsHandler) ExecuteAOFCommand(cmdValue protocol.Value) {
   }
 }
 
+// LoadSnapshot nạp phần snapshot nhị phân ở đầu file AOF vào Store
+func (h *CommandsHandler) LoadSnapshot(entries map[string]store.Entry) {
+  h.store.Restore(entries)
+}
+
 // HandleCommand là điểm vào chính để xử lý lệnh từ client
 func (h *CommandsHandler) HandleCommand(cmdValue protocol.Value) []byte {
   // Kiểm tra xem lệnh có phải là Array không
@@ -68,16 +211,111 @@ func (h *CommandsHandler) HandleCommand(cmdValue protocol.Value) []byte {
 
   // Tìm handler
   if handler, ok := h.commands[commandName]; ok {
+    if reply := h.checkMemory(commandName); reply != nil {
+      return reply
+    }
+    if !ungatedCommands[commandName] {
+      h.gate.RLock()
+      defer h.gate.RUnlock()
+      if h.frozen {
+        return errorReply(errShuttingDown.Error())
+      }
+    }
     return handler(h.store, h.aof, args)
   }
 
   return protocol.Value{Typ: "error", Str: fmt.Sprintf("ERR unknown command '%s'", commandName)}.Marshal()
 }
 
+// errNotInteger là thông báo lỗi chuẩn khi đối số không phải số nguyên hợp lệ
+const errNotInteger = "ERR value is not an integer or out of range"
+
+// errorReply mã hóa một thông báo lỗi thành RESP Error
+func errorReply(msg string) []byte {
+  return protocol.Value{Typ: "error", Str: msg}.Marshal()
+}
+
+// wrongArgsReply trả về lỗi sai số lượng đối số cho lệnh cmd (viết thường)
+func wrongArgsReply(cmd string) []byte {
+  return errorReply(fmt.Sprintf("ERR wrong number of arguments for '%s' command", cmd))
+}
+
+// bulkArray chuyển danh sách chuỗi thành các phần tử Bulk String
+func bulkArray(items []string) []protocol.Value {
+  array := make([]protocol.Value, len(items))
+  for i, item := range items {
+    array[i] = protocol.Value{Typ: "bulk", Bulk: item}
+  }
+  return array
+}
+
+// bulkArrayReply mã hóa danh sách chuỗi thành RESP Array gồm các Bulk String
+func bulkArrayReply(items []string) []byte {
+  return protocol.Value{Typ: "array", Array: bulkArray(items)}.Marshal()
+}
+
+// setReply mã hóa danh sách phần tử thành RESP3 Set (Array với kết nối RESP2)
+func setReply(items []string) []byte {
+  return protocol.Value{Typ: "set", Array: bulkArray(items)}.Marshal()
+}
+
+// doubleReply mã hóa số thực thành RESP3 Double (Bulk String với kết nối RESP2)
+func doubleReply(f float64) []byte {
+  return protocol.Value{Typ: "double", Double: f}.Marshal()
+}
+
+// argStrings trích xuất giá trị Bulk của các đối số
+func argStrings(args []protocol.Value) []string {
+  result := make([]string, len(args))
+  for i, arg := range args {
+    result[i] = arg.Bulk
+  }
+  return result
+}
+
+// boolToInt chuyển bool thành 1/0 cho phản hồi dạng Integer
+func boolToInt(b bool) int {
+  if b {
+    return 1
+  }
+  return 0
+}
+
+// logCommand ghi nhận một thay đổi trên Store: tăng bộ đếm thay đổi (dùng cho các quy tắc save)
+// và ghi lệnh vào AOF (bỏ qua khi đang phát lại AOF hoặc khi AOF bị tắt, lúc đó aof == nil)
+func logCommand(s *store.Store, aof *store.AOF, parts ...string) {
+  s.MarkDirty()
+  if aof != nil {
+    aof.AppendCommand(parts...)
+  }
+}
+
+// logCommandNoWait giống logCommand nhưng không chờ fsync, dùng khi đang giữ khóa của Store
+// (callback của BPOP/BLMOVE, xóa key hết hạn). Trả về số thứ tự cần truyền cho aof.WaitSync
+// trước khi trả lời client (0 nếu không cần chờ).
+func logCommandNoWait(s *store.Store, aof *store.AOF, parts ...string) uint64 {
+  s.MarkDirty()
+  if aof == nil {
+    return 0
+  }
+  seq, _ := aof.AppendCommandNoWait(parts...)
+  return seq
+}
+
+// waitLogged chờ fsync lệnh đã ghi bằng logCommandNoWait (chỉ chờ với appendfsync always),
+// gọi sau khi đã nhả khóa của Store và trước khi trả lời client
+func waitLogged(aof *store.AOF, seq uint64) {
+  if aof != nil {
+    aof.WaitSync(seq)
+  }
+}
+
 func (h *CommandsHandler) handlePING(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
   return protocol.Value{Typ: "string", Str: "PONG"}.Marshal()
 }
 
+// handleSET: SET key value [NX|XX] [GET] [EX seconds|PX milliseconds|EXAT timestamp|PXAT ms-timestamp|KEEPTTL].
+// Các tùy chọn có thể xuất hiện theo thứ tự bất kỳ.
 func (h *CommandsHandler) handleSET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
   if len(args) < 2 {
     return protocol.Value{Typ: "error", Str: "ERR wrong number of arguments for 'set' command"}.Marshal()
@@ -86,88 +324,115 @@ func (h *CommandsHandler) handleSET(s *store.Store, aof *store.AOF, args []proto
   key := args[0].Bulk
   value := args[1].Bulk
 
-  // Parse TTL arguments (EX = seconds, PX = milliseconds)
-  var ttl time.Duration = 0
-  if len(args) >= 4 {
-    ttlStr := strings.ToUpper(args[2].Bulk)
-    if ttlStr == "EX" || ttlStr == "PX" {
-      secs, err := strconv.Atoi(args[3].Bulk)
-      if err == nil {
-        if ttlStr == "EX" {
-          ttl = time.Duration(secs) * time.Second
-        } else {
-          ttl = time.Duration(secs) * time.Millisecond
-        }
+  var opts store.SetOptions
+  expireOptions := 0
+  for i := 2; i < len(args); i++ {
+    option := strings.ToUpper(args[i].Bulk)
+    switch option {
+    case "NX":
+      opts.NX = true
+    case "XX":
+      opts.XX = true
+    case "GET":
+      opts.Get = true
+    case "KEEPTTL":
+      opts.KeepTTL = true
+      expireOptions++
+    case "EX", "PX", "EXAT", "PXAT":
+      if i+1 >= len(args) {
+        return errorReply("ERR syntax error")
+      }
+      expiresAt, errResp := parseExpireOption(option, args[i+1].Bulk, "set")
+      if errResp != nil {
+        return errResp
       }
+      opts.ExpiresAt = expiresAt
+      expireOptions++
+      i++
+    default:
+      return errorReply("ERR syntax error")
     }
   }
+  if (opts.NX && opts.XX) || expireOptions > 1 {
+    return errorReply("ERR syntax error")
+  }
 
-  s.SET(key, value, ttl)
+  old, oldExists, applied, err := s.SETWithOptions(key, value, opts)
+  if err != nil {
+    return errorReply(err.Error())
+  }
 
-  // Ghi lệnh vào AOF
-  if aof != nil {
+  // Ghi lệnh vào AOF (chỉ khi lệnh thực sự được áp dụng, không kèm NX/XX/GET).
+  // TTL được ghi dưới dạng thời điểm tuyệt đối (PXAT) để khởi động lại không kéo dài TTL.
+  if applied {
     commandParts := []string{"SET", key, value}
-    if ttl > 0 {
-      commandParts = append(commandParts, "EX", strconv.Itoa(int(ttl.Seconds())))
+    if opts.KeepTTL {
+      commandParts = append(commandParts, "KEEPTTL")
+    } else if !opts.ExpiresAt.IsZero() {
+      commandParts = append(commandParts, "PXAT", strconv.FormatInt(opts.ExpiresAt.UnixMilli(), 10))
     }
-    aof.WriteCommand(protocol.MarshalCommand(commandParts))
+    logCommand(s, aof, commandParts...)
   }
 
-  return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
-}
-
-func (h *CommandsHandler) handleGET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
-  if len(args) != 1 {
-    return protocol.Value{Typ: "error", Str: "ERR wrong number of arguments for 'get' command"}.Marshal()
+  if opts.Get {
+    if !oldExists {
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    return protocol.Value{Typ: "bulk", Bulk: old}.Marshal()
   }
-
-  value, found := s.GET(args[0].Bulk)
-
-  if !found {
+  if !applied {
     return protocol.Value{Typ: "null"}.Marshal()
   }
-  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+  return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
 }
 
-func (h *CommandsHandler) handleHSET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
-  if len(args) < 3 || len(args)%2 != 1 {
-    return protocol.Value{Typ: "error", Str: "ERR wrong number of arguments for 'hset' command"}.Marshal()
+// setVariant chuyển SETNX/SETEX/PSETEX thành lệnh SET tương đương
+func (h *CommandsHandler) setVariant(s *store.Store, aof *store.AOF, key, value protocol.Value, options ...string) []byte {
+  args := []protocol.Value{key, value}
+  for _, option := range options {
+    args = append(args, protocol.Value{Typ: "bulk", Bulk: option})
   }
+  return h.handleSET(s, aof, args)
+}
 
-  key := args[0].Bulk
-  // HSET có thể có nhiều cặp field-value
-  fieldsAdded := 0
-  for i := 1; i < len(args); i += 2 {
-    field := args[i].Bulk
-    value := args[i+1].Bulk
-    if s.HSET(key, field, value) {
-      fieldsAdded++
-    }
+// handleSETNX: SETNX key value, trả về 1 nếu đã ghi, 0 nếu key đã tồn tại
+func (h *CommandsHandler) handleSETNX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("setnx")
   }
 
-  // Ghi lệnh vào AOF
-  if aof != nil {
-    commandParts := make([]string, len(args)+1)
-    commandParts[0] = "HSET"
-    commandParts[1] = key
-    for i := 1; i < len(args); i++ {
-      commandParts[i+1] = args[i].Bulk
-    }
-    aof.WriteCommand(protocol.MarshalCommand(commandParts))
+  _, _, applied, err := s.SETWithOptions(args[0].Bulk, args[1].Bulk, store.SetOptions{NX: true})
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  if applied {
+    logCommand(s, aof, "SET", args[0].Bulk, args[1].Bulk)
   }
+  return protocol.Value{Typ: "integer", Num: boolToInt(applied)}.Marshal()
+}
 
-  return protocol.Value{Typ: "integer", Num: fieldsAdded}.Marshal()
+// handleSETEX: SETEX key seconds value
+func (h *CommandsHandler) handleSETEX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("setex")
+  }
+  return h.setVariant(s, aof, args[0], args[2], "EX", args[1].Bulk)
 }
 
-func (h *CommandsHandler) handleHGET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
-  if len(args) != 2 {
-    return protocol.Value{Typ: "error", Str: "ERR wrong number of arguments for 'hget' command"}.Marshal()
+// handlePSETEX: PSETEX key milliseconds value
+func (h *CommandsHandler) handlePSETEX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("psetex")
   }
+  return h.setVariant(s, aof, args[0], args[2], "PX", args[1].Bulk)
+}
 
-  key := args[0].Bulk
-  field := args[1].Bulk
+func (h *CommandsHandler) handleGET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return protocol.Value{Typ: "error", Str: "ERR wrong number of arguments for 'get' command"}.Marshal()
+  }
 
-  value, found := s.HGET(key, field)
+  value, found := s.GET(args[0].Bulk)
 
   if !found {
     return protocol.Value{Typ: "null"}.Marshal()
@@ -195,9 +460,8 @@ func (h *CommandsHandler) handleDEL(s *store.Store, aof *store.AOF, args []proto
   }
 
   // Ghi lệnh vào AOF
-  if aof != nil && len(keysToDelete) > 0 {
-    commandParts := append([]string{"DEL"}, keysToDelete...)
-    aof.WriteCommand(protocol.MarshalCommand(commandParts))
+  if len(keysToDelete) > 0 {
+    logCommand(s, aof, append([]string{"DEL"}, keysToDelete...)...)
   }
 
   return protocol.Value{Typ: "integer", Num: count}.Marshal()
@@ -226,28 +490,3 @@ func (h *CommandsHandler) handleTTL(s *store.Store, aof *store.AOF, args []proto
   ttl := s.TTL(args[0].Bulk)
   return protocol.Value{Typ: "integer", Num: int(ttl)}.Marshal()
 }
-
-func (h *CommandsHandler) handleHGETALL(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
-  if len(args) != 1 {
-    return protocol.Value{Typ: "error", Str: "ERR wrong number of arguments for 'hgetall' command"}.Marshal()
-  }
-
-  key := args[0].Bulk
-  hash, found := s.HGETALL(key)
-
-  if !found {
-    return protocol.Value{Typ: "array", Array: []protocol.Value{}}.Marshal()
-  }
-
-  // Return an array alternating field and value
-  array := make([]protocol.Value, len(hash)*2)
-  idx := 0
-  for field, value := range hash {
-    array[idx] = protocol.Value{Typ: "bulk", Bulk: field}
-    array[idx+1] = protocol.Value{Typ: "bulk", Bulk: value}
-    idx += 2
-  }
-
-  return protocol.Value{Typ: "array", Array: array}.Marshal()
-}
-
@@ -0,0 +1,85 @@
+package service
+
+import (
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/config"
+  "mnhgo/mnh-go-kv-store/internal/logging"
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// EnableConfig gắn cấu hình cho CONFIG GET/SET/REWRITE, maxmemory và requirepass,
+// đồng thời áp dụng các giá trị hiện tại cho AOF, RDB và log
+func (h *CommandsHandler) EnableConfig(cfg *config.Config) {
+  h.config = cfg
+  h.applyConfig()
+}
+
+// applyConfig đẩy các tham số có thể thay đổi khi chạy tới các thành phần sử dụng chúng
+func (h *CommandsHandler) applyConfig() {
+  settings := h.config.Settings()
+  logging.SetLevel(settings.LogLevel)
+  if h.aof != nil {
+    h.aof.SetFsyncPolicy(settings.AppendFsync)
+    h.aof.SetRewriteOptions(settings.AutoAOFRewritePercentage, settings.AutoAOFRewriteMinSize, settings.AOFUseRDBPreamble)
+  }
+  if h.rdb != nil {
+    h.rdb.SetSaveRules(settings.Save)
+  }
+  // requirepass là mật khẩu của user default; chỉ áp dụng khi giá trị thay đổi để không ghi đè
+  // mật khẩu đặt bằng ACL SETUSER hoặc file ACL mỗi lần CONFIG SET tham số khác
+  if settings.RequirePass != h.requirePass {
+    h.acl.SetDefaultPassword(settings.RequirePass)
+    h.requirePass = settings.RequirePass
+  }
+}
+
+// handleCONFIG: CONFIG GET pattern [pattern ...] | SET name value [name value ...] | REWRITE
+func (h *CommandsHandler) handleCONFIG(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) == 0 {
+    return wrongArgsReply("config")
+  }
+  if h.config == nil {
+    return errorReply("ERR CONFIG is not available")
+  }
+
+  sub := strings.ToUpper(args[0].Bulk)
+  params := argStrings(args[1:])
+  switch sub {
+  case "GET":
+    if len(params) == 0 {
+      return wrongArgsReply("config|get")
+    }
+    var items []string
+    for _, pair := range h.config.Get(params...) {
+      items = append(items, pair[0], pair[1])
+    }
+    return protocol.Value{Typ: "map", Array: bulkArray(items)}.Marshal()
+
+  case "SET":
+    if len(params) == 0 || len(params)%2 != 0 {
+      return wrongArgsReply("config|set")
+    }
+    if err := h.config.SetRuntime(params...); err != nil {
+      return errorReply(err.Error())
+    }
+    h.applyConfig()
+    return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+
+  case "REWRITE":
+    if len(params) != 0 {
+      return wrongArgsReply("config|rewrite")
+    }
+    if err := h.config.Rewrite(); err != nil {
+      if strings.HasPrefix(err.Error(), "ERR ") {
+        return errorReply(err.Error())
+      }
+      logging.Warningf("CONFIG REWRITE failed: %v", err)
+      return errorReply("ERR Rewriting config file: " + err.Error())
+    }
+    logging.Noticef("CONFIG REWRITE executed with success")
+    return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+  }
+  return errorReply("ERR unknown subcommand '" + args[0].Bulk + "'. Try CONFIG GET, CONFIG SET or CONFIG REWRITE.")
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// expireGeneric xử lý chung cho EXPIRE/PEXPIRE/EXPIREAT/PEXPIREAT [NX|XX|GT|LT].
+// unitMs là số mili giây của một đơn vị thời gian (1000 cho giây), absolute = true với *AT.
+// AOF luôn ghi lại dưới dạng PEXPIREAT với thời điểm tuyệt đối để khởi động lại không kéo dài TTL.
+func (h *CommandsHandler) expireGeneric(s *store.Store, aof *store.AOF, args []protocol.Value, cmd string, unitMs int64, absolute bool) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply(cmd)
+  }
+
+  key := args[0].Bulk
+  n, err := strconv.ParseInt(args[1].Bulk, 10, 64)
+  if err != nil {
+    return errorReply(errNotInteger)
+  }
+
+  var opts store.ExpireOptions
+  for _, arg := range args[2:] {
+    switch strings.ToUpper(arg.Bulk) {
+    case "NX":
+      opts.NX = true
+    case "XX":
+      opts.XX = true
+    case "GT":
+      opts.GT = true
+    case "LT":
+      opts.LT = true
+    default:
+      return errorReply(fmt.Sprintf("ERR Unsupported option %s", arg.Bulk))
+    }
+  }
+  if opts.NX && (opts.XX || opts.GT || opts.LT) {
+    return errorReply("ERR NX and XX, GT or LT options at the same time are not compatible")
+  }
+  if opts.GT && opts.LT {
+    return errorReply("ERR GT and LT options at the same time are not compatible")
+  }
+
+  // Quy đổi sang Unix mili giây, kiểm tra tràn số giống Redis
+  invalid := errorReply(fmt.Sprintf("ERR invalid expire time in '%s' command", cmd))
+  if n > math.MaxInt64/unitMs || n < math.MinInt64/unitMs {
+    return invalid
+  }
+  ms := n * unitMs
+  if !absolute {
+    now := time.Now().UnixMilli()
+    if ms > math.MaxInt64-now {
+      return invalid
+    }
+    ms += now
+  }
+
+  if !s.EXPIREAT(key, time.UnixMilli(ms), opts) {
+    return protocol.Value{Typ: "integer", Num: 0}.Marshal()
+  }
+
+  logCommand(s, aof, "PEXPIREAT", key, strconv.FormatInt(ms, 10))
+  return protocol.Value{Typ: "integer", Num: 1}.Marshal()
+}
+
+// handleEXPIRE: EXPIRE key seconds [NX|XX|GT|LT]
+func (h *CommandsHandler) handleEXPIRE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.expireGeneric(s, aof, args, "expire", 1000, false)
+}
+
+// handlePEXPIRE: PEXPIRE key milliseconds [NX|XX|GT|LT]
+func (h *CommandsHandler) handlePEXPIRE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.expireGeneric(s, aof, args, "pexpire", 1, false)
+}
+
+// handleEXPIREAT: EXPIREAT key unix-time-seconds [NX|XX|GT|LT]
+func (h *CommandsHandler) handleEXPIREAT(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.expireGeneric(s, aof, args, "expireat", 1000, true)
+}
+
+// handlePEXPIREAT: PEXPIREAT key unix-time-milliseconds [NX|XX|GT|LT]
+func (h *CommandsHandler) handlePEXPIREAT(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.expireGeneric(s, aof, args, "pexpireat", 1, true)
+}
+
+// handlePERSIST: PERSIST key, trả về 1 nếu TTL đã bị xóa
+func (h *CommandsHandler) handlePERSIST(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("persist")
+  }
+
+  removed := s.PERSIST(args[0].Bulk)
+  if removed {
+    logCommand(s, aof, "PERSIST", args[0].Bulk)
+  }
+  return protocol.Value{Typ: "integer", Num: boolToInt(removed)}.Marshal()
+}
+
+// handlePTTL: PTTL key, thời gian sống còn lại tính bằng mili giây (-2: không tồn tại, -1: không có TTL)
+func (h *CommandsHandler) handlePTTL(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("pttl")
+  }
+  return protocol.Value{Typ: "integer", Num: int(s.PTTL(args[0].Bulk))}.Marshal()
+}
+
+// handleEXPIRETIME: EXPIRETIME key, thời điểm hết hạn tuyệt đối tính bằng giây
+func (h *CommandsHandler) handleEXPIRETIME(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("expiretime")
+  }
+
+  at := s.PEXPIRETIME(args[0].Bulk)
+  if at > 0 {
+    at /= 1000
+  }
+  return protocol.Value{Typ: "integer", Num: int(at)}.Marshal()
+}
+
+// handlePEXPIRETIME: PEXPIRETIME key, thời điểm hết hạn tuyệt đối tính bằng mili giây
+func (h *CommandsHandler) handlePEXPIRETIME(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("pexpiretime")
+  }
+  return protocol.Value{Typ: "integer", Num: int(s.PEXPIRETIME(args[0].Bulk))}.Marshal()
+}
+
+// StartActiveExpire chạy vòng xóa key hết hạn chủ động trong nền.
+// Mỗi key bị xóa được ghi vào AOF dưới dạng DEL khi đang giữ khóa của Store, nên không chờ fsync
+// (với appendfsync always, syncLoop của AOF fsync các lệnh này). Trả về hàm dừng vòng xóa.
+func (h *CommandsHandler) StartActiveExpire() func() {
+  return h.store.StartActiveExpire(store.ActiveExpireInterval, store.ActiveExpireBudget, func(key string) {
+    logCommandNoWait(h.store, h.aof, "DEL", key)
+  })
+}
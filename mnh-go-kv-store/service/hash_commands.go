@@ -0,0 +1,319 @@
+package service
+
+import (
+  "math"
+  "strconv"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// hsetGeneric xử lý chung cho HSET và HMSET (chỉ khác nhau ở phản hồi)
+func (h *CommandsHandler) hsetGeneric(name string, s *store.Store, aof *store.AOF, args []protocol.Value) (int, []byte) {
+  if len(args) < 3 || len(args)%2 != 1 {
+    return 0, wrongArgsReply(strings.ToLower(name))
+  }
+
+  key := args[0].Bulk
+  // HSET có thể có nhiều cặp field-value
+  fieldsAdded := 0
+  for i := 1; i < len(args); i += 2 {
+    field := args[i].Bulk
+    value := args[i+1].Bulk
+    added, err := s.HSET(key, field, value)
+    if err != nil {
+      return 0, errorReply(err.Error())
+    }
+    if added {
+      fieldsAdded++
+    }
+  }
+
+  // Ghi lệnh vào AOF
+  commandParts := make([]string, len(args)+1)
+  commandParts[0] = "HSET"
+  commandParts[1] = key
+  for i := 1; i < len(args); i++ {
+    commandParts[i+1] = args[i].Bulk
+  }
+  logCommand(s, aof, commandParts...)
+
+  return fieldsAdded, nil
+}
+
+func (h *CommandsHandler) handleHSET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  fieldsAdded, errResp := h.hsetGeneric("HSET", s, aof, args)
+  if errResp != nil {
+    return errResp
+  }
+  return protocol.Value{Typ: "integer", Num: fieldsAdded}.Marshal()
+}
+
+func (h *CommandsHandler) handleHMSET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  _, errResp := h.hsetGeneric("HMSET", s, aof, args)
+  if errResp != nil {
+    return errResp
+  }
+  return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+}
+
+func (h *CommandsHandler) handleHSETNX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("hsetnx")
+  }
+
+  set, err := s.HSETNX(args[0].Bulk, args[1].Bulk, args[2].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if set {
+    logCommand(s, aof, "HSET", args[0].Bulk, args[1].Bulk, args[2].Bulk)
+  }
+  return protocol.Value{Typ: "integer", Num: boolToInt(set)}.Marshal()
+}
+
+func (h *CommandsHandler) handleHGET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return protocol.Value{Typ: "error", Str: "ERR wrong number of arguments for 'hget' command"}.Marshal()
+  }
+
+  key := args[0].Bulk
+  field := args[1].Bulk
+
+  value, found, err := s.HGET(key, field)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if !found {
+    return protocol.Value{Typ: "null"}.Marshal()
+  }
+  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+}
+
+func (h *CommandsHandler) handleHMGET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply("hmget")
+  }
+
+  values, found, err := s.HMGET(args[0].Bulk, argStrings(args[1:])...)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  array := make([]protocol.Value, len(values))
+  for i := range values {
+    if found[i] {
+      array[i] = protocol.Value{Typ: "bulk", Bulk: values[i]}
+    } else {
+      array[i] = protocol.Value{Typ: "null"}
+    }
+  }
+  return protocol.Value{Typ: "array", Array: array}.Marshal()
+}
+
+func (h *CommandsHandler) handleHGETALL(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return protocol.Value{Typ: "error", Str: "ERR wrong number of arguments for 'hgetall' command"}.Marshal()
+  }
+
+  key := args[0].Bulk
+  hash, err := s.HGETALL(key)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  // Map field -> value (RESP2: Array field và value xen kẽ)
+  array := make([]protocol.Value, len(hash)*2)
+  idx := 0
+  for field, value := range hash {
+    array[idx] = protocol.Value{Typ: "bulk", Bulk: field}
+    array[idx+1] = protocol.Value{Typ: "bulk", Bulk: value}
+    idx += 2
+  }
+
+  return protocol.Value{Typ: "map", Array: array}.Marshal()
+}
+
+func (h *CommandsHandler) handleHDEL(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply("hdel")
+  }
+
+  key := args[0].Bulk
+  fields := argStrings(args[1:])
+
+  removed, err := s.HDEL(key, fields...)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if removed > 0 {
+    logCommand(s, aof, append([]string{"HDEL", key}, fields...)...)
+  }
+  return protocol.Value{Typ: "integer", Num: removed}.Marshal()
+}
+
+func (h *CommandsHandler) handleHEXISTS(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("hexists")
+  }
+
+  exists, err := s.HEXISTS(args[0].Bulk, args[1].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: boolToInt(exists)}.Marshal()
+}
+
+func (h *CommandsHandler) handleHLEN(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("hlen")
+  }
+
+  length, err := s.HLEN(args[0].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: length}.Marshal()
+}
+
+func (h *CommandsHandler) handleHSTRLEN(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("hstrlen")
+  }
+
+  length, err := s.HSTRLEN(args[0].Bulk, args[1].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: length}.Marshal()
+}
+
+func (h *CommandsHandler) handleHKEYS(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("hkeys")
+  }
+
+  fields, err := s.HKEYS(args[0].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return bulkArrayReply(fields)
+}
+
+func (h *CommandsHandler) handleHVALS(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("hvals")
+  }
+
+  values, err := s.HVALS(args[0].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return bulkArrayReply(values)
+}
+
+func (h *CommandsHandler) handleHINCRBY(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("hincrby")
+  }
+
+  increment, err := strconv.ParseInt(args[2].Bulk, 10, 64)
+  if err != nil {
+    return errorReply(errNotInteger)
+  }
+
+  value, err := s.HINCRBY(args[0].Bulk, args[1].Bulk, increment)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  logCommand(s, aof, "HINCRBY", args[0].Bulk, args[1].Bulk, args[2].Bulk)
+  return protocol.Value{Typ: "integer", Num: int(value)}.Marshal()
+}
+
+// handleHINCRBYFLOAT ghi AOF dưới dạng HSET với giá trị kết quả để phát lại không phụ thuộc
+// vào sai số làm tròn của phép cộng số thực
+func (h *CommandsHandler) handleHINCRBYFLOAT(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("hincrbyfloat")
+  }
+
+  increment, err := strconv.ParseFloat(args[2].Bulk, 64)
+  if err != nil || math.IsNaN(increment) || math.IsInf(increment, 0) {
+    return errorReply("ERR value is not a valid float")
+  }
+
+  // Giá trị tuyệt đối được ghi dưới dạng HSET khi còn giữ khóa của Store, như INCRBYFLOAT
+  var seq uint64
+  value, err := s.HINCRBYFLOAT(args[0].Bulk, args[1].Bulk, increment, func(value string) {
+    seq = logCommandNoWait(s, aof, "HSET", args[0].Bulk, args[1].Bulk, value)
+  })
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  waitLogged(aof, seq)
+  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+}
+
+// handleHSCAN: HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+func (h *CommandsHandler) handleHSCAN(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply("hscan")
+  }
+
+  cursor, err := strconv.ParseUint(args[1].Bulk, 10, 64)
+  if err != nil {
+    return errorReply("ERR invalid cursor")
+  }
+
+  match, count, noValues := "", 10, false
+  for i := 2; i < len(args); i++ {
+    switch strings.ToUpper(args[i].Bulk) {
+    case "MATCH":
+      if i+1 >= len(args) {
+        return errorReply("ERR syntax error")
+      }
+      match = args[i+1].Bulk
+      i++
+    case "COUNT":
+      if i+1 >= len(args) {
+        return errorReply("ERR syntax error")
+      }
+      n, err := strconv.Atoi(args[i+1].Bulk)
+      if err != nil {
+        return errorReply(errNotInteger)
+      }
+      if n < 1 {
+        return errorReply("ERR syntax error")
+      }
+      count = n
+      i++
+    case "NOVALUES":
+      noValues = true
+    default:
+      return errorReply("ERR syntax error")
+    }
+  }
+
+  next, fields, values, err := s.HSCAN(args[0].Bulk, cursor, match, count)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  items := make([]string, 0, len(fields)*2)
+  for i, field := range fields {
+    items = append(items, field)
+    if !noValues {
+      items = append(items, values[i])
+    }
+  }
+
+  return protocol.Value{Typ: "array", Array: []protocol.Value{
+    {Typ: "bulk", Bulk: strconv.FormatUint(next, 10)},
+    {Typ: "array", Array: bulkArray(items)},
+  }}.Marshal()
+}
@@ -0,0 +1,7 @@
+package service
+
+import "testing"
+
+func TestHINCRBYFLOATConcurrentReplay(t *testing.T) {
+  checkConcurrentReplay(t, []string{"HINCRBYFLOAT", "h", "f", "0.5"}, []string{"HGET", "h", "f"})
+}
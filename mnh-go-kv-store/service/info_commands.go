@@ -0,0 +1,113 @@
+package service
+
+import (
+  "fmt"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// infoSection là một mục của lệnh INFO, trả về các dòng "field:value"
+type infoSection struct {
+  name   string
+  fields func(h *CommandsHandler) []string
+}
+
+// infoSections liệt kê các mục theo thứ tự hiển thị
+var infoSections = []infoSection{
+  {name: "Memory", fields: memoryInfo},
+  {name: "Persistence", fields: persistenceInfo},
+  {name: "Stats", fields: statsInfo},
+  {name: "Keyspace", fields: keyspaceInfo},
+}
+
+// persistenceInfo: trạng thái snapshot RDB, AOF và việc ghi lại AOF
+func persistenceInfo(h *CommandsHandler) []string {
+  fields := rdbInfo(h)
+  if h.aof == nil {
+    return append(fields, "aof_enabled:0")
+  }
+
+  stats := h.aof.Stats()
+  status, writeStatus := "ok", "ok"
+  if stats.LastRewriteErr != nil {
+    status = "err"
+  }
+  if stats.LastFsyncErr != nil {
+    writeStatus = "err"
+  }
+  return append(fields,
+    "aof_enabled:1",
+    fmt.Sprintf("aof_fsync:%s", stats.FsyncPolicy),
+    fmt.Sprintf("aof_rewrite_in_progress:%d", boolToInt(stats.RewriteInProgress)),
+    fmt.Sprintf("aof_last_bgrewrite_status:%s", status),
+    fmt.Sprintf("aof_last_write_status:%s", writeStatus),
+    fmt.Sprintf("aof_rewrites:%d", stats.Rewrites),
+    fmt.Sprintf("aof_current_size:%d", stats.CurrentSize),
+    fmt.Sprintf("aof_base_size:%d", stats.BaseSize),
+    fmt.Sprintf("aof_pending_bio_fsync:%d", boolToInt(stats.PendingFsync)),
+  )
+}
+
+// rdbInfo: trạng thái snapshot RDB
+func rdbInfo(h *CommandsHandler) []string {
+  if h.rdb == nil {
+    return []string{fmt.Sprintf("rdb_changes_since_last_save:%d", h.store.Dirty())}
+  }
+
+  stats := h.rdb.Stats(h.store)
+  status := "ok"
+  if stats.LastErr != nil {
+    status = "err"
+  }
+  return []string{
+    fmt.Sprintf("rdb_changes_since_last_save:%d", stats.Changes),
+    fmt.Sprintf("rdb_bgsave_in_progress:%d", boolToInt(stats.BgsaveInProgress)),
+    fmt.Sprintf("rdb_last_save_time:%d", stats.LastSave.Unix()),
+    fmt.Sprintf("rdb_last_bgsave_status:%s", status),
+  }
+}
+
+// statsInfo: các bộ đếm về key hết hạn
+func statsInfo(h *CommandsHandler) []string {
+  stats := h.store.ExpireStats()
+  return []string{
+    fmt.Sprintf("expired_keys:%d", stats.ExpiredKeys),
+    fmt.Sprintf("expired_time_cap_reached_count:%d", stats.TimeCapReached),
+    fmt.Sprintf("expire_cycle_cpu_milliseconds:%d", stats.CycleTime.Milliseconds()),
+  }
+}
+
+// keyspaceInfo: số key và số key có TTL
+func keyspaceInfo(h *CommandsHandler) []string {
+  keys, expires := h.store.KeyspaceStats()
+  if keys == 0 {
+    return nil
+  }
+  return []string{fmt.Sprintf("db0:keys=%d,expires=%d", keys, expires)}
+}
+
+// handleINFO: INFO [section ...], trả về các mục được yêu cầu (mặc định tất cả) dạng Verbatim String (Bulk String với RESP2)
+func (h *CommandsHandler) handleINFO(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  wanted := make(map[string]bool)
+  for _, arg := range args {
+    wanted[strings.ToLower(arg.Bulk)] = true
+  }
+  all := len(wanted) == 0 || wanted["all"] || wanted["default"] || wanted["everything"]
+
+  var b strings.Builder
+  for _, section := range infoSections {
+    if !all && !wanted[strings.ToLower(section.name)] {
+      continue
+    }
+    if b.Len() > 0 {
+      b.WriteString("\r\n")
+    }
+    b.WriteString("# " + section.name + "\r\n")
+    for _, field := range section.fields(h) {
+      b.WriteString(field + "\r\n")
+    }
+  }
+  return protocol.Value{Typ: "verbatim", Str: "txt", Bulk: b.String()}.Marshal()
+}
@@ -0,0 +1,430 @@
+package service
+
+import (
+  "math"
+  "strconv"
+  "strings"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// pushGeneric xử lý chung cho LPUSH/RPUSH/LPUSHX/RPUSHX
+func (h *CommandsHandler) pushGeneric(s *store.Store, name string, push func(string, ...string) (int, error), aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply(strings.ToLower(name))
+  }
+
+  key := args[0].Bulk
+  values := argStrings(args[1:])
+
+  length, err := push(key, values...)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  // Chỉ ghi AOF khi List thực sự thay đổi (LPUSHX/RPUSHX có thể không làm gì)
+  if length > 0 {
+    logCommand(s, aof, append([]string{name, key}, values...)...)
+    s.SignalKeyReady(key)
+  }
+
+  return protocol.Value{Typ: "integer", Num: length}.Marshal()
+}
+
+func (h *CommandsHandler) handleLPUSH(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.pushGeneric(s, "LPUSH", s.LPUSH, aof, args)
+}
+
+func (h *CommandsHandler) handleRPUSH(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.pushGeneric(s, "RPUSH", s.RPUSH, aof, args)
+}
+
+func (h *CommandsHandler) handleLPUSHX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.pushGeneric(s, "LPUSHX", s.LPUSHX, aof, args)
+}
+
+func (h *CommandsHandler) handleRPUSHX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.pushGeneric(s, "RPUSHX", s.RPUSHX, aof, args)
+}
+
+// popGeneric xử lý chung cho LPOP/RPOP với đối số count tùy chọn
+func (h *CommandsHandler) popGeneric(name string, pop func(string, int) ([]string, error), s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 1 || len(args) > 2 {
+    return wrongArgsReply(strings.ToLower(name))
+  }
+
+  key := args[0].Bulk
+  count := 1
+  hasCount := len(args) == 2
+  if hasCount {
+    n, err := strconv.Atoi(args[1].Bulk)
+    if err != nil || n < 0 {
+      return errorReply("ERR value is out of range, must be positive")
+    }
+    count = n
+  }
+
+  values, err := pop(key, count)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  // Ghi số phần tử thực sự đã lấy ra để phát lại AOF cho kết quả giống hệt
+  if len(values) > 0 {
+    logCommand(s, aof, name, key, strconv.Itoa(len(values)))
+  }
+
+  if values == nil {
+    return protocol.Value{Typ: "null"}.Marshal()
+  }
+  if !hasCount {
+    if len(values) == 0 {
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    return protocol.Value{Typ: "bulk", Bulk: values[0]}.Marshal()
+  }
+  return bulkArrayReply(values)
+}
+
+func (h *CommandsHandler) handleLPOP(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.popGeneric("LPOP", s.LPOP, s, aof, args)
+}
+
+func (h *CommandsHandler) handleRPOP(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.popGeneric("RPOP", s.RPOP, s, aof, args)
+}
+
+func (h *CommandsHandler) handleLLEN(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("llen")
+  }
+
+  length, err := s.LLEN(args[0].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: length}.Marshal()
+}
+
+func (h *CommandsHandler) handleLRANGE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("lrange")
+  }
+
+  start, err1 := strconv.Atoi(args[1].Bulk)
+  stop, err2 := strconv.Atoi(args[2].Bulk)
+  if err1 != nil || err2 != nil {
+    return errorReply(errNotInteger)
+  }
+
+  values, err := s.LRANGE(args[0].Bulk, start, stop)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return bulkArrayReply(values)
+}
+
+func (h *CommandsHandler) handleLINDEX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("lindex")
+  }
+
+  index, err := strconv.Atoi(args[1].Bulk)
+  if err != nil {
+    return errorReply(errNotInteger)
+  }
+
+  value, found, err := s.LINDEX(args[0].Bulk, index)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  if !found {
+    return protocol.Value{Typ: "null"}.Marshal()
+  }
+  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+}
+
+func (h *CommandsHandler) handleLSET(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("lset")
+  }
+
+  index, err := strconv.Atoi(args[1].Bulk)
+  if err != nil {
+    return errorReply(errNotInteger)
+  }
+
+  if err := s.LSET(args[0].Bulk, index, args[2].Bulk); err != nil {
+    return errorReply(err.Error())
+  }
+
+  logCommand(s, aof, "LSET", args[0].Bulk, strconv.Itoa(index), args[2].Bulk)
+  return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+}
+
+func (h *CommandsHandler) handleLTRIM(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("ltrim")
+  }
+
+  start, err1 := strconv.Atoi(args[1].Bulk)
+  stop, err2 := strconv.Atoi(args[2].Bulk)
+  if err1 != nil || err2 != nil {
+    return errorReply(errNotInteger)
+  }
+
+  if err := s.LTRIM(args[0].Bulk, start, stop); err != nil {
+    return errorReply(err.Error())
+  }
+
+  logCommand(s, aof, "LTRIM", args[0].Bulk, strconv.Itoa(start), strconv.Itoa(stop))
+  return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+}
+
+func (h *CommandsHandler) handleLINSERT(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 4 {
+    return wrongArgsReply("linsert")
+  }
+
+  where := strings.ToUpper(args[1].Bulk)
+  if where != "BEFORE" && where != "AFTER" {
+    return errorReply("ERR syntax error")
+  }
+
+  length, err := s.LINSERT(args[0].Bulk, where == "BEFORE", args[2].Bulk, args[3].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if length > 0 {
+    logCommand(s, aof, "LINSERT", args[0].Bulk, where, args[2].Bulk, args[3].Bulk)
+    s.SignalKeyReady(args[0].Bulk)
+  }
+  return protocol.Value{Typ: "integer", Num: length}.Marshal()
+}
+
+func (h *CommandsHandler) handleLREM(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("lrem")
+  }
+
+  count, err := strconv.Atoi(args[1].Bulk)
+  if err != nil {
+    return errorReply(errNotInteger)
+  }
+
+  removed, err := s.LREM(args[0].Bulk, count, args[2].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if removed > 0 {
+    logCommand(s, aof, "LREM", args[0].Bulk, strconv.Itoa(count), args[2].Bulk)
+  }
+  return protocol.Value{Typ: "integer", Num: removed}.Marshal()
+}
+
+// parseDirection chuyển LEFT/RIGHT thành front = true/false
+func parseDirection(arg string) (string, bool, bool) {
+  dir := strings.ToUpper(arg)
+  switch dir {
+  case "LEFT":
+    return dir, true, true
+  case "RIGHT":
+    return dir, false, true
+  }
+  return "", false, false
+}
+
+// lmoveGeneric thực hiện LMOVE và ghi AOF, dùng chung cho LMOVE và RPOPLPUSH
+func (h *CommandsHandler) lmoveGeneric(s *store.Store, aof *store.AOF, src, dst, from, to string) []byte {
+  _, srcFront, ok1 := parseDirection(from)
+  _, dstFront, ok2 := parseDirection(to)
+  if !ok1 || !ok2 {
+    return errorReply("ERR syntax error")
+  }
+
+  value, ok, err := s.LMOVE(src, dst, srcFront, dstFront)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  if !ok {
+    return protocol.Value{Typ: "null"}.Marshal()
+  }
+
+  logCommand(s, aof, "LMOVE", src, dst, strings.ToUpper(from), strings.ToUpper(to))
+  s.SignalKeyReady(dst)
+  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+}
+
+func (h *CommandsHandler) handleLMOVE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 4 {
+    return wrongArgsReply("lmove")
+  }
+  return h.lmoveGeneric(s, aof, args[0].Bulk, args[1].Bulk, args[2].Bulk, args[3].Bulk)
+}
+
+func (h *CommandsHandler) handleRPOPLPUSH(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("rpoplpush")
+  }
+  return h.lmoveGeneric(s, aof, args[0].Bulk, args[1].Bulk, "RIGHT", "LEFT")
+}
+
+// parseBlockTimeout đọc timeout (giây, có thể là số thực) của các lệnh blocking.
+// Trả về deadline rỗng nếu timeout = 0 (chờ vô hạn).
+func parseBlockTimeout(arg string) (time.Time, []byte) {
+  secs, err := strconv.ParseFloat(arg, 64)
+  if err != nil || math.IsNaN(secs) || math.IsInf(secs, 0) {
+    return time.Time{}, errorReply("ERR timeout is not a float or out of range")
+  }
+  if secs < 0 {
+    return time.Time{}, errorReply("ERR timeout is negative")
+  }
+  if secs == 0 {
+    return time.Time{}, nil
+  }
+  return time.Now().Add(time.Duration(secs * float64(time.Second))), nil
+}
+
+// waitForKeys chặn goroutine của kết nối cho tới khi waiter được phục vụ, hết hạn hoặc server dừng.
+// Trả về false nếu chưa được phục vụ.
+func (h *CommandsHandler) waitForKeys(s *store.Store, w *store.KeyWaiter, deadline time.Time) bool {
+  var timeout <-chan time.Time
+  if !deadline.IsZero() {
+    timer := time.NewTimer(time.Until(deadline))
+    defer timer.Stop()
+    timeout = timer.C
+  }
+
+  select {
+  case <-w.Ready():
+    return true
+  case <-timeout:
+  case <-h.closing:
+  }
+  // Waiter có thể vừa được phục vụ ngay trước khi hủy, khi đó vẫn trả kết quả
+  return s.CancelWait(w)
+}
+
+// blockingPopGeneric xử lý chung cho BLPOP/BRPOP.
+// Trong AOF lệnh được ghi lại dưới dạng LPOP/RPOP trên key thực sự đã lấy phần tử.
+func (h *CommandsHandler) blockingPopGeneric(name string, s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply(strings.ToLower(name))
+  }
+
+  deadline, errResp := parseBlockTimeout(args[len(args)-1].Bulk)
+  if errResp != nil {
+    return errResp
+  }
+  keys := argStrings(args[:len(args)-1])
+  front := name == "BLPOP"
+  popCmd := "RPOP"
+  if front {
+    popCmd = "LPOP"
+  }
+
+  // Lệnh chặn tự giữ gate khi thay đổi Store, không giữ trong lúc chờ (xem ungatedCommands)
+  // Waiter được phục vụ khi đang giữ khóa của Store: chỉ ghi AOF, việc chờ fsync làm sau khi thức dậy
+  var seq uint64
+  h.gate.RLock()
+  if h.frozen {
+    h.gate.RUnlock()
+    return errorReply(errShuttingDown.Error())
+  }
+  key, value, w, err := s.BPOP(keys, front, func(key, value string) {
+    seq = logCommandNoWait(s, aof, popCmd, key)
+  })
+  if err == nil && w == nil {
+    logCommand(s, aof, popCmd, key)
+  }
+  h.gate.RUnlock()
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if w != nil {
+    // Không bao giờ chặn khi đang phát lại AOF
+    if s.Loading() {
+      s.CancelWait(w)
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    if !h.waitForKeys(s, w, deadline) {
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    if w.Err != nil {
+      return errorReply(w.Err.Error())
+    }
+    if aof != nil {
+      aof.WaitSync(seq)
+    }
+    key, value = w.Key, w.Value
+  }
+  return bulkArrayReply([]string{key, value})
+}
+
+func (h *CommandsHandler) handleBLPOP(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.blockingPopGeneric("BLPOP", s, aof, args)
+}
+
+func (h *CommandsHandler) handleBRPOP(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.blockingPopGeneric("BRPOP", s, aof, args)
+}
+
+// handleBLMOVE: BLMOVE source destination LEFT|RIGHT LEFT|RIGHT timeout, ghi AOF dưới dạng LMOVE
+func (h *CommandsHandler) handleBLMOVE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 5 {
+    return wrongArgsReply("blmove")
+  }
+
+  src, dst := args[0].Bulk, args[1].Bulk
+  from, srcFront, ok1 := parseDirection(args[2].Bulk)
+  to, dstFront, ok2 := parseDirection(args[3].Bulk)
+  if !ok1 || !ok2 {
+    return errorReply("ERR syntax error")
+  }
+  deadline, errResp := parseBlockTimeout(args[4].Bulk)
+  if errResp != nil {
+    return errResp
+  }
+
+  var seq uint64
+  h.gate.RLock()
+  if h.frozen {
+    h.gate.RUnlock()
+    return errorReply(errShuttingDown.Error())
+  }
+  value, ok, w, err := s.BLMOVE(src, dst, srcFront, dstFront, func(key, value string) {
+    seq = logCommandNoWait(s, aof, "LMOVE", src, dst, from, to)
+  })
+  if err == nil && ok {
+    logCommand(s, aof, "LMOVE", src, dst, from, to)
+  }
+  h.gate.RUnlock()
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if !ok {
+    if s.Loading() {
+      s.CancelWait(w)
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    if !h.waitForKeys(s, w, deadline) {
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    if w.Err != nil {
+      return errorReply(w.Err.Error())
+    }
+    if aof != nil {
+      aof.WaitSync(seq)
+    }
+    value = w.Value
+  }
+
+  s.SignalKeyReady(dst)
+  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+}
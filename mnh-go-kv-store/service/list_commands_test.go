@@ -0,0 +1,66 @@
+package service
+
+import (
+  "path/filepath"
+  "testing"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// command tạo lệnh dạng Array các Bulk String như client gửi
+func command(parts ...string) protocol.Value {
+  return protocol.Value{Typ: "array", Array: bulkArray(parts)}
+}
+
+func TestBlockingPopFsyncAlways(t *testing.T) {
+  dir := filepath.Join(t.TempDir(), "appendonlydir")
+  aof, err := store.NewAOF(dir, "appendonly.aof")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer aof.Close()
+  aof.SetFsyncPolicy(store.FsyncAlways)
+  handler := NewCommandsHandler(store.NewStore(), aof)
+
+  replies := make(chan string, 2)
+  for _, cmd := range [][]string{{"BLPOP", "list", "5"}, {"BLMOVE", "src", "dst", "LEFT", "RIGHT", "5"}} {
+    go func() { replies <- string(handler.HandleCommand(command(cmd...))) }()
+  }
+  // Chờ cả hai lệnh chặn được đăng ký trước khi ghi vào List
+  time.Sleep(50 * time.Millisecond)
+  handler.HandleCommand(command("RPUSH", "list", "a"))
+  handler.HandleCommand(command("RPUSH", "src", "b"))
+
+  want := map[string]bool{"*2\r\n$4\r\nlist\r\n$1\r\na\r\n": true, "$1\r\nb\r\n": true}
+  for range 2 {
+    select {
+    case reply := <-replies:
+      if !want[reply] {
+        t.Fatalf("unexpected reply %q", reply)
+      }
+      delete(want, reply)
+    case <-time.After(5 * time.Second):
+      t.Fatal("blocked command was not served")
+    }
+  }
+  // Lệnh POP/LMOVE của waiter đã được fsync trước khi client nhận phản hồi
+  if aof.Stats().PendingFsync {
+    t.Fatal("a served blocking command replied before its AOF entry was fsynced")
+  }
+
+  reloaded := NewCommandsHandler(store.NewStore(), nil)
+  if err := aof.ReadAndLoad(reloaded); err != nil {
+    t.Fatal(err)
+  }
+  for cmd, want := range map[string]string{
+    "list": "*0\r\n",
+    "src":  "*0\r\n",
+    "dst":  "*1\r\n$1\r\nb\r\n",
+  } {
+    if got := string(reloaded.HandleCommand(command("LRANGE", cmd, "0", "-1"))); got != want {
+      t.Errorf("LRANGE %s after reloading the AOF = %q, want %q", cmd, got, want)
+    }
+  }
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+  "fmt"
+  "runtime/metrics"
+)
+
+// errOOM được trả về cho các lệnh cần thêm bộ nhớ khi bộ nhớ đang dùng vượt quá maxmemory
+const errOOM = "OOM command not allowed when used memory > 'maxmemory'."
+
+// denyOOMCommands là các lệnh có thể làm tăng bộ nhớ; chúng bị từ chối khi vượt maxmemory
+// (chính sách noeviction), còn lệnh đọc và lệnh xóa vẫn được thực hiện để giải phóng bộ nhớ
+var denyOOMCommands = map[string]bool{
+  "SET": true, "SETNX": true, "SETEX": true, "PSETEX": true, "GETEX": true,
+  "INCR": true, "INCRBY": true, "DECR": true, "DECRBY": true, "INCRBYFLOAT": true,
+  "APPEND": true, "SETRANGE": true,
+  "HSET": true, "HMSET": true, "HSETNX": true, "HINCRBY": true, "HINCRBYFLOAT": true,
+  "LPUSH": true, "RPUSH": true, "LPUSHX": true, "RPUSHX": true, "LSET": true, "LINSERT": true,
+  "LMOVE": true, "RPOPLPUSH": true, "BLMOVE": true,
+  "SADD": true, "SINTERSTORE": true, "SUNIONSTORE": true, "SDIFFSTORE": true,
+  "ZADD": true, "ZINCRBY": true,
+}
+
+// heapMetric là số byte của các đối tượng trên heap (gồm cả đối tượng chưa được GC thu hồi)
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// usedMemory trả về bộ nhớ heap đang được sử dụng (byte)
+func usedMemory() int64 {
+  sample := []metrics.Sample{{Name: heapMetric}}
+  metrics.Read(sample)
+  if sample[0].Value.Kind() != metrics.KindUint64 {
+    return 0
+  }
+  return int64(sample[0].Value.Uint64())
+}
+
+// checkMemory trả về lỗi OOM nếu lệnh làm tăng bộ nhớ trong khi đã vượt maxmemory, ngược lại trả về nil
+func (h *CommandsHandler) checkMemory(commandName string) []byte {
+  if h.config == nil || !denyOOMCommands[commandName] {
+    return nil
+  }
+  limit := h.config.Settings().MaxMemory
+  if limit <= 0 || usedMemory() <= limit {
+    return nil
+  }
+  return errorReply(errOOM)
+}
+
+// memoryInfo: bộ nhớ đang dùng và giới hạn maxmemory
+func memoryInfo(h *CommandsHandler) []string {
+  var limit int64
+  if h.config != nil {
+    limit = h.config.Settings().MaxMemory
+  }
+  return []string{
+    fmt.Sprintf("used_memory:%d", usedMemory()),
+    fmt.Sprintf("maxmemory:%d", limit),
+    "maxmemory_policy:noeviction",
+  }
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+  "errors"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/logging"
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// saveRulesInterval là chu kỳ kiểm tra các quy tắc save
+const saveRulesInterval = time.Second
+
+// errRDBDisabled được trả về khi server chạy mà không cấu hình file snapshot
+var errRDBDisabled = errors.New("ERR RDB snapshots are not enabled")
+
+// EnableRDB gắn bộ quản lý snapshot cho SAVE/BGSAVE/LASTSAVE và các quy tắc save tự động
+func (h *CommandsHandler) EnableRDB(rdb *store.RDB) {
+  h.rdb = rdb
+}
+
+// bgsave bắt đầu lưu snapshot trong nền và ghi log kết quả
+func (h *CommandsHandler) bgsave() error {
+  if h.rdb == nil {
+    return errRDBDisabled
+  }
+
+  if err := h.beginJob(); err != nil {
+    return err
+  }
+  start := time.Now()
+  err := h.rdb.BackgroundSave(h.store, func(keys int, err error) {
+    defer h.endJob()
+    if err != nil {
+      logging.Warningf("Background saving failed: %v", err)
+      return
+    }
+    logging.Noticef("Background saving finished successfully (%d keys, %v)", keys, time.Since(start))
+  })
+  if err != nil {
+    h.endJob()
+  }
+  return err
+}
+
+// handleSAVE: SAVE, lưu snapshot đồng bộ (chặn client cho tới khi ghi xong)
+func (h *CommandsHandler) handleSAVE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 0 {
+    return wrongArgsReply("save")
+  }
+  if h.rdb == nil {
+    return errorReply(errRDBDisabled.Error())
+  }
+
+  if err := h.rdb.Save(s); err != nil {
+    if errors.Is(err, store.ErrBgsaveInProgress) {
+      return errorReply(err.Error())
+    }
+    logging.Warningf("Saving failed: %v", err)
+    return errorReply("ERR " + err.Error())
+  }
+  return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+}
+
+// handleBGSAVE: BGSAVE, lưu snapshot trong nền
+func (h *CommandsHandler) handleBGSAVE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 0 {
+    return wrongArgsReply("bgsave")
+  }
+  // Không bao giờ lưu snapshot khi đang phát lại AOF
+  if s.Loading() {
+    return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+  }
+
+  if err := h.bgsave(); err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "string", Str: "Background saving started"}.Marshal()
+}
+
+// handleLASTSAVE: LASTSAVE, thời điểm lưu snapshot thành công gần nhất (Unix giây)
+func (h *CommandsHandler) handleLASTSAVE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 0 {
+    return wrongArgsReply("lastsave")
+  }
+  if h.rdb == nil {
+    return errorReply(errRDBDisabled.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: int(h.rdb.Stats(s).LastSave.Unix())}.Marshal()
+}
+
+// StartSaveRules định kỳ kiểm tra các quy tắc save và chạy BGSAVE khi có quy tắc được thỏa.
+// Trả về hàm dừng việc kiểm tra.
+func (h *CommandsHandler) StartSaveRules() func() {
+  done := make(chan struct{})
+  stopped := make(chan struct{})
+
+  go func() {
+    defer close(stopped)
+    ticker := time.NewTicker(saveRulesInterval)
+    defer ticker.Stop()
+
+    for {
+      select {
+      case <-done:
+        return
+      case <-ticker.C:
+        if h.rdb != nil && h.rdb.ShouldSave(h.store) {
+          logging.Noticef("%d changes since last save, saving...", h.store.Dirty())
+          if err := h.bgsave(); err != nil {
+            logging.Warningf("Automatic background saving failed to start: %v", err)
+          }
+        }
+      }
+    }
+  }()
+
+  return func() {
+    close(done)
+    <-stopped
+  }
+}
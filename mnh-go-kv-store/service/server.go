@@ -1,92 +1,479 @@
 package service
 
 import (
+  "bufio"
+  "context"
+  "crypto/tls"
+  "errors"
   "fmt"
   "io"
-  "log"
   "net"
+  "os"
+  "strconv"
+  "strings"
+  "sync"
+  "sync/atomic"
+  "time"
 
+  "mnhgo/mnh-go-kv-store/internal/logging"
   "mnhgo/mnh-go-kv-store/internal/protocol"
 )
 
 const DefaultPort = ":6379"
 
+// ServerVersion là phiên bản server được báo trong HELLO
+const ServerVersion = "1.0.0"
+
+// replyBufferSize là kích thước buffer gom phản hồi của mỗi kết nối
+const replyBufferSize = 16 * 1024
+
+// ErrServerClosed được Start trả về khi server đã dừng trước đó
+var ErrServerClosed = errors.New("server closed")
+
 // Server chứa các thành phần mạng và logic xử lý lệnh
 type Server struct {
-  listener net.Listener
-  handler  *CommandsHandler // Tham chiếu đến bộ xử lý lệnh
+  handler *CommandsHandler // Tham chiếu đến bộ xử lý lệnh
+
+  mu        sync.Mutex
+  listeners []net.Listener        // TCP, TLS và Unix socket, mỗi listener có một vòng lặp chấp nhận kết nối
+  conns     map[net.Conn]struct{} // Các kết nối đang mở
+  lastID    atomic.Int64          // ID của kết nối gần nhất (báo trong HELLO)
+  closing   atomic.Bool           // Đã bắt đầu dừng: không nhận kết nối và lệnh mới (chỉ đặt khi giữ mu)
+  connWG    sync.WaitGroup        // Đếm các Goroutine xử lý kết nối
+
+  shutdownOnce sync.Once
+  shutdownErr  error
+  done         chan struct{} // Đóng khi việc dừng server đã hoàn tất
 }
 
 func NewServer(handler *CommandsHandler) *Server {
   return &Server{
     handler: handler,
+    conns:   make(map[net.Conn]struct{}),
+    done:    make(chan struct{}),
   }
 }
 
-// Start khởi động Listener và bắt đầu vòng lặp chấp nhận kết nối
+// Start lắng nghe kết nối TCP trên addr rồi phục vụ như Serve.
+// Start chặn cho tới khi server dừng hẳn (Shutdown hoặc lệnh SHUTDOWN) và trả về lỗi của việc dừng.
 func (s *Server) Start(addr string) error {
+  if err := s.Listen(addr); err != nil {
+    return err
+  }
+  return s.Serve()
+}
+
+// Listen mở listener TCP trên addr (mặc định DefaultPort). Kết nối chỉ được nhận sau khi gọi Serve.
+func (s *Server) Listen(addr string) error {
   if addr == "" {
     addr = DefaultPort
   }
+  listener, err := net.Listen("tcp", addr)
+  if err != nil {
+    return fmt.Errorf("failed to listen on %s: %w", addr, err)
+  }
+  logging.Noticef("KV Store listening on %s", addr)
+  return s.addListener(listener)
+}
 
-  var err error
-  s.listener, err = net.Listen("tcp", addr)
+// ListenTLS mở listener TLS trên addr với cấu hình config (chứng chỉ của server và, với mutual TLS,
+// các CA dùng để xác minh chứng chỉ của client). Có thể dùng cùng lúc với Listen trên cổng khác.
+func (s *Server) ListenTLS(addr string, config *tls.Config) error {
+  listener, err := tls.Listen("tcp", addr, config)
   if err != nil {
     return fmt.Errorf("failed to listen on %s: %w", addr, err)
   }
+  logging.Noticef("KV Store listening for TLS connections on %s", addr)
+  return s.addListener(listener)
+}
 
-  log.Printf("KV Store listening on %s", addr)
-  s.acceptLoop()
+// ListenUnix mở listener trên Unix socket path (dành cho tiến trình cùng máy, không tốn chi phí TCP).
+// File socket cũ còn sót lại (server trước bị dừng đột ngột) được xóa trước; perm khác 0 được đặt làm
+// quyền của file socket. File socket bị xóa khi server dừng.
+func (s *Server) ListenUnix(path string, perm os.FileMode) error {
+  if info, err := os.Lstat(path); err == nil {
+    if info.Mode()&os.ModeSocket == 0 {
+      return fmt.Errorf("failed to listen on %s: file exists and is not a socket", path)
+    }
+    os.Remove(path)
+  }
+  listener, err := net.Listen("unix", path)
+  if err != nil {
+    return fmt.Errorf("failed to listen on %s: %w", path, err)
+  }
+  if perm != 0 {
+    if err := os.Chmod(path, perm); err != nil {
+      listener.Close()
+      return fmt.Errorf("failed to set permissions of %s: %w", path, err)
+    }
+  }
+  logging.Noticef("KV Store listening on Unix socket %s", path)
+  return s.addListener(listener)
+}
 
+// addListener ghi nhận listener mới; trả về ErrServerClosed (và đóng listener) nếu server đang dừng
+func (s *Server) addListener(listener net.Listener) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  if s.closing.Load() {
+    listener.Close()
+    return ErrServerClosed
+  }
+  s.listeners = append(s.listeners, listener)
   return nil
 }
 
-// acceptLoop là vòng lặp chính chấp nhận kết nối và khởi tạo Goroutine xử lý
-func (s *Server) acceptLoop() {
+// Serve chấp nhận kết nối trên mọi listener đã mở bằng Listen/ListenTLS/ListenUnix và chặn cho tới khi
+// server dừng hẳn (Shutdown hoặc lệnh SHUTDOWN), rồi trả về lỗi của việc dừng
+func (s *Server) Serve() error {
+  s.mu.Lock()
+  listeners := s.listeners
+  s.mu.Unlock()
+  if len(listeners) == 0 {
+    return errors.New("no listener: call Listen, ListenTLS or ListenUnix before Serve")
+  }
+
+  for _, listener := range listeners {
+    go s.acceptLoop(listener)
+  }
+  <-s.done
+  return s.shutdownErr
+}
+
+// acceptLoop là vòng lặp chấp nhận kết nối của một listener và khởi tạo Goroutine xử lý
+func (s *Server) acceptLoop(listener net.Listener) {
   for {
-    conn, err := s.listener.Accept()
+    conn, err := listener.Accept()
     if err != nil {
-      log.Printf("Error accepting connection: %v", err)
+      if s.closing.Load() {
+        return
+      }
+      logging.Warningf("Error accepting connection: %v", err)
       continue
     }
+    if !s.trackConn(conn) {
+      conn.Close()
+      return
+    }
     // Xử lý mỗi kết nối trong một Goroutine riêng biệt
     go s.handleConn(conn)
   }
 }
 
+// trackConn ghi nhận kết nối mới; trả về false nếu server đang dừng
+func (s *Server) trackConn(conn net.Conn) bool {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  if s.closing.Load() {
+    return false
+  }
+  s.conns[conn] = struct{}{}
+  s.connWG.Add(1)
+  return true
+}
+
+// untrackConn xóa kết nối đã đóng
+func (s *Server) untrackConn(conn net.Conn) {
+  s.mu.Lock()
+  delete(s.conns, conn)
+  s.mu.Unlock()
+  s.connWG.Done()
+}
+
+// clientConn là trạng thái riêng của một kết nối client
+type clientConn struct {
+  id      int64
+  user    string                // User ACL đã xác thực ("" : phải gửi AUTH hoặc HELLO AUTH trước)
+  name    string                // Tên đặt bằng HELLO SETNAME
+  addr    net.Addr
+  replies *protocol.ReplyWriter // Ghi phản hồi theo phiên bản giao thức đã chọn bằng HELLO
+}
+
+// String mô tả kết nối trong log theo định dạng của CLIENT LIST
+func (c *clientConn) String() string {
+  return fmt.Sprintf("id=%d addr=%s name=%s user=%s", c.id, c.addr, c.name, c.user)
+}
+
 // handleConn xử lý một kết nối client duy nhất
 func (s *Server) handleConn(conn net.Conn) {
+  defer s.untrackConn(conn)
   defer conn.Close()
 
-  log.Printf("New connection from %s", conn.RemoteAddr())
+  logging.Verbosef("New connection from %s", conn.RemoteAddr())
 
   resp := protocol.NewResp(conn)
+  // Phản hồi được gom vào buffer và chỉ gửi khi đã xử lý hết các lệnh client gửi tới
+  // (pipeline), nên N lệnh pipeline chỉ tốn một lần ghi thay vì N lần
+  writer := bufio.NewWriterSize(conn, replyBufferSize)
+  defer writer.Flush()
+  client := &clientConn{
+    id:      s.lastID.Add(1),
+    user:    s.handler.initialUser(),
+    addr:    conn.RemoteAddr(),
+    replies: protocol.NewReplyWriter(writer),
+  }
 
   // Vòng lặp để đọc lệnh liên tục từ client
   for {
-    // 1. Đọc lệnh từ client (RESP format)
-    cmdValue, _, err := resp.Read()
+    // Server đang dừng: không nhận thêm lệnh (kể cả lệnh đã nằm trong buffer)
+    if s.closing.Load() {
+      return
+    }
+
+    // 1. Đọc lệnh từ client (RESP Array hoặc lệnh inline gõ qua telnet/nc)
+    cmdValue, _, err := resp.ReadCommand()
 
     if err != nil {
       if err == io.EOF {
-        log.Printf("Connection closed by client: %s", conn.RemoteAddr())
+        logging.Verbosef("Connection closed by client: %s", client)
+        return
+      }
+      if s.closing.Load() {
         return
       }
-      log.Printf("Error reading command from %s: %v", conn.RemoteAddr(), err)
+      logging.Verbosef("Error reading command from %s: %v", client, err)
 
-      // Gửi phản hồi lỗi giao thức và đóng kết nối
-      conn.Write(protocol.Value{Typ: "error", Str: "ERR protocol error"}.Marshal())
+      // Gửi phản hồi lỗi giao thức và đóng kết nối (lỗi kết nối thì chỉ đóng)
+      if protocol.IsProtocolError(err) {
+        writer.Write(protocol.Value{Typ: "error", Str: "ERR " + err.Error()}.Marshal())
+      }
       return
     }
 
-    // 2. Chuyển lệnh đã parse tới CommandsHandler
-    response := s.handler.HandleCommand(cmdValue)
+    // 2. Xác thực (AUTH, HELLO), kiểm tra quyền của user rồi chuyển lệnh đã parse tới CommandsHandler
+    var response []byte
+    name := commandName(cmdValue)
+    switch {
+    case name == "AUTH":
+      response = s.handleAUTH(cmdValue.Array[1:], client)
+    case name == "HELLO":
+      response = s.handleHELLO(cmdValue.Array[1:], client)
+    case client.user == "":
+      response = protocol.Value{Typ: "error", Str: errNoAuth.Error()}.Marshal()
+    default:
+      response = s.handler.checkACL(client.user, cmdValue)
+    }
+
+    switch {
+    case response != nil:
+      // Đã có phản hồi: AUTH, HELLO hoặc lỗi xác thực/phân quyền
+    case name == "ACL":
+      response = s.handler.handleACL(cmdValue.Array[1:], client.user)
+    case name == "SHUTDOWN":
+      mode, errResp := parseShutdownArgs(cmdValue.Array[1:])
+      if errResp == nil {
+        logging.Warningf("User requested shutdown...")
+        // Snapshot được lưu ngay trên kết nối này để có thể trả lỗi nếu lưu thất bại (server tiếp tục chạy).
+        // Thành công thì không có phản hồi: kết nối bị đóng khi server dừng (giống Redis).
+        ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+        if err := s.handler.saveBeforeShutdown(ctx, mode); err != nil {
+          cancel()
+          response = errorReply(err.Error())
+          break
+        }
+        go func() {
+          defer cancel()
+          s.shutdown(ctx, mode)
+        }()
+        return
+      }
+      response = errResp
+    default:
+      // Lệnh chặn có thể chờ rất lâu: gửi trước phản hồi của các lệnh trước đó
+      if blockingCommands[name] {
+        if err := writer.Flush(); err != nil {
+          logging.Verbosef("Error writing response to %s: %v", client, err)
+          return
+        }
+      }
+      response = s.handler.HandleCommand(cmdValue)
+    }
 
-    // 3. Gửi phản hồi RESP trở lại client
-    _, err = conn.Write(response)
+    // 3. Gom phản hồi (theo RESP2 hoặc RESP3), gửi khi không còn lệnh nào đang chờ trong buffer đọc
+    if err = client.replies.WriteReply(response); err == nil && resp.Buffered() == 0 {
+      err = writer.Flush()
+    }
     if err != nil {
-      log.Printf("Error writing response to %s: %v", conn.RemoteAddr(), err)
+      logging.Verbosef("Error writing response to %s: %v", client, err)
       return
     }
   }
 }
+
+// commandName trả về tên lệnh viết hoa ("" nếu lệnh không đúng định dạng)
+func commandName(cmdValue protocol.Value) string {
+  if cmdValue.Typ != "array" || len(cmdValue.Array) == 0 {
+    return ""
+  }
+  return strings.ToUpper(cmdValue.Array[0].Bulk)
+}
+
+// handleAUTH: AUTH [username] password, xác thực kết nối hiện tại với một user ACL
+// (không có username: user default, mật khẩu là requirepass)
+func (s *Server) handleAUTH(args []protocol.Value, client *clientConn) []byte {
+  var username, password string
+  switch len(args) {
+  case 1:
+    password = args[0].Bulk
+  case 2:
+    username, password = args[0].Bulk, args[1].Bulk
+  default:
+    return wrongArgsReply("auth")
+  }
+
+  user, err := s.handler.authenticate(username, password)
+  if err != nil {
+    logging.Verbosef("Authentication failed for %s", client)
+    return errorReply(err.Error())
+  }
+  client.user = user
+  return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+}
+
+// handleHELLO: HELLO [protover [AUTH username password] [SETNAME clientname]], chọn phiên bản giao thức
+// (2 hoặc 3) cho kết nối, có thể xác thực cùng lúc, rồi trả về thông tin server dạng Map.
+// Phản hồi của HELLO đã dùng phiên bản giao thức mới.
+func (s *Server) handleHELLO(args []protocol.Value, client *clientConn) []byte {
+  proto := client.replies.Proto
+  if len(args) > 0 {
+    version, err := strconv.Atoi(args[0].Bulk)
+    if err != nil {
+      return errorReply("ERR Protocol version is not an integer or out of range")
+    }
+    if version != 2 && version != 3 {
+      return errorReply("NOPROTO unsupported protocol version")
+    }
+    proto = version
+    args = args[1:]
+  }
+
+  var username, password, name string
+  hasAuth, hasName := false, false
+  for i := 0; i < len(args); i++ {
+    option := strings.ToUpper(args[i].Bulk)
+    switch {
+    case option == "AUTH" && i+2 < len(args):
+      username, password = args[i+1].Bulk, args[i+2].Bulk
+      hasAuth = true
+      i += 2
+    case option == "SETNAME" && i+1 < len(args):
+      name = args[i+1].Bulk
+      if !validClientName(name) {
+        return errorReply("ERR Client names cannot contain spaces, newlines or special characters.")
+      }
+      hasName = true
+      i++
+    default:
+      return errorReply(fmt.Sprintf("ERR Syntax error in HELLO option '%s'", args[i].Bulk))
+    }
+  }
+
+  if hasAuth {
+    // Xác thực giống hệt AUTH username password; user nopass chấp nhận mọi mật khẩu
+    user, err := s.handler.authenticate(username, password)
+    if err != nil {
+      logging.Verbosef("Authentication failed for %s", client)
+      return errorReply(err.Error())
+    }
+    client.user = user
+  }
+  if client.user == "" {
+    return errorReply("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+  }
+
+  if hasName {
+    client.name = name
+  }
+  client.replies.Proto = proto
+  return protocol.Value{Typ: "map", Array: []protocol.Value{
+    {Typ: "bulk", Bulk: "server"}, {Typ: "bulk", Bulk: "mnh-kv"},
+    {Typ: "bulk", Bulk: "version"}, {Typ: "bulk", Bulk: ServerVersion},
+    {Typ: "bulk", Bulk: "proto"}, {Typ: "integer", Num: proto},
+    {Typ: "bulk", Bulk: "id"}, {Typ: "integer", Num: int(client.id)},
+    {Typ: "bulk", Bulk: "mode"}, {Typ: "bulk", Bulk: "standalone"},
+    {Typ: "bulk", Bulk: "role"}, {Typ: "bulk", Bulk: "master"},
+    {Typ: "bulk", Bulk: "modules"}, {Typ: "array", Array: []protocol.Value{}},
+  }}.Marshal()
+}
+
+// validClientName kiểm tra tên client chỉ gồm các ký tự in được, không có khoảng trắng (giống Redis)
+func validClientName(name string) bool {
+  for i := 0; i < len(name); i++ {
+    if name[i] <= ' ' || name[i] > '~' {
+      return false
+    }
+  }
+  return true
+}
+
+// Shutdown dừng server an toàn: lưu snapshot nếu có quy tắc save, ngừng nhận kết nối, chờ các lệnh
+// đang chạy kết thúc (client đang chặn trong BLPOP/BRPOP/BLMOVE nhận về null), fsync AOF rồi đóng
+// mọi kết nối. Nếu ctx hết hạn trước khi các lệnh kết thúc, các kết nối còn lại bị đóng ngay.
+// Nếu không lưu được snapshot, Shutdown trả về ErrShutdownAborted và server vẫn chạy (có thể gọi lại).
+// Gọi nhiều lần (hoặc đồng thời với lệnh SHUTDOWN) chỉ dừng server một lần và trả về cùng kết quả.
+func (s *Server) Shutdown(ctx context.Context) error {
+  return s.shutdown(ctx, ShutdownDefault)
+}
+
+// shutdown dừng server với chế độ lưu snapshot mode (xem Shutdown)
+func (s *Server) shutdown(ctx context.Context, mode ShutdownMode) error {
+  // Lưu snapshot trước khi ngắt kết nối: nếu lỗi, server chưa bị thay đổi gì và tiếp tục chạy
+  if !s.closing.Load() {
+    if err := s.handler.saveBeforeShutdown(ctx, mode); err != nil {
+      return err
+    }
+  }
+
+  s.shutdownOnce.Do(func() {
+    s.shutdownErr = s.drainAndPersist(ctx)
+    if s.shutdownErr != nil {
+      logging.Warningf("Errors during shutdown: %v", s.shutdownErr)
+    } else {
+      logging.Noticef("Server is now ready to exit, bye bye...")
+    }
+    close(s.done)
+  })
+  <-s.done
+  return s.shutdownErr
+}
+
+// drainAndPersist thực hiện các bước của việc dừng server
+func (s *Server) drainAndPersist(ctx context.Context) error {
+  // 1. Ngừng nhận kết nối và ngắt các kết nối đang chờ đọc lệnh. Lệnh đang chạy vẫn ghi được
+  // phản hồi; sau đó vòng lặp của kết nối thấy closing và kết thúc.
+  s.mu.Lock()
+  s.closing.Store(true)
+  for _, listener := range s.listeners {
+    listener.Close()
+  }
+  for conn := range s.conns {
+    conn.SetReadDeadline(time.Now())
+  }
+  s.mu.Unlock()
+  s.handler.unblockClients()
+
+  // 2. Chờ các kết nối kết thúc
+  var errs []error
+  drained := make(chan struct{})
+  go func() {
+    s.connWG.Wait()
+    close(drained)
+  }()
+  select {
+  case <-drained:
+  case <-ctx.Done():
+    s.mu.Lock()
+    logging.Warningf("Shutdown: closing %d connections with commands still running", len(s.conns))
+    for conn := range s.conns {
+      conn.Close()
+    }
+    s.mu.Unlock()
+    errs = append(errs, fmt.Errorf("waiting for running commands: %w", ctx.Err()))
+  }
+
+  // 3. Fsync AOF (snapshot đã được lưu trước khi ngắt kết nối)
+  if err := s.handler.finishPersistence(ctx); err != nil {
+    errs = append(errs, err)
+  }
+  return errors.Join(errs...)
+}
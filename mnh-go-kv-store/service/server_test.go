@@ -0,0 +1,676 @@
+package service
+
+import (
+  "context"
+  "crypto/ecdsa"
+  "crypto/elliptic"
+  "crypto/rand"
+  "crypto/tls"
+  "crypto/x509"
+  "crypto/x509/pkix"
+  "encoding/pem"
+  "fmt"
+  "io"
+  "math/big"
+  "net"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/config"
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+  "mnhgo/mnh-go-kv-store/pkg/client"
+)
+
+// startServer chạy server của handler với các listener được mở bởi listen, trả về server và địa chỉ
+// của listener đầu tiên. Server được dừng khi test kết thúc.
+func startServer(t *testing.T, handler *CommandsHandler, listen func(*Server) error) (*Server, string) {
+  t.Helper()
+  server := NewServer(handler)
+  if err := listen(server); err != nil {
+    t.Fatal(err)
+  }
+  served := make(chan error, 1)
+  go func() { served <- server.Serve() }()
+  t.Cleanup(func() {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    server.Shutdown(ctx)
+    <-served
+  })
+
+  server.mu.Lock()
+  defer server.mu.Unlock()
+  return server, server.listeners[0].Addr().String()
+}
+
+// testPKI là các file chứng chỉ và khóa PEM được sinh cho một test
+type testPKI struct {
+  caCert     string
+  serverCert string
+  serverKey  string
+  clientCert string
+  clientKey  string
+}
+
+// newTestPKI sinh một CA, chứng chỉ server cho 127.0.0.1/localhost và chứng chỉ client do CA này ký
+func newTestPKI(t *testing.T, name string) testPKI {
+  t.Helper()
+  dir := t.TempDir()
+  ca, caKey := createCert(t, dir, name+"-ca", &x509.Certificate{
+    Subject:               pkix.Name{CommonName: name + " CA"},
+    IsCA:                  true,
+    KeyUsage:              x509.KeyUsageCertSign,
+    BasicConstraintsValid: true,
+  }, nil, nil)
+  createCert(t, dir, name+"-server", &x509.Certificate{
+    Subject:     pkix.Name{CommonName: "server"},
+    DNSNames:    []string{"localhost"},
+    IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+    KeyUsage:    x509.KeyUsageDigitalSignature,
+    ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+  }, ca, caKey)
+  createCert(t, dir, name+"-client", &x509.Certificate{
+    Subject:     pkix.Name{CommonName: "client"},
+    KeyUsage:    x509.KeyUsageDigitalSignature,
+    ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+  }, ca, caKey)
+
+  path := func(file string) string { return filepath.Join(dir, name+"-"+file) }
+  return testPKI{
+    caCert:     path("ca.crt"),
+    serverCert: path("server.crt"),
+    serverKey:  path("server.key"),
+    clientCert: path("client.crt"),
+    clientKey:  path("client.key"),
+  }
+}
+
+// createCert tạo chứng chỉ từ template, ký bởi parent (nil: tự ký), và ghi <base>.crt, <base>.key vào dir
+func createCert(t *testing.T, dir, base string, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+  t.Helper()
+  key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+  if err != nil {
+    t.Fatal(err)
+  }
+  serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+  if err != nil {
+    t.Fatal(err)
+  }
+  template.SerialNumber = serial
+  template.NotBefore = time.Now().Add(-time.Hour)
+  template.NotAfter = time.Now().Add(time.Hour)
+  if parent == nil {
+    parent, parentKey = template, key
+  }
+
+  der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+  if err != nil {
+    t.Fatal(err)
+  }
+  cert, err := x509.ParseCertificate(der)
+  if err != nil {
+    t.Fatal(err)
+  }
+  keyDER, err := x509.MarshalECPrivateKey(key)
+  if err != nil {
+    t.Fatal(err)
+  }
+  writePEM(t, filepath.Join(dir, base+".crt"), "CERTIFICATE", der)
+  writePEM(t, filepath.Join(dir, base+".key"), "EC PRIVATE KEY", keyDER)
+  return cert, key
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+  t.Helper()
+  if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+    t.Fatal(err)
+  }
+}
+
+// startTLSServer chạy server chỉ có listener TLS, cấu hình bằng tls-* giống khi đọc file cấu hình
+func startTLSServer(t *testing.T, pki testPKI, authClients string) string {
+  t.Helper()
+  cfg := config.New()
+  for name, value := range map[string]string{
+    "tls-cert-file":    pki.serverCert,
+    "tls-key-file":     pki.serverKey,
+    "tls-ca-cert-file": pki.caCert,
+    "tls-auth-clients": authClients,
+  } {
+    if err := cfg.Set(name, value); err != nil {
+      t.Fatal(err)
+    }
+  }
+  tlsConfig, err := cfg.Settings().TLSConfig()
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  _, addr := startServer(t, NewCommandsHandler(store.NewStore(), nil), func(s *Server) error {
+    return s.ListenTLS("127.0.0.1:0", tlsConfig)
+  })
+  return addr
+}
+
+// clientTLS tạo cấu hình TLS của client tin CA của pki, kèm chứng chỉ client của certPKI (nil: không có)
+func clientTLS(t *testing.T, pki testPKI, certPKI *testPKI) *tls.Config {
+  t.Helper()
+  pool, err := config.LoadCertPool(pki.caCert)
+  if err != nil {
+    t.Fatal(err)
+  }
+  tlsConfig := &tls.Config{RootCAs: pool}
+  if certPKI != nil {
+    cert, err := tls.LoadX509KeyPair(certPKI.clientCert, certPKI.clientKey)
+    if err != nil {
+      t.Fatal(err)
+    }
+    // Luôn gửi chứng chỉ, kể cả khi nó không do CA mà server yêu cầu ký (mặc định crypto/tls
+    // không gửi chứng chỉ như vậy), để test việc server xác minh chứng chỉ
+    tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+      return &cert, nil
+    }
+  }
+  return tlsConfig
+}
+
+// tlsPing kết nối bằng client.WithTLS và gửi PING. Với TLS 1.3 server chỉ từ chối chứng chỉ client
+// sau khi bắt tay xong phía client, nên lỗi có thể xuất hiện ở lần đọc đầu tiên.
+func tlsPing(addr string, tlsConfig *tls.Config) error {
+  c, err := client.NewClient(addr, client.WithTLS(tlsConfig))
+  if err != nil {
+    return err
+  }
+  defer c.Close()
+  reply, err := c.Do("PING")
+  if err != nil {
+    return err
+  }
+  if reply.Str != "PONG" {
+    return fmt.Errorf("unexpected PING reply %+v", reply)
+  }
+  return nil
+}
+
+func TestTLSClientAuth(t *testing.T) {
+  pki := newTestPKI(t, "trusted")
+  untrusted := newTestPKI(t, "untrusted")
+
+  tests := []struct {
+    authClients string
+    cert        *testPKI
+    wantOK      bool
+  }{
+    {"yes", &pki, true},
+    {"yes", nil, false},
+    {"yes", &untrusted, false},
+    {"optional", &pki, true},
+    {"optional", nil, true},
+    {"optional", &untrusted, false},
+    {"no", nil, true},
+  }
+  for _, tt := range tests {
+    name := tt.authClients + "/no-cert"
+    if tt.cert != nil {
+      name = tt.authClients + "/" + filepath.Base(tt.cert.clientCert)
+    }
+    t.Run(name, func(t *testing.T) {
+      addr := startTLSServer(t, pki, tt.authClients)
+      err := tlsPing(addr, clientTLS(t, pki, tt.cert))
+      if tt.wantOK && err != nil {
+        t.Fatalf("PING over TLS failed: %v", err)
+      }
+      if !tt.wantOK && err == nil {
+        t.Fatal("PING over TLS succeeded, want the client certificate to be rejected")
+      }
+    })
+  }
+}
+
+func TestTLSClientRejectsUnknownServer(t *testing.T) {
+  pki := newTestPKI(t, "trusted")
+  other := newTestPKI(t, "other")
+  addr := startTLSServer(t, pki, "no")
+  if err := tlsPing(addr, clientTLS(t, other, nil)); err == nil {
+    t.Fatal("client accepted a server certificate signed by an unknown CA")
+  }
+}
+
+func TestTLSConfigRequiresCA(t *testing.T) {
+  pki := newTestPKI(t, "trusted")
+  cfg := config.New()
+  cfg.Set("tls-cert-file", pki.serverCert)
+  cfg.Set("tls-key-file", pki.serverKey)
+  if _, err := cfg.Settings().TLSConfig(); err == nil {
+    t.Fatal("TLSConfig() without tls-ca-cert-file succeeded under tls-auth-clients yes")
+  }
+  cfg.Set("tls-auth-clients", "no")
+  if _, err := cfg.Settings().TLSConfig(); err != nil {
+    t.Fatalf("TLSConfig() under tls-auth-clients no: %v", err)
+  }
+}
+
+// dial kết nối tới server bằng pkg/client
+func dial(t *testing.T, addr string) *client.Client {
+  t.Helper()
+  c, err := client.NewClient(addr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { c.Close() })
+  return c
+}
+
+// shutdownNow dừng server và kiểm tra việc dừng không có lỗi
+func shutdownNow(t *testing.T, server *Server) {
+  t.Helper()
+  ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+  defer cancel()
+  if err := server.Shutdown(ctx); err != nil {
+    t.Fatalf("Shutdown() = %v", err)
+  }
+}
+
+func TestShutdownStopsAccepting(t *testing.T) {
+  server, addr := startServer(t, NewCommandsHandler(store.NewStore(), nil), func(s *Server) error {
+    return s.Listen("127.0.0.1:0")
+  })
+  c := dial(t, addr)
+  if reply, err := c.Do("PING"); err != nil || reply.Str != "PONG" {
+    t.Fatalf("PING = %+v, %v", reply, err)
+  }
+
+  shutdownNow(t, server)
+  if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+    conn.Close()
+    t.Fatal("the server still accepts connections after Shutdown")
+  }
+  if _, err := c.Do("PING"); err == nil {
+    t.Fatal("an idle connection is still served after Shutdown")
+  }
+}
+
+func TestShutdownWaitsForRunningCommand(t *testing.T) {
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  started, release := make(chan struct{}), make(chan struct{})
+  handler.commands["SLOWTEST"] = func(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+    close(started)
+    <-release
+    return protocol.Value{Typ: "string", Str: "OK"}.Marshal()
+  }
+  server, addr := startServer(t, handler, func(s *Server) error {
+    return s.Listen("127.0.0.1:0")
+  })
+
+  c := dial(t, addr)
+  replied := make(chan protocol.Value, 1)
+  go func() {
+    reply, _ := c.Do("SLOWTEST")
+    replied <- reply
+  }()
+  <-started
+
+  stopped := make(chan error, 1)
+  go func() { stopped <- server.Shutdown(context.Background()) }()
+  select {
+  case err := <-stopped:
+    t.Fatalf("Shutdown() returned %v while a command was still running", err)
+  case <-time.After(100 * time.Millisecond):
+  }
+
+  close(release)
+  if reply := <-replied; reply.Str != "OK" {
+    t.Fatalf("the running command got %+v, want its reply before the connection is closed", reply)
+  }
+  if err := <-stopped; err != nil {
+    t.Fatalf("Shutdown() = %v", err)
+  }
+}
+
+func TestShutdownSyncsAOF(t *testing.T) {
+  dir := filepath.Join(t.TempDir(), "appendonlydir")
+  aof, err := store.NewAOF(dir, "appendonly.aof")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer aof.Close()
+  // appendfsync no: lệnh chỉ được fsync khi Shutdown gọi fsync AOF
+  aof.SetFsyncPolicy(store.FsyncNo)
+
+  server, addr := startServer(t, NewCommandsHandler(store.NewStore(), aof), func(s *Server) error {
+    return s.Listen("127.0.0.1:0")
+  })
+  if reply, err := dial(t, addr).Do("SET", "key", "value"); err != nil || reply.Str != "OK" {
+    t.Fatalf("SET = %+v, %v", reply, err)
+  }
+  if !aof.Stats().PendingFsync {
+    t.Fatal("SET was fsynced under appendfsync no")
+  }
+  shutdownNow(t, server)
+  if aof.Stats().PendingFsync {
+    t.Fatal("the AOF has commands that were not fsynced after Shutdown")
+  }
+
+  // Đọc lại các file AOF trên đĩa bằng một AOF mới
+  reloaded, err := store.NewAOF(dir, "appendonly.aof")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer reloaded.Close()
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  if err := reloaded.ReadAndLoad(handler); err != nil {
+    t.Fatal(err)
+  }
+  got := handler.HandleCommand(protocol.Value{Typ: "array", Array: bulkArray([]string{"GET", "key"})})
+  if want := "$5\r\nvalue\r\n"; string(got) != want {
+    t.Fatalf("GET key after reloading the AOF = %q, want %q", got, want)
+  }
+}
+
+func TestShutdownCommandSaveModes(t *testing.T) {
+  tests := []struct {
+    command  []string
+    wantSave bool
+  }{
+    {[]string{"SHUTDOWN", "SAVE"}, true},
+    {[]string{"SHUTDOWN", "NOSAVE"}, false},
+  }
+  for _, tt := range tests {
+    t.Run(tt.command[1], func(t *testing.T) {
+      path := filepath.Join(t.TempDir(), "dump.rdb")
+      rdb := store.NewRDB(path)
+      // Có quy tắc save: SHUTDOWN không đối số sẽ lưu snapshot, NOSAVE phải bỏ qua việc lưu
+      rules, err := store.ParseSaveRules("3600 1")
+      if err != nil {
+        t.Fatal(err)
+      }
+      rdb.SetSaveRules(rules)
+      handler := NewCommandsHandler(store.NewStore(), nil)
+      handler.EnableRDB(rdb)
+      server, addr := startServer(t, handler, func(s *Server) error {
+        return s.Listen("127.0.0.1:0")
+      })
+
+      c := dial(t, addr)
+      if reply, err := c.Do("SET", "key", "value"); err != nil || reply.Str != "OK" {
+        t.Fatalf("SET = %+v, %v", reply, err)
+      }
+      // SHUTDOWN thành công không có phản hồi: kết nối bị đóng khi server dừng
+      if reply, err := c.Do(tt.command...); err == nil {
+        t.Fatalf("%v replied %+v, want the connection to be closed", tt.command, reply)
+      }
+      // Chờ lần dừng do lệnh SHUTDOWN kết thúc
+      shutdownNow(t, server)
+
+      keys, err := store.NewRDB(path).Load(store.NewStore())
+      if tt.wantSave && (err != nil || keys != 1) {
+        t.Fatalf("loading the snapshot after %v: %d keys, %v", tt.command, keys, err)
+      }
+      if _, err := os.Stat(path); !tt.wantSave && !os.IsNotExist(err) {
+        t.Fatalf("%v wrote a snapshot (stat: %v)", tt.command, err)
+      }
+    })
+  }
+}
+
+func TestShutdownAbortsWhenSaveFails(t *testing.T) {
+  // Thư mục của snapshot chưa tồn tại: mọi lần lưu đều lỗi cho tới khi tạo thư mục
+  dir := filepath.Join(t.TempDir(), "data")
+  path := filepath.Join(dir, "dump.rdb")
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  handler.EnableRDB(store.NewRDB(path))
+  server, addr := startServer(t, handler, func(s *Server) error {
+    return s.Listen("127.0.0.1:0")
+  })
+
+  c := dial(t, addr)
+  expectReply(t, c, "OK", "SET", "before", "value")
+  expectReply(t, c, ErrShutdownAborted.Error(), "SHUTDOWN", "SAVE")
+  ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+  defer cancel()
+  if err := server.shutdown(ctx, ShutdownSave); err != ErrShutdownAborted {
+    t.Fatalf("shutdown(SAVE) with a failing save = %v, want ErrShutdownAborted", err)
+  }
+
+  // Server vẫn phục vụ client như trước, kể cả kết nối mới và công việc nền
+  expectReply(t, c, "OK", "SET", "after", "value")
+  expectReply(t, dial(t, addr), "value", "GET", "before")
+  expectReply(t, c, "Background saving started", "BGSAVE")
+
+  // Sửa lỗi rồi dừng lại: snapshot có mọi thay đổi
+  if err := os.Mkdir(dir, 0755); err != nil {
+    t.Fatal(err)
+  }
+  if reply, err := c.Do("SHUTDOWN", "SAVE"); err == nil {
+    t.Fatalf("SHUTDOWN SAVE replied %+v, want the connection to be closed", reply)
+  }
+  shutdownNow(t, server)
+  if keys, err := store.NewRDB(path).Load(store.NewStore()); err != nil || keys != 2 {
+    t.Fatalf("loading the snapshot: %d keys, %v, want 2 keys", keys, err)
+  }
+}
+
+func TestShutdownSaveFreezesStore(t *testing.T) {
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  handler.EnableRDB(store.NewRDB(filepath.Join(t.TempDir(), "dump.rdb")))
+  if err := handler.saveBeforeShutdown(context.Background(), ShutdownSave); err != nil {
+    t.Fatal(err)
+  }
+  // Sau khi đã lưu snapshot cuối cùng, không lệnh nào được thay đổi Store nữa
+  for _, cmd := range [][]string{{"SET", "key", "value"}, {"BLPOP", "list", "0"}, {"BLMOVE", "a", "b", "LEFT", "LEFT", "0"}} {
+    if got := string(handler.HandleCommand(command(cmd...))); got != "-"+errShuttingDown.Error()+"\r\n" {
+      t.Errorf("%v after the final save = %q", cmd, got)
+    }
+  }
+}
+
+// rawConn gửi lệnh và đọc phản hồi dạng byte thô, để kiểm tra đúng kiểu RESP mà server ghi ra
+type rawConn struct {
+  t    *testing.T
+  conn net.Conn
+  resp *protocol.Resp
+}
+
+func dialRaw(t *testing.T, addr string) *rawConn {
+  t.Helper()
+  conn, err := net.Dial("tcp", addr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { conn.Close() })
+  conn.SetDeadline(time.Now().Add(5 * time.Second))
+  return &rawConn{t: t, conn: conn, resp: protocol.NewResp(conn)}
+}
+
+// do gửi lệnh và trả về phản hồi đã giải mã
+func (c *rawConn) do(parts ...string) protocol.Value {
+  c.t.Helper()
+  if _, err := c.conn.Write(protocol.AppendCommand(nil, parts)); err != nil {
+    c.t.Fatal(err)
+  }
+  reply, _, err := c.resp.Read()
+  if err != nil {
+    c.t.Fatal(err)
+  }
+  return reply
+}
+
+// expectRaw gửi lệnh và kiểm tra phản hồi trên đường truyền đúng bằng want
+func (c *rawConn) expectRaw(want string, parts ...string) {
+  c.t.Helper()
+  if _, err := c.conn.Write(protocol.AppendCommand(nil, parts)); err != nil {
+    c.t.Fatal(err)
+  }
+  got := make([]byte, len(want))
+  if _, err := io.ReadFull(c.conn, got); err != nil {
+    c.t.Fatalf("%v: %v", parts, err)
+  }
+  if string(got) != want {
+    c.t.Errorf("%v = %q, want %q", parts, got, want)
+  }
+}
+
+func TestHELLOProtocolReplies(t *testing.T) {
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  for _, cmd := range [][]string{{"HSET", "h", "f", "v"}, {"SADD", "s", "a"}, {"ZADD", "z", "1.5", "m"}} {
+    handler.HandleCommand(command(cmd...))
+  }
+  _, addr := startServer(t, handler, func(s *Server) error {
+    return s.Listen("127.0.0.1:0")
+  })
+
+  tests := []struct {
+    cmd          []string
+    resp2, resp3 string
+  }{
+    {[]string{"HGETALL", "h"}, "*2\r\n$1\r\nf\r\n$1\r\nv\r\n", "%1\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+    {[]string{"SMEMBERS", "s"}, "*1\r\n$1\r\na\r\n", "~1\r\n$1\r\na\r\n"},
+    {[]string{"ZSCORE", "z", "m"}, "$3\r\n1.5\r\n", ",1.5\r\n"},
+    {[]string{"GET", "missing"}, "$-1\r\n", "_\r\n"},
+  }
+  for _, hello := range []string{"", "2", "3"} {
+    name := "no HELLO"
+    if hello != "" {
+      name = "HELLO " + hello
+    }
+    t.Run(name, func(t *testing.T) {
+      c := dialRaw(t, addr)
+      if hello != "" {
+        reply := c.do("HELLO", hello)
+        wantTyp := map[string]string{"2": "array", "3": "map"}[hello]
+        if reply.Typ != wantTyp {
+          t.Fatalf("HELLO %s replied with a %s, want a %s", hello, reply.Typ, wantTyp)
+        }
+      }
+      for _, tt := range tests {
+        want := tt.resp2
+        if hello == "3" {
+          want = tt.resp3
+        }
+        c.expectRaw(want, tt.cmd...)
+      }
+    })
+  }
+
+  // HELLO 2 sau HELLO 3 đưa kết nối về RESP2
+  c := dialRaw(t, addr)
+  c.do("HELLO", "3")
+  c.do("HELLO", "2")
+  c.expectRaw(tests[0].resp2, tests[0].cmd...)
+}
+
+func TestHELLOErrors(t *testing.T) {
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  handler.acl.SetDefaultPassword("secret")
+  _, addr := startServer(t, handler, func(s *Server) error {
+    return s.Listen("127.0.0.1:0")
+  })
+
+  tests := []struct {
+    hello []string
+    want  string
+  }{
+    {[]string{"HELLO", "3"}, "NOAUTH HELLO must be called with the client already authenticated"},
+    {[]string{"HELLO"}, "NOAUTH HELLO must be called with the client already authenticated"},
+    {[]string{"HELLO", "4", "AUTH", "default", "secret"}, "NOPROTO unsupported protocol version"},
+    {[]string{"HELLO", "1"}, "NOPROTO unsupported protocol version"},
+    {[]string{"HELLO", "three"}, "ERR Protocol version is not an integer or out of range"},
+    {[]string{"HELLO", "3", "AUTH", "default"}, "ERR Syntax error in HELLO option 'AUTH'"},
+  }
+  for _, tt := range tests {
+    c := dialRaw(t, addr)
+    reply := c.do(tt.hello...)
+    if reply.Typ != "error" || !strings.HasPrefix(reply.Str, tt.want) {
+      t.Errorf("%v = %s %q, want %q", tt.hello, reply.Typ, reply.Str, tt.want)
+    }
+    // HELLO lỗi không xác thực kết nối và không đổi giao thức
+    c.expectRaw("-NOAUTH Authentication required.\r\n", "GET", "missing")
+  }
+
+  // HELLO với phiên bản không hỗ trợ giữ nguyên giao thức đã chọn
+  c := dialRaw(t, addr)
+  if reply := c.do("HELLO", "3", "AUTH", "default", "secret"); reply.Typ != "map" {
+    t.Fatalf("HELLO 3 AUTH replied with a %s, want a map", reply.Typ)
+  }
+  c.do("HELLO", "4")
+  c.expectRaw("_\r\n", "GET", "missing")
+}
+
+func TestListenUnix(t *testing.T) {
+  tests := []struct {
+    name    string
+    setup   func(t *testing.T, path string) // Tạo sẵn file tại path trước khi listen
+    perm    os.FileMode
+    wantErr string // Rỗng: listen thành công
+  }{
+    {"new socket", nil, 0700, ""},
+    {"default permissions", nil, 0, ""},
+    {"stale socket is replaced", func(t *testing.T, path string) {
+      listener, err := net.Listen("unix", path)
+      if err != nil {
+        t.Fatal(err)
+      }
+      // Giữ lại file socket như khi server trước bị dừng đột ngột
+      listener.(*net.UnixListener).SetUnlinkOnClose(false)
+      listener.Close()
+    }, 0770, ""},
+    {"regular file", func(t *testing.T, path string) {
+      if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+        t.Fatal(err)
+      }
+    }, 0700, "file exists and is not a socket"},
+    {"missing directory", func(t *testing.T, path string) {
+      if err := os.Remove(filepath.Dir(path)); err != nil {
+        t.Fatal(err)
+      }
+    }, 0700, "failed to listen"},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      path := filepath.Join(t.TempDir(), "sub", "kv.sock")
+      if err := os.Mkdir(filepath.Dir(path), 0755); err != nil {
+        t.Fatal(err)
+      }
+      if tt.setup != nil {
+        tt.setup(t, path)
+      }
+
+      if tt.wantErr != "" {
+        err := NewServer(NewCommandsHandler(store.NewStore(), nil)).ListenUnix(path, tt.perm)
+        if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+          t.Fatalf("ListenUnix() = %v, want an error containing %q", err, tt.wantErr)
+        }
+        return
+      }
+
+      server, addr := startServer(t, NewCommandsHandler(store.NewStore(), nil), func(s *Server) error {
+        return s.ListenUnix(path, tt.perm)
+      })
+      info, err := os.Lstat(path)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if info.Mode()&os.ModeSocket == 0 {
+        t.Fatalf("%s has mode %v, want a socket", path, info.Mode())
+      }
+      if tt.perm != 0 && info.Mode().Perm() != tt.perm {
+        t.Errorf("socket permissions = %v, want %v", info.Mode().Perm(), tt.perm)
+      }
+
+      c := dial(t, "unix://"+addr)
+      if reply, err := c.Do("PING"); err != nil || reply.Str != "PONG" {
+        t.Fatalf("PING = %+v, %v", reply, err)
+      }
+
+      // File socket bị xóa khi server dừng
+      shutdownNow(t, server)
+      if _, err := os.Lstat(path); !os.IsNotExist(err) {
+        t.Errorf("socket file after Shutdown: %v", err)
+      }
+    })
+  }
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+  "strconv"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+func (h *CommandsHandler) handleSADD(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply("sadd")
+  }
+
+  key := args[0].Bulk
+  members := argStrings(args[1:])
+
+  added, err := s.SADD(key, members...)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if added > 0 {
+    logCommand(s, aof, append([]string{"SADD", key}, members...)...)
+  }
+  return protocol.Value{Typ: "integer", Num: added}.Marshal()
+}
+
+func (h *CommandsHandler) handleSREM(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply("srem")
+  }
+
+  key := args[0].Bulk
+  members := argStrings(args[1:])
+
+  removed, err := s.SREM(key, members...)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if removed > 0 {
+    logCommand(s, aof, append([]string{"SREM", key}, members...)...)
+  }
+  return protocol.Value{Typ: "integer", Num: removed}.Marshal()
+}
+
+func (h *CommandsHandler) handleSMEMBERS(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("smembers")
+  }
+
+  members, err := s.SMEMBERS(args[0].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return setReply(members)
+}
+
+func (h *CommandsHandler) handleSISMEMBER(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("sismember")
+  }
+
+  isMember, err := s.SISMEMBER(args[0].Bulk, args[1].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: boolToInt(isMember)}.Marshal()
+}
+
+func (h *CommandsHandler) handleSMISMEMBER(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply("smismember")
+  }
+
+  results, err := s.SMISMEMBER(args[0].Bulk, argStrings(args[1:])...)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  array := make([]protocol.Value, len(results))
+  for i, isMember := range results {
+    array[i] = protocol.Value{Typ: "integer", Num: boolToInt(isMember)}
+  }
+  return protocol.Value{Typ: "array", Array: array}.Marshal()
+}
+
+func (h *CommandsHandler) handleSCARD(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("scard")
+  }
+
+  card, err := s.SCARD(args[0].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: card}.Marshal()
+}
+
+// handleSPOP: SPOP key [count]. Các phần tử bị lấy ra ngẫu nhiên nên AOF ghi lại
+// dưới dạng SREM với đúng các phần tử đó để phát lại cho kết quả giống hệt.
+func (h *CommandsHandler) handleSPOP(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 1 || len(args) > 2 {
+    return wrongArgsReply("spop")
+  }
+
+  key := args[0].Bulk
+  count := 1
+  hasCount := len(args) == 2
+  if hasCount {
+    n, err := strconv.Atoi(args[1].Bulk)
+    if err != nil || n < 0 {
+      return errorReply("ERR value is out of range, must be positive")
+    }
+    count = n
+  }
+
+  members, err := s.SPOP(key, count)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if len(members) > 0 {
+    logCommand(s, aof, append([]string{"SREM", key}, members...)...)
+  }
+
+  if !hasCount {
+    if len(members) == 0 {
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    return protocol.Value{Typ: "bulk", Bulk: members[0]}.Marshal()
+  }
+  if members == nil {
+    members = []string{}
+  }
+  return bulkArrayReply(members)
+}
+
+func (h *CommandsHandler) handleSRANDMEMBER(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 1 || len(args) > 2 {
+    return wrongArgsReply("srandmember")
+  }
+
+  count := 1
+  hasCount := len(args) == 2
+  if hasCount {
+    n, err := strconv.Atoi(args[1].Bulk)
+    if err != nil {
+      return errorReply(errNotInteger)
+    }
+    count = n
+  }
+
+  members, err := s.SRANDMEMBER(args[0].Bulk, count)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if !hasCount {
+    if len(members) == 0 {
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    return protocol.Value{Typ: "bulk", Bulk: members[0]}.Marshal()
+  }
+  return bulkArrayReply(members)
+}
+
+// setAlgebraGeneric xử lý chung cho SINTER/SUNION/SDIFF
+func (h *CommandsHandler) setAlgebraGeneric(name string, op func(...string) ([]string, error), args []protocol.Value) []byte {
+  if len(args) < 1 {
+    return wrongArgsReply(strings.ToLower(name))
+  }
+
+  members, err := op(argStrings(args)...)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return setReply(members)
+}
+
+// setAlgebraStoreGeneric xử lý chung cho SINTERSTORE/SUNIONSTORE/SDIFFSTORE.
+// Kết quả chỉ phụ thuộc vào trạng thái Store nên lệnh được ghi nguyên vẹn vào AOF.
+func (h *CommandsHandler) setAlgebraStoreGeneric(name string, op func(string, ...string) (int, error), s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply(strings.ToLower(name))
+  }
+
+  parts := argStrings(args)
+  card, err := op(parts[0], parts[1:]...)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  logCommand(s, aof, append([]string{name}, parts...)...)
+  return protocol.Value{Typ: "integer", Num: card}.Marshal()
+}
+
+func (h *CommandsHandler) handleSINTER(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.setAlgebraGeneric("SINTER", s.SINTER, args)
+}
+
+func (h *CommandsHandler) handleSUNION(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.setAlgebraGeneric("SUNION", s.SUNION, args)
+}
+
+func (h *CommandsHandler) handleSDIFF(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.setAlgebraGeneric("SDIFF", s.SDIFF, args)
+}
+
+func (h *CommandsHandler) handleSINTERSTORE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.setAlgebraStoreGeneric("SINTERSTORE", s.SINTERSTORE, s, aof, args)
+}
+
+func (h *CommandsHandler) handleSUNIONSTORE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.setAlgebraStoreGeneric("SUNIONSTORE", s.SUNIONSTORE, s, aof, args)
+}
+
+func (h *CommandsHandler) handleSDIFFSTORE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.setAlgebraStoreGeneric("SDIFFSTORE", s.SDIFFSTORE, s, aof, args)
+}
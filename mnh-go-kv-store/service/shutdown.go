@@ -0,0 +1,147 @@
+package service
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "strings"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/logging"
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+)
+
+// ShutdownTimeout là thời gian tối đa chờ các lệnh đang chạy và các lần lưu nền kết thúc khi dừng server
+const ShutdownTimeout = 10 * time.Second
+
+// ShutdownMode quyết định có lưu snapshot RDB khi dừng server hay không
+type ShutdownMode int
+
+const (
+  ShutdownDefault ShutdownMode = iota // Lưu snapshot nếu có quy tắc save (giống Redis)
+  ShutdownSave                        // Luôn lưu snapshot (SHUTDOWN SAVE)
+  ShutdownNoSave                      // Không lưu snapshot (SHUTDOWN NOSAVE)
+)
+
+// errShuttingDown được trả về khi bắt đầu BGSAVE/BGREWRITEAOF, hoặc chạy lệnh sau khi đã lưu
+// snapshot cuối cùng, trong lúc server đang dừng
+var errShuttingDown = errors.New("ERR The server is shutting down")
+
+// ErrShutdownAborted được trả về khi không lưu được snapshot cuối cùng: server không dừng mà tiếp tục
+// phục vụ client như trước, để có thể sửa lỗi (ví dụ ổ đĩa đầy) rồi thử dừng lại (giống Redis)
+var ErrShutdownAborted = errors.New("ERR Errors trying to SHUTDOWN. Check logs.")
+
+// parseShutdownArgs đọc các đối số của SHUTDOWN [NOSAVE|SAVE]
+func parseShutdownArgs(args []protocol.Value) (ShutdownMode, []byte) {
+  mode := ShutdownDefault
+  for _, arg := range args {
+    switch strings.ToUpper(arg.Bulk) {
+    case "SAVE":
+      if mode == ShutdownNoSave {
+        return 0, errorReply("ERR syntax error")
+      }
+      mode = ShutdownSave
+    case "NOSAVE":
+      if mode == ShutdownSave {
+        return 0, errorReply("ERR syntax error")
+      }
+      mode = ShutdownNoSave
+    default:
+      return 0, errorReply("ERR syntax error")
+    }
+  }
+  return mode, nil
+}
+
+// beginJob đăng ký một công việc nền (BGSAVE, ghi lại AOF) để việc dừng server chờ nó kết thúc.
+// Trả về errShuttingDown nếu server đã bắt đầu dừng.
+func (h *CommandsHandler) beginJob() error {
+  h.jobsMu.Lock()
+  defer h.jobsMu.Unlock()
+  if h.shuttingDown {
+    return errShuttingDown
+  }
+  h.jobs.Add(1)
+  return nil
+}
+
+// endJob đánh dấu công việc nền đã kết thúc
+func (h *CommandsHandler) endJob() {
+  h.jobs.Done()
+}
+
+// unblockClients đánh thức các client đang chờ trong BLPOP/BRPOP/BLMOVE (chúng nhận về null)
+// và từ chối các công việc nền mới. Gọi khi bắt đầu dừng server.
+func (h *CommandsHandler) unblockClients() {
+  h.jobsMu.Lock()
+  h.shuttingDown = true
+  h.jobsMu.Unlock()
+  h.closeOnce.Do(func() { close(h.closing) })
+}
+
+// waitJobs chờ BGSAVE/ghi lại AOF đang chạy kết thúc, tối đa tới khi ctx hết hạn
+func (h *CommandsHandler) waitJobs(ctx context.Context) {
+  finished := make(chan struct{})
+  go func() {
+    h.jobs.Wait()
+    close(finished)
+  }()
+  select {
+  case <-finished:
+  case <-ctx.Done():
+    logging.Warningf("Shutdown: background saving or AOF rewrite still running: %v", ctx.Err())
+  }
+}
+
+// setShuttingDown bật/tắt việc từ chối các công việc nền mới
+func (h *CommandsHandler) setShuttingDown(v bool) {
+  h.jobsMu.Lock()
+  h.shuttingDown = v
+  h.jobsMu.Unlock()
+}
+
+// saveBeforeShutdown lưu snapshot cuối cùng theo mode, trước khi ngắt bất kỳ kết nối nào. Trong lúc
+// lưu, gate được giữ ở chế độ ghi nên không lệnh nào thay đổi Store. Lưu thành công thì Store bị
+// đóng băng: mọi lệnh sau đó bị từ chối, không có thay đổi nào nằm ngoài snapshot. Lưu lỗi thì trả về
+// ErrShutdownAborted và server tiếp tục chạy bình thường.
+func (h *CommandsHandler) saveBeforeShutdown(ctx context.Context, mode ShutdownMode) error {
+  if mode == ShutdownNoSave || (mode == ShutdownDefault && (h.rdb == nil || !h.rdb.HasSaveRules())) {
+    return nil
+  }
+  if h.rdb == nil {
+    logging.Warningf("Error trying to save the DB, can't exit: %v", errRDBDisabled)
+    return ErrShutdownAborted
+  }
+
+  // Không bắt đầu công việc nền mới trong lúc chờ các công việc đang chạy
+  h.setShuttingDown(true)
+  h.waitJobs(ctx)
+
+  h.gate.Lock()
+  defer h.gate.Unlock()
+  if h.frozen {
+    return nil
+  }
+  logging.Noticef("Saving the final RDB snapshot before exiting.")
+  if err := h.rdb.Save(h.store); err != nil {
+    logging.Warningf("Error trying to save the DB, can't exit: %v", err)
+    h.setShuttingDown(false)
+    return ErrShutdownAborted
+  }
+  h.frozen = true
+  return nil
+}
+
+// finishPersistence hoàn tất việc lưu trữ khi dừng server: chờ BGSAVE/ghi lại AOF đang chạy
+// (tối đa tới khi ctx hết hạn) rồi ghi và fsync toàn bộ AOF. Snapshot cuối cùng đã được lưu bởi
+// saveBeforeShutdown. Phải được gọi sau unblockClients, khi không còn lệnh nào của client đang chạy.
+func (h *CommandsHandler) finishPersistence(ctx context.Context) error {
+  h.waitJobs(ctx)
+  if h.aof != nil {
+    logging.Noticef("Calling fsync() on the AOF file.")
+    if err := h.aof.Sync(); err != nil {
+      return fmt.Errorf("syncing the AOF: %v", err)
+    }
+  }
+  return nil
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+  "time"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// parseExpireOption chuyển một tùy chọn hết hạn (EX, PX, EXAT, PXAT) cùng giá trị của nó
+// thành thời điểm hết hạn tuyệt đối. cmd là tên lệnh (viết thường) dùng trong thông báo lỗi.
+func parseExpireOption(option, arg, cmd string) (time.Time, []byte) {
+  n, err := strconv.ParseInt(arg, 10, 64)
+  if err != nil {
+    return time.Time{}, errorReply(errNotInteger)
+  }
+  if n <= 0 {
+    return time.Time{}, errorReply(fmt.Sprintf("ERR invalid expire time in '%s' command", cmd))
+  }
+
+  switch option {
+  case "EX":
+    if n > math.MaxInt64/int64(time.Second) {
+      return time.Time{}, errorReply(fmt.Sprintf("ERR invalid expire time in '%s' command", cmd))
+    }
+    return time.Now().Add(time.Duration(n) * time.Second), nil
+  case "PX":
+    if n > math.MaxInt64/int64(time.Millisecond) {
+      return time.Time{}, errorReply(fmt.Sprintf("ERR invalid expire time in '%s' command", cmd))
+    }
+    return time.Now().Add(time.Duration(n) * time.Millisecond), nil
+  case "EXAT":
+    return time.Unix(n, 0), nil
+  default: // PXAT
+    return time.UnixMilli(n), nil
+  }
+}
+
+// incrGeneric xử lý chung cho INCR/INCRBY/DECR/DECRBY.
+// AOF ghi lại dưới dạng INCRBY với delta cụ thể.
+func (h *CommandsHandler) incrGeneric(s *store.Store, aof *store.AOF, key string, delta int64) []byte {
+  value, err := s.INCRBY(key, delta)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  logCommand(s, aof, "INCRBY", key, strconv.FormatInt(delta, 10))
+  return protocol.Value{Typ: "integer", Num: int(value)}.Marshal()
+}
+
+func (h *CommandsHandler) handleINCR(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("incr")
+  }
+  return h.incrGeneric(s, aof, args[0].Bulk, 1)
+}
+
+func (h *CommandsHandler) handleDECR(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("decr")
+  }
+  return h.incrGeneric(s, aof, args[0].Bulk, -1)
+}
+
+func (h *CommandsHandler) handleINCRBY(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("incrby")
+  }
+
+  delta, err := strconv.ParseInt(args[1].Bulk, 10, 64)
+  if err != nil {
+    return errorReply(errNotInteger)
+  }
+  return h.incrGeneric(s, aof, args[0].Bulk, delta)
+}
+
+func (h *CommandsHandler) handleDECRBY(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("decrby")
+  }
+
+  delta, err := strconv.ParseInt(args[1].Bulk, 10, 64)
+  if err != nil || delta == math.MinInt64 {
+    return errorReply(errNotInteger)
+  }
+  return h.incrGeneric(s, aof, args[0].Bulk, -delta)
+}
+
+func (h *CommandsHandler) handleINCRBYFLOAT(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("incrbyfloat")
+  }
+
+  delta, err := strconv.ParseFloat(args[1].Bulk, 64)
+  if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+    return errorReply(store.ErrNotFloat.Error())
+  }
+
+  // Ghi kết quả dưới dạng SET ... KEEPTTL để phát lại không phụ thuộc vào phép cộng số thực.
+  // Giá trị tuyệt đối phải được ghi khi còn giữ khóa của Store: nếu ghi sau, hai lệnh đồng thời
+  // có thể nằm trong AOF ngược thứ tự và phát lại cho giá trị cũ.
+  var seq uint64
+  value, err := s.INCRBYFLOAT(args[0].Bulk, delta, func(value string) {
+    seq = logCommandNoWait(s, aof, "SET", args[0].Bulk, value, "KEEPTTL")
+  })
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  waitLogged(aof, seq)
+  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+}
+
+func (h *CommandsHandler) handleAPPEND(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("append")
+  }
+
+  length, err := s.APPEND(args[0].Bulk, args[1].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  logCommand(s, aof, "APPEND", args[0].Bulk, args[1].Bulk)
+  return protocol.Value{Typ: "integer", Num: length}.Marshal()
+}
+
+func (h *CommandsHandler) handleSTRLEN(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("strlen")
+  }
+
+  length, err := s.STRLEN(args[0].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: length}.Marshal()
+}
+
+func (h *CommandsHandler) handleGETRANGE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("getrange")
+  }
+
+  start, err1 := strconv.Atoi(args[1].Bulk)
+  end, err2 := strconv.Atoi(args[2].Bulk)
+  if err1 != nil || err2 != nil {
+    return errorReply(errNotInteger)
+  }
+
+  value, err := s.GETRANGE(args[0].Bulk, start, end)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+}
+
+func (h *CommandsHandler) handleSETRANGE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("setrange")
+  }
+
+  offset, err := strconv.Atoi(args[1].Bulk)
+  if err != nil {
+    return errorReply(errNotInteger)
+  }
+  if offset < 0 {
+    return errorReply("ERR offset is out of range")
+  }
+
+  length, err := s.SETRANGE(args[0].Bulk, offset, args[2].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if len(args[2].Bulk) > 0 {
+    logCommand(s, aof, "SETRANGE", args[0].Bulk, args[1].Bulk, args[2].Bulk)
+  }
+  return protocol.Value{Typ: "integer", Num: length}.Marshal()
+}
+
+func (h *CommandsHandler) handleGETDEL(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("getdel")
+  }
+
+  value, found, err := s.GETDEL(args[0].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  if !found {
+    return protocol.Value{Typ: "null"}.Marshal()
+  }
+
+  logCommand(s, aof, "DEL", args[0].Bulk)
+  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+}
+
+// handleGETEX: GETEX key [EX seconds | PX milliseconds | EXAT timestamp | PXAT ms-timestamp | PERSIST].
+// Thời điểm hết hạn được ghi vào AOF dưới dạng tuyệt đối (PXAT) để khởi động lại không kéo dài TTL.
+func (h *CommandsHandler) handleGETEX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 1 {
+    return wrongArgsReply("getex")
+  }
+
+  key := args[0].Bulk
+  var expiresAt time.Time
+  persist := false
+  options := 0
+
+  for i := 1; i < len(args); i++ {
+    option := strings.ToUpper(args[i].Bulk)
+    switch option {
+    case "EX", "PX", "EXAT", "PXAT":
+      if i+1 >= len(args) {
+        return errorReply("ERR syntax error")
+      }
+      var errResp []byte
+      expiresAt, errResp = parseExpireOption(option, args[i+1].Bulk, "getex")
+      if errResp != nil {
+        return errResp
+      }
+      i++
+    case "PERSIST":
+      persist = true
+    default:
+      return errorReply("ERR syntax error")
+    }
+    options++
+  }
+  if options > 1 {
+    return errorReply("ERR syntax error")
+  }
+
+  value, found, err := s.GETEX(key, expiresAt, persist)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  if !found {
+    return protocol.Value{Typ: "null"}.Marshal()
+  }
+
+  switch {
+  case persist:
+    logCommand(s, aof, "GETEX", key, "PERSIST")
+  case !expiresAt.IsZero():
+    logCommand(s, aof, "GETEX", key, "PXAT", strconv.FormatInt(expiresAt.UnixMilli(), 10))
+  }
+  return protocol.Value{Typ: "bulk", Bulk: value}.Marshal()
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+  "path/filepath"
+  "sync"
+  "testing"
+
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// checkConcurrentReplay chạy incr song song từ nhiều goroutine, rồi kiểm tra rằng phát lại AOF
+// cho cùng kết quả read như Store đang chạy (các giá trị tuyệt đối phải nằm đúng thứ tự áp dụng)
+func checkConcurrentReplay(t *testing.T, incr, read []string) {
+  t.Helper()
+  dir := filepath.Join(t.TempDir(), "appendonlydir")
+  aof, err := store.NewAOF(dir, "appendonly.aof")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer aof.Close()
+  handler := NewCommandsHandler(store.NewStore(), aof)
+
+  var wg sync.WaitGroup
+  for range 32 {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      for range 50 {
+        handler.HandleCommand(command(incr...))
+      }
+    }()
+  }
+  wg.Wait()
+
+  want := string(handler.HandleCommand(command(read...)))
+  reloaded := NewCommandsHandler(store.NewStore(), nil)
+  if err := aof.ReadAndLoad(reloaded); err != nil {
+    t.Fatal(err)
+  }
+  if got := string(reloaded.HandleCommand(command(read...))); got != want {
+    t.Fatalf("%v after reloading the AOF = %q, want %q", read, got, want)
+  }
+}
+
+func TestINCRBYFLOATConcurrentReplay(t *testing.T) {
+  checkConcurrentReplay(t, []string{"INCRBYFLOAT", "counter", "0.5"}, []string{"GET", "counter"})
+}
@@ -0,0 +1,493 @@
+package service
+
+import (
+  "math"
+  "strconv"
+  "strings"
+
+  "mnhgo/mnh-go-kv-store/internal/protocol"
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+// formatScore định dạng score giống Redis: "inf"/"-inf" cho vô cùng,
+// còn lại dùng biểu diễn ngắn nhất mà vẫn đọc lại chính xác
+func formatScore(score float64) string {
+  switch {
+  case math.IsInf(score, 1):
+    return "inf"
+  case math.IsInf(score, -1):
+    return "-inf"
+  }
+  return strconv.FormatFloat(score, 'g', -1, 64)
+}
+
+// parseScore đọc một score (chấp nhận "inf", "+inf", "-inf"), từ chối NaN
+func parseScore(arg string) (float64, error) {
+  score, err := strconv.ParseFloat(arg, 64)
+  if err != nil || math.IsNaN(score) {
+    return 0, store.ErrNotFloat
+  }
+  return score, nil
+}
+
+// scoredMembersReply mã hóa danh sách phần tử, kèm score xen kẽ nếu withScores
+func scoredMembersReply(members []store.ScoredMember, withScores bool) []byte {
+  items := make([]string, 0, len(members)*2)
+  for _, m := range members {
+    items = append(items, m.Member)
+    if withScores {
+      items = append(items, formatScore(m.Score))
+    }
+  }
+  return bulkArrayReply(items)
+}
+
+// setZAddFlag bật cờ arg của ZADD trong opts, trả về false nếu arg không phải là cờ
+func setZAddFlag(opts *store.ZAddOptions, arg string) bool {
+  switch strings.ToUpper(arg) {
+  case "NX":
+    opts.NX = true
+  case "XX":
+    opts.XX = true
+  case "GT":
+    opts.GT = true
+  case "LT":
+    opts.LT = true
+  case "CH":
+    opts.CH = true
+  case "INCR":
+    opts.INCR = true
+  default:
+    return false
+  }
+  return true
+}
+
+// handleZADD: ZADD key [NX|XX] [GT|LT] [CH] [INCR] score member [score member ...]
+func (h *CommandsHandler) handleZADD(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 3 {
+    return wrongArgsReply("zadd")
+  }
+
+  key := args[0].Bulk
+  var opts store.ZAddOptions
+  i := 1
+  for ; i < len(args); i++ {
+    // Các cờ đứng trước cặp score/member đầu tiên
+    if !setZAddFlag(&opts, args[i].Bulk) {
+      break
+    }
+  }
+
+  rest := args[i:]
+  if len(rest) == 0 || len(rest)%2 != 0 {
+    return errorReply("ERR syntax error")
+  }
+  if opts.NX && opts.XX {
+    return errorReply("ERR XX and NX options at the same time are not compatible")
+  }
+  if (opts.GT && opts.LT) || (opts.NX && (opts.GT || opts.LT)) {
+    return errorReply("ERR GT, LT, and/or NX options at the same time are not compatible")
+  }
+  if opts.INCR && len(rest) != 2 {
+    return errorReply("ERR INCR option supports a single increment-element pair")
+  }
+
+  members := make([]store.ScoredMember, 0, len(rest)/2)
+  for j := 0; j < len(rest); j += 2 {
+    score, err := parseScore(rest[j].Bulk)
+    if err != nil {
+      return errorReply(err.Error())
+    }
+    members = append(members, store.ScoredMember{Member: rest[j+1].Bulk, Score: score})
+  }
+
+  // Ghi score cuối cùng của các phần tử đã áp dụng, không kèm cờ, nên phát lại AOF luôn cho
+  // kết quả giống hệt. Score tuyệt đối phải được ghi khi còn giữ khóa của Store để các lệnh
+  // INCR đồng thời nằm trong AOF đúng thứ tự áp dụng.
+  var seq uint64
+  count, applied, err := s.ZADD(key, opts, members, func(applied []store.ScoredMember) {
+    seq = logZAddApplied(s, aof, key, applied)
+  })
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  waitLogged(aof, seq)
+
+  if opts.INCR {
+    if len(applied) == 0 {
+      return protocol.Value{Typ: "null"}.Marshal()
+    }
+    return doubleReply(applied[0].Score)
+  }
+  return protocol.Value{Typ: "integer", Num: count}.Marshal()
+}
+
+func (h *CommandsHandler) handleZINCRBY(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("zincrby")
+  }
+
+  increment, err := parseScore(args[1].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  var seq uint64
+  score, err := s.ZINCRBY(args[0].Bulk, increment, args[2].Bulk, func(applied []store.ScoredMember) {
+    seq = logZAddApplied(s, aof, args[0].Bulk, applied)
+  })
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  waitLogged(aof, seq)
+  return doubleReply(score)
+}
+
+// logZAddApplied ghi các phần tử đã áp dụng dưới dạng ZADD key score member ... với score cuối cùng;
+// được gọi khi Store còn giữ khóa nên chỉ dùng logCommandNoWait
+func logZAddApplied(s *store.Store, aof *store.AOF, key string, applied []store.ScoredMember) uint64 {
+  parts := []string{"ZADD", key}
+  for _, m := range applied {
+    parts = append(parts, formatScore(m.Score), m.Member)
+  }
+  return logCommandNoWait(s, aof, parts...)
+}
+
+func (h *CommandsHandler) handleZREM(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 2 {
+    return wrongArgsReply("zrem")
+  }
+
+  key := args[0].Bulk
+  members := argStrings(args[1:])
+
+  removed, err := s.ZREM(key, members...)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if removed > 0 {
+    logCommand(s, aof, append([]string{"ZREM", key}, members...)...)
+  }
+  return protocol.Value{Typ: "integer", Num: removed}.Marshal()
+}
+
+func (h *CommandsHandler) handleZSCORE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 2 {
+    return wrongArgsReply("zscore")
+  }
+
+  score, found, err := s.ZSCORE(args[0].Bulk, args[1].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  if !found {
+    return protocol.Value{Typ: "null"}.Marshal()
+  }
+  return doubleReply(score)
+}
+
+func (h *CommandsHandler) handleZCARD(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 1 {
+    return wrongArgsReply("zcard")
+  }
+
+  card, err := s.ZCARD(args[0].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: card}.Marshal()
+}
+
+// rankGeneric xử lý chung cho ZRANK/ZREVRANK key member [WITHSCORE]
+func (h *CommandsHandler) rankGeneric(name string, s *store.Store, args []protocol.Value, rev bool) []byte {
+  if len(args) < 2 || len(args) > 3 {
+    return wrongArgsReply(strings.ToLower(name))
+  }
+  withScore := len(args) == 3
+  if withScore && strings.ToUpper(args[2].Bulk) != "WITHSCORE" {
+    return errorReply("ERR syntax error")
+  }
+
+  key, member := args[0].Bulk, args[1].Bulk
+  rank, found, err := s.ZRANK(key, member, rev)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  if !found {
+    return protocol.Value{Typ: "null"}.Marshal()
+  }
+
+  if withScore {
+    score, _, _ := s.ZSCORE(key, member)
+    return protocol.Value{Typ: "array", Array: []protocol.Value{
+      {Typ: "integer", Num: rank},
+      {Typ: "double", Double: score},
+    }}.Marshal()
+  }
+  return protocol.Value{Typ: "integer", Num: rank}.Marshal()
+}
+
+func (h *CommandsHandler) handleZRANK(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.rankGeneric("ZRANK", s, args, false)
+}
+
+func (h *CommandsHandler) handleZREVRANK(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.rankGeneric("ZREVRANK", s, args, true)
+}
+
+// handleZRANGE: ZRANGE key start stop [BYSCORE|BYLEX] [REV] [LIMIT offset count] [WITHSCORES].
+// Với REV và BYSCORE/BYLEX, start là cận trên và stop là cận dưới (giống Redis).
+func (h *CommandsHandler) handleZRANGE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) < 3 {
+    return wrongArgsReply("zrange")
+  }
+
+  key, start, stop := args[0].Bulk, args[1].Bulk, args[2].Bulk
+  byScore, byLex, rev, withScores, hasLimit := false, false, false, false, false
+  offset, count := 0, -1
+
+  for i := 3; i < len(args); i++ {
+    switch strings.ToUpper(args[i].Bulk) {
+    case "BYSCORE":
+      byScore = true
+    case "BYLEX":
+      byLex = true
+    case "REV":
+      rev = true
+    case "WITHSCORES":
+      withScores = true
+    case "LIMIT":
+      if i+2 >= len(args) {
+        return errorReply("ERR syntax error")
+      }
+      var err1, err2 error
+      offset, err1 = strconv.Atoi(args[i+1].Bulk)
+      count, err2 = strconv.Atoi(args[i+2].Bulk)
+      if err1 != nil || err2 != nil {
+        return errorReply(errNotInteger)
+      }
+      hasLimit = true
+      i += 2
+    default:
+      return errorReply("ERR syntax error")
+    }
+  }
+
+  if byScore && byLex {
+    return errorReply("ERR syntax error")
+  }
+  if hasLimit && !byScore && !byLex {
+    return errorReply("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+  }
+  if withScores && byLex {
+    return errorReply("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+  }
+
+  // Với REV, thứ tự cận được đảo ngược
+  min, max := start, stop
+  if rev {
+    min, max = stop, start
+  }
+
+  var members []store.ScoredMember
+  var err error
+  switch {
+  case byScore:
+    r, perr := store.ParseScoreRange(min, max)
+    if perr != nil {
+      return errorReply(perr.Error())
+    }
+    members, err = s.ZRangeByScore(key, r, rev, offset, count)
+  case byLex:
+    r, perr := store.ParseLexRange(min, max)
+    if perr != nil {
+      return errorReply(perr.Error())
+    }
+    members, err = s.ZRangeByLex(key, r, rev, offset, count)
+  default:
+    startIdx, err1 := strconv.Atoi(start)
+    stopIdx, err2 := strconv.Atoi(stop)
+    if err1 != nil || err2 != nil {
+      return errorReply(errNotInteger)
+    }
+    members, err = s.ZRangeByRank(key, startIdx, stopIdx, rev)
+  }
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  return scoredMembersReply(members, withScores)
+}
+
+// legacyRange chuyển các lệnh cũ (ZRANGEBYSCORE, ZREVRANGE, ...) thành ZRANGE tương đương
+func (h *CommandsHandler) legacyRange(name string, s *store.Store, aof *store.AOF, args []protocol.Value, flags ...string) []byte {
+  if len(args) < 3 {
+    return wrongArgsReply(strings.ToLower(name))
+  }
+
+  converted := append([]protocol.Value{}, args[:3]...)
+  for _, flag := range flags {
+    converted = append(converted, protocol.Value{Typ: "bulk", Bulk: flag})
+  }
+  converted = append(converted, args[3:]...)
+  return h.handleZRANGE(s, aof, converted)
+}
+
+func (h *CommandsHandler) handleZREVRANGE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.legacyRange("ZREVRANGE", s, aof, args, "REV")
+}
+
+func (h *CommandsHandler) handleZRANGEBYSCORE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.legacyRange("ZRANGEBYSCORE", s, aof, args, "BYSCORE")
+}
+
+func (h *CommandsHandler) handleZREVRANGEBYSCORE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.legacyRange("ZREVRANGEBYSCORE", s, aof, args, "BYSCORE", "REV")
+}
+
+func (h *CommandsHandler) handleZRANGEBYLEX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.legacyRange("ZRANGEBYLEX", s, aof, args, "BYLEX")
+}
+
+func (h *CommandsHandler) handleZREVRANGEBYLEX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.legacyRange("ZREVRANGEBYLEX", s, aof, args, "BYLEX", "REV")
+}
+
+func (h *CommandsHandler) handleZCOUNT(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("zcount")
+  }
+
+  r, err := store.ParseScoreRange(args[1].Bulk, args[2].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  count, err := s.ZCOUNT(args[0].Bulk, r)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: count}.Marshal()
+}
+
+func (h *CommandsHandler) handleZLEXCOUNT(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("zlexcount")
+  }
+
+  r, err := store.ParseLexRange(args[1].Bulk, args[2].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  count, err := s.ZLEXCOUNT(args[0].Bulk, r)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+  return protocol.Value{Typ: "integer", Num: count}.Marshal()
+}
+
+// popMinMaxGeneric xử lý chung cho ZPOPMIN/ZPOPMAX key [count], ghi AOF dưới dạng ZREM
+func (h *CommandsHandler) popMinMaxGeneric(name string, s *store.Store, aof *store.AOF, args []protocol.Value, max bool) []byte {
+  if len(args) < 1 || len(args) > 2 {
+    return wrongArgsReply(strings.ToLower(name))
+  }
+
+  key := args[0].Bulk
+  count := 1
+  if len(args) == 2 {
+    n, err := strconv.Atoi(args[1].Bulk)
+    if err != nil || n < 0 {
+      return errorReply("ERR value is out of range, must be positive")
+    }
+    count = n
+  }
+
+  members, err := s.ZPOP(key, count, max)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if len(members) > 0 {
+    parts := []string{"ZREM", key}
+    for _, m := range members {
+      parts = append(parts, m.Member)
+    }
+    logCommand(s, aof, parts...)
+  }
+  return scoredMembersReply(members, true)
+}
+
+func (h *CommandsHandler) handleZPOPMIN(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.popMinMaxGeneric("ZPOPMIN", s, aof, args, false)
+}
+
+func (h *CommandsHandler) handleZPOPMAX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  return h.popMinMaxGeneric("ZPOPMAX", s, aof, args, true)
+}
+
+func (h *CommandsHandler) handleZREMRANGEBYRANK(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("zremrangebyrank")
+  }
+
+  start, err1 := strconv.Atoi(args[1].Bulk)
+  stop, err2 := strconv.Atoi(args[2].Bulk)
+  if err1 != nil || err2 != nil {
+    return errorReply(errNotInteger)
+  }
+
+  removed, err := s.ZREMRANGEBYRANK(args[0].Bulk, start, stop)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if removed > 0 {
+    logCommand(s, aof, "ZREMRANGEBYRANK", args[0].Bulk, strconv.Itoa(start), strconv.Itoa(stop))
+  }
+  return protocol.Value{Typ: "integer", Num: removed}.Marshal()
+}
+
+func (h *CommandsHandler) handleZREMRANGEBYSCORE(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("zremrangebyscore")
+  }
+
+  r, err := store.ParseScoreRange(args[1].Bulk, args[2].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  removed, err := s.ZREMRANGEBYSCORE(args[0].Bulk, r)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if removed > 0 {
+    logCommand(s, aof, "ZREMRANGEBYSCORE", args[0].Bulk, args[1].Bulk, args[2].Bulk)
+  }
+  return protocol.Value{Typ: "integer", Num: removed}.Marshal()
+}
+
+func (h *CommandsHandler) handleZREMRANGEBYLEX(s *store.Store, aof *store.AOF, args []protocol.Value) []byte {
+  if len(args) != 3 {
+    return wrongArgsReply("zremrangebylex")
+  }
+
+  r, err := store.ParseLexRange(args[1].Bulk, args[2].Bulk)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  removed, err := s.ZREMRANGEBYLEX(args[0].Bulk, r)
+  if err != nil {
+    return errorReply(err.Error())
+  }
+
+  if removed > 0 {
+    logCommand(s, aof, "ZREMRANGEBYLEX", args[0].Bulk, args[1].Bulk, args[2].Bulk)
+  }
+  return protocol.Value{Typ: "integer", Num: removed}.Marshal()
+}
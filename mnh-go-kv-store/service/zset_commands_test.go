@@ -0,0 +1,41 @@
+package service
+
+import (
+  "testing"
+
+  "mnhgo/mnh-go-kv-store/internal/store"
+)
+
+func TestZADDFlags(t *testing.T) {
+  handler := NewCommandsHandler(store.NewStore(), nil)
+  tests := []struct {
+    args []string
+    want string
+  }{
+    {[]string{"ZADD", "z", "NX", "1", "a"}, ":1\r\n"},
+    {[]string{"ZADD", "z", "nx", "5", "a"}, ":0\r\n"},
+    {[]string{"ZADD", "z", "XX", "CH", "2", "a", "3", "b"}, ":1\r\n"},
+    {[]string{"ZADD", "z", "GT", "CH", "1", "a"}, ":0\r\n"},
+    {[]string{"ZADD", "z", "INCR", "1.5", "a"}, ",3.5\r\n"},
+    {[]string{"ZADD", "z", "XX", "INCR", "1", "missing"}, "$-1\r\n"},
+    {[]string{"ZADD", "z", "NX", "XX", "1", "a"}, "-ERR XX and NX options at the same time are not compatible\r\n"},
+    {[]string{"ZADD", "z", "GT", "LT", "1", "a"}, "-ERR GT, LT, and/or NX options at the same time are not compatible\r\n"},
+    {[]string{"ZADD", "z", "INCR", "1", "a", "2", "b"}, "-ERR INCR option supports a single increment-element pair\r\n"},
+    {[]string{"ZADD", "z", "CH", "NX"}, "-ERR syntax error\r\n"},
+    {[]string{"ZADD", "z", "1", "a", "NX"}, "-ERR syntax error\r\n"},
+  }
+  for _, tt := range tests {
+    if got := string(handler.HandleCommand(command(tt.args...))); got != tt.want {
+      t.Errorf("%v = %q, want %q", tt.args, got, tt.want)
+    }
+  }
+}
+
+func TestZSetIncrementConcurrentReplay(t *testing.T) {
+  for _, incr := range [][]string{
+    {"ZINCRBY", "z", "0.25", "m"},
+    {"ZADD", "z", "INCR", "0.25", "m"},
+  } {
+    checkConcurrentReplay(t, incr, []string{"ZSCORE", "z", "m"})
+  }
+}